@@ -2,22 +2,85 @@ package main
 
 import (
 	"context"
+	"errors"
+	"file-service/m/internal/apikey"
+	"file-service/m/internal/audit"
+	"file-service/m/internal/auth"
+	"file-service/m/internal/bandwidth"
 	"file-service/m/internal/config"
+	"file-service/m/internal/database"
 	"file-service/m/internal/database/postgres"
+	"file-service/m/internal/events"
+	addgroupmember "file-service/m/internal/handlers/addGroupMember"
+	createapikey "file-service/m/internal/handlers/createApiKey"
+	createshare "file-service/m/internal/handlers/createShare"
+	createsignedurl "file-service/m/internal/handlers/createSignedUrl"
+	createuploadurl "file-service/m/internal/handlers/createUploadUrl"
+	createuser "file-service/m/internal/handlers/createUser"
+	createwebhook "file-service/m/internal/handlers/createWebhook"
 	"file-service/m/internal/handlers/delete"
+	deleteapikey "file-service/m/internal/handlers/deleteApiKey"
+	deletewebhook "file-service/m/internal/handlers/deleteWebhook"
+	"file-service/m/internal/handlers/download"
+	downloadshare "file-service/m/internal/handlers/downloadShare"
 	"file-service/m/internal/handlers/get"
+	getbandwidthlimits "file-service/m/internal/handlers/getBandwidthLimits"
+	getshare "file-service/m/internal/handlers/getShare"
+	grantaccess "file-service/m/internal/handlers/grantAccess"
+	listaccess "file-service/m/internal/handlers/listAccess"
+	listapikeys "file-service/m/internal/handlers/listApiKeys"
+	listauditevents "file-service/m/internal/handlers/listAuditEvents"
+	listshares "file-service/m/internal/handlers/listShares"
+	listwebhookdeliveries "file-service/m/internal/handlers/listWebhookDeliveries"
+	listwebhooks "file-service/m/internal/handlers/listWebhooks"
+	"file-service/m/internal/handlers/liveness"
+	"file-service/m/internal/handlers/readiness"
+	removegroupmember "file-service/m/internal/handlers/removeGroupMember"
+	resetpassword "file-service/m/internal/handlers/resetPassword"
+	"file-service/m/internal/handlers/restore"
+	retrywebhookdelivery "file-service/m/internal/handlers/retryWebhookDelivery"
+	revokeaccess "file-service/m/internal/handlers/revokeAccess"
+	revokeshare "file-service/m/internal/handlers/revokeShare"
 	"file-service/m/internal/handlers/save"
+	setbandwidthlimits "file-service/m/internal/handlers/setBandwidthLimits"
 	setdelete "file-service/m/internal/handlers/setDelete"
+	setquota "file-service/m/internal/handlers/setQuota"
+	setuserdisabled "file-service/m/internal/handlers/setUserDisabled"
+	setuserrole "file-service/m/internal/handlers/setUserRole"
+	streamevents "file-service/m/internal/handlers/streamEvents"
+	"file-service/m/internal/handlers/usage"
+	"file-service/m/internal/health"
+	"file-service/m/internal/jwtauth"
 	mwLogger "file-service/m/internal/logger"
+	"file-service/m/internal/metrics"
+	"file-service/m/internal/policy"
+	"file-service/m/internal/ratelimit"
+	"file-service/m/internal/signedurl"
+	"file-service/m/internal/tracing"
 	"file-service/m/internal/uuidgenerator"
+	"file-service/m/internal/webhook"
+	"fmt"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"file-service/m/internal/middleware/auditmiddleware"
+	"file-service/m/internal/middleware/authmiddleware"
+	"file-service/m/internal/middleware/clientipmiddleware"
 	"file-service/m/internal/middleware/fileidctxmiddleware"
 	"file-service/m/internal/middleware/loggerMiddleware"
+	"file-service/m/internal/middleware/metricsmiddleware"
+	"file-service/m/internal/middleware/ratelimitmiddleware"
 	"file-service/m/internal/middleware/reqidctxmiddleware"
+	"file-service/m/internal/middleware/sharectxmiddleware"
+	"file-service/m/internal/middleware/signeduploadmiddleware"
+	"file-service/m/internal/middleware/tracingmiddleware"
+	compressedstorage "file-service/m/internal/storage/compressedStorage"
+	encryptedstorage "file-service/m/internal/storage/encryptedStorage"
+	instrumentedstorage "file-service/m/internal/storage/instrumentedStorage"
 	localstorage "file-service/m/internal/storage/localStorage"
+	timeoutstorage "file-service/m/internal/storage/timeoutStorage"
 	"log/slog"
 	"net/http"
 	"os"
@@ -31,7 +94,22 @@ func main() {
 
 	logger := mwLogger.NewLogger(cfg.Environment)
 
-	db, err := postgres.New(cfg.DatabaseConfig)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Error("failed to configure tracing", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.HttpServer.ShutdownTimeout)
+		defer cancel()
+
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("failed to flush traces", slog.String("error", err.Error()))
+		}
+	}()
+
+	db, err := postgres.New(cfg.DatabaseConfig, cfg.Quota)
 	if err != nil {
 		logger.Error("failed to connect to database", slog.String("error", err.Error()))
 		os.Exit(1)
@@ -46,13 +124,48 @@ func main() {
 		logger.Info("storage closed")
 	}()
 
-	storage, err := localstorage.New(cfg.StoragePath)
+	if err := bootstrapAdmin(logger, db, cfg.AuthConfig); err != nil {
+		logger.Error("failed to create bootstrap admin", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	m := metrics.New()
+	if err := m.Register(metrics.NewDatabaseCollector(db)); err != nil {
+		logger.Error("failed to register metrics", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	storage, err := newStorage(cfg, m)
 	if err != nil {
 		logger.Error("failed to create storage", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 
-	router := InitRouter(logger, db, storage, cfg)
+	checker := health.New(cfg.Health.CheckTimeout)
+	checker.Add("database", db.Ping)
+	checker.Add("storage", health.DirWritable(cfg.StoragePath))
+	checker.Add("disk", health.DiskSpace(cfg.StoragePath, cfg.Health.MinFreeBytes, cfg.Health.MinFreePercent))
+
+	verifier, err := newTokenVerifier(cfg.AuthConfig)
+	if err != nil {
+		logger.Error("failed to configure authentication", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	pol, err := policy.New(cfg.Policy)
+	if err != nil {
+		logger.Error("failed to configure policy", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	signer, err := newSigner(cfg.SignedURL)
+	if err != nil {
+		logger.Error("failed to configure signed urls", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	broker := events.NewBroker()
+	router := InitRouter(logger, db, storage, verifier, pol, signer, newRateLimits(cfg.RateLimit, cfg.Bandwidth), m, checker, broker, cfg)
 
 	srv := &http.Server{
 		Addr:         cfg.HttpServer.Address,
@@ -62,7 +175,43 @@ func main() {
 		IdleTimeout:  cfg.HttpServer.IdleTimeout,
 	}
 
-	sigterm, done := setupGracefulShutdown(logger, srv, cfg.HttpServer.ShutdownTimeout)
+	// event streams never finish on their own
+	srv.RegisterOnShutdown(broker.Close)
+
+	servers := []*http.Server{srv}
+
+	if cfg.AdminServer.Address != "" {
+		admin := newAdminServer(cfg, m)
+		servers = append(servers, admin)
+
+		go func() {
+			logger.Info("starting admin server", slog.String("address", admin.Addr))
+			if err := admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("failed to start admin server", slog.String("error", err.Error()))
+			}
+		}()
+	}
+
+	background, stopBackground := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		webhook.New(logger, db, cfg.Webhook).Run(background)
+	}()
+
+	// notifications of every replica reach the event streams of this one,
+	// without them streams only catch up with the heartbeat
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := db.Listen(background, logger, broker.Notify); err != nil {
+			logger.Error("failed to listen for notifications", slog.String("error", err.Error()))
+		}
+	}()
+
+	sigterm, done := setupGracefulShutdown(logger, checker, cfg.Health.ShutdownDelay, cfg.HttpServer.ShutdownTimeout, servers...)
 
 	logger.Info("starting http server", slog.String("address", srv.Addr))
 	if err = srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -71,10 +220,15 @@ func main() {
 	}
 
 	<-done
+
+	// deliveries in flight are recorded before the dispatcher returns
+	stopBackground()
+	wg.Wait()
+
 	logger.Info("server http stopped")
 }
 
-func setupGracefulShutdown(logger *slog.Logger, srv *http.Server, ShutdownTimeout time.Duration) (chan os.Signal, chan struct{}) {
+func setupGracefulShutdown(logger *slog.Logger, checker *health.Checker, ShutdownDelay time.Duration, ShutdownTimeout time.Duration, servers ...*http.Server) (chan os.Signal, chan struct{}) {
 	done := make(chan struct{})
 	sigterm := make(chan os.Signal, 1)
 	signal.Notify(sigterm, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -82,38 +236,301 @@ func setupGracefulShutdown(logger *slog.Logger, srv *http.Server, ShutdownTimeou
 		defer close(done)
 		<-sigterm
 
+		// load balancers notice the failing readiness probe and take the
+		// service out before the listeners are closed
+		checker.SetShuttingDown()
+		logger.Info("draining before shutdown", slog.Duration("delay", ShutdownDelay))
+		time.Sleep(ShutdownDelay)
+
 		ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
 		defer cancel()
 
-		if err := srv.Shutdown(ctx); err != nil {
-			logger.Error("failed to stop server", slog.String("error", err.Error()))
+		for _, srv := range servers {
+			if err := srv.Shutdown(ctx); err != nil {
+				logger.Error("failed to stop server", slog.String("error", err.Error()), slog.String("address", srv.Addr))
+			}
 		}
 	}()
 
 	return sigterm, done
 }
 
-func InitRouter(log *slog.Logger, db *postgres.Postgres, storage *localstorage.Storage, cfg *config.Config) *chi.Mux {
+// newAdminServer serves the operational endpoints on their own listener,
+// which is not exposed to the clients of the API.
+func newAdminServer(cfg *config.Config, m *metrics.Metrics) *http.Server {
+	router := chi.NewRouter()
+	router.Use(middleware.Recoverer)
+	router.Handle("/metrics", m.Handler())
+
+	return &http.Server{
+		Addr:        cfg.AdminServer.Address,
+		Handler:     router,
+		ReadTimeout: cfg.HttpServer.Timeout,
+		IdleTimeout: cfg.HttpServer.IdleTimeout,
+	}
+}
+
+// newTokenVerifier returns the JWT verifier in jwt mode and nil in basic
+// mode, where the middleware checks passwords instead.
+func newTokenVerifier(cfg config.AuthConfig) (authmiddleware.TokenVerifier, error) {
+	switch cfg.Mode {
+	case config.AuthModeBasic:
+		return nil, nil
+	case config.AuthModeJWT:
+		return jwtauth.New(cfg.JWT)
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q", cfg.Mode)
+	}
+}
+
+// newSigner returns the signer of download URLs, or nil when no key is
+// configured and signed URLs are disabled.
+func newSigner(cfg config.SignedURLConfig) (*signedurl.Signer, error) {
+	if cfg.Key == "" {
+		return nil, nil
+	}
+
+	key, err := signedurl.ParseKey(cfg.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return signedurl.New(key)
+}
+
+// rateLimits holds the limiters of the router, a nil limiter is disabled.
+type rateLimits struct {
+	ip       *ratelimit.Limiter
+	user     *ratelimit.Limiter
+	upload   *ratelimit.Limiter
+	download *ratelimit.Limiter
+	uploads  *ratelimit.Concurrency
+	throttle *bandwidth.Throttle
+}
+
+func newRateLimits(cfg config.RateLimitConfig, bandwidthCfg config.BandwidthConfig) rateLimits {
+	limiter := func(perSecond float64, burst int) *ratelimit.Limiter {
+		if perSecond <= 0 {
+			return nil
+		}
+
+		return ratelimit.New(perSecond, max(burst, 1))
+	}
+
+	limits := rateLimits{
+		ip:       limiter(cfg.IPRate, cfg.IPBurst),
+		user:     limiter(cfg.UserRate, cfg.UserBurst),
+		upload:   limiter(cfg.UploadRate, cfg.UploadBurst),
+		download: limiter(cfg.DownloadRate, cfg.DownloadBurst),
+		// always installed so that limits can be set at runtime
+		throttle: bandwidth.New(bandwidth.Limits{
+			Global:    bandwidthCfg.Global,
+			PerClient: bandwidthCfg.PerClient,
+		}),
+	}
+
+	if cfg.MaxConcurrentUploads > 0 {
+		limits.uploads = ratelimit.NewConcurrency(cfg.MaxConcurrentUploads)
+	}
+
+	return limits
+}
+
+// bootstrapAdmin creates the administrator from the config so that a fresh
+// database has someone who can create the other users.
+func bootstrapAdmin(logger *slog.Logger, db *postgres.Postgres, cfg config.AuthConfig) error {
+	if cfg.User == "" {
+		return nil
+	}
+
+	hash, err := auth.HashPassword(cfg.Password)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.CreateUser(context.Background(), database.User{
+		Username:     cfg.User,
+		PasswordHash: hash,
+		Role:         database.RoleAdmin,
+	})
+	if errors.Is(err, database.ErrorAlreadyExists) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Info("bootstrap admin created", slog.String("username", cfg.User))
+
+	return nil
+}
+
+type Storage interface {
+	save.Storage
+	get.Storage
+	delete.Storage
+}
+
+func newStorage(cfg *config.Config, m *metrics.Metrics) (Storage, error) {
+	local, err := localstorage.New(cfg.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The encryption layer is always installed so that clients can supply
+	// their own keys, master keys only enable encryption of everything else.
+	var keyring *encryptedstorage.Keyring
+	if cfg.EncryptionConfig.MasterKeys != "" {
+		keyring, err = encryptedstorage.NewKeyringFromConfig(cfg.EncryptionConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var storage Storage = encryptedstorage.New(local, keyring)
+
+	// compression has to happen before encryption, encrypted data does not
+	// compress
+	if cfg.Compression.Enabled {
+		storage = compressedstorage.New(storage, cfg.Compression.MinSize)
+	}
+
+	storage = timeoutstorage.New(storage, cfg.StorageTimeout)
+
+	// outermost, so that the latencies include the encryption and
+	// compression and the bytes are counted as the clients send them
+	return instrumentedstorage.New(storage, m), nil
+}
+
+func InitRouter(log *slog.Logger, db *postgres.Postgres, storage Storage, verifier authmiddleware.TokenVerifier, pol *policy.Policy, signer *signedurl.Signer, limits rateLimits, m *metrics.Metrics, checker *health.Checker, broker *events.Broker, cfg *config.Config) *chi.Mux {
 	router := chi.NewRouter()
 
 	router.Use(middleware.RequestID)
 	router.Use(reqidctxmiddleware.RequestIdCtx)
+	router.Use(tracingmiddleware.New)
 	router.Use(loggerMiddleware.New(log))
+	router.Use(metricsmiddleware.New(m))
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
-	router.Use(middleware.BasicAuth("file-service", map[string]string{
-		cfg.AuthConfig.User: cfg.AuthConfig.Password,
-	}))
-
-	router.Route("/file", func(r chi.Router) {
-		r.Post("/", save.New(log, db, storage, uuidgenerator.New()))
-		r.Route("/{fileID}", func(r chi.Router) {
-			r.Use(fileidctxmiddleware.FileIdCtx)
-			r.Get("/", get.New(log, db, storage))
-			r.Patch("/", setdelete.New(log, db))
-			r.Delete("/", delete.New(log, db, storage))
+	router.Use(clientipmiddleware.New(cfg.RateLimit.TrustedProxies))
+	router.Use(ratelimitmiddleware.ByIP(limits.ip))
+
+	uploads := chi.Chain(
+		ratelimitmiddleware.ByClient(limits.upload),
+		ratelimitmiddleware.ConcurrentUploads(limits.uploads),
+		ratelimitmiddleware.Bandwidth(limits.throttle, cfg.HttpServer.Timeout),
+	)
+	downloads := chi.Chain(
+		ratelimitmiddleware.ByClient(limits.download),
+		ratelimitmiddleware.Bandwidth(limits.throttle, cfg.HttpServer.Timeout),
+	)
+
+	if signer != nil {
+		// signed URLs are their own credential
+		router.With(auditmiddleware.New(log, db, audit.ActionDownload)).With(downloads...).
+			Get("/download/{fileID}", download.New(log, db, storage, signer))
+		router.With(auditmiddleware.New(log, db, audit.ActionUpload), signeduploadmiddleware.New(log, db, signer)).With(uploads...).
+			Post(signedurl.UploadPath, save.New(log, db, storage, uuidgenerator.New()))
+	}
+
+	router.Route("/s/{token}", func(r chi.Router) {
+		// share links are public, protected by their token and password
+		r.Use(sharectxmiddleware.ShareCtx(log, db))
+		r.Get("/", getshare.New(log, db))
+		r.With(auditmiddleware.New(log, db, audit.ActionDownload)).With(downloads...).
+			Get("/download", downloadshare.New(log, db, storage))
+		r.With(auditmiddleware.New(log, db, audit.ActionDownload)).With(downloads...).
+			Post("/download", downloadshare.New(log, db, storage))
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(authmiddleware.New(log, db, verifier))
+		r.Use(ratelimitmiddleware.ByClient(limits.user))
+
+		r.Route("/file", func(r chi.Router) {
+			r.With(auditmiddleware.New(log, db, audit.ActionUpload), authmiddleware.RequireScope(apikey.ScopeFileWrite), authmiddleware.Authorize(pol, policy.ActionUpload)).With(uploads...).
+				Post("/", save.New(log, db, storage, uuidgenerator.New()))
+			if signer != nil {
+				r.With(authmiddleware.RequireScope(apikey.ScopeFileWrite), authmiddleware.Authorize(pol, policy.ActionUpload)).
+					Post("/upload-url", createuploadurl.New(log, db, signer, cfg.SignedURL, cfg.HttpServer.PublicURL))
+			}
+			r.Route("/{fileID}", func(r chi.Router) {
+				// resolved per route, so that audited requests for unknown or
+				// invalid ids are recorded as well
+				fileId := fileidctxmiddleware.FileIdCtx(log, db, cfg.FileId.AcceptLegacyIds)
+				r.With(auditmiddleware.New(log, db, audit.ActionDownload), fileId, authmiddleware.RequireScope(apikey.ScopeFileRead), authmiddleware.Authorize(pol, policy.ActionRead)).With(downloads...).
+					Get("/", get.New(log, db, storage))
+				r.With(auditmiddleware.New(log, db, audit.ActionTrash), fileId, authmiddleware.RequireScope(apikey.ScopeFileDelete), authmiddleware.Authorize(pol, policy.ActionTrash)).
+					Patch("/", setdelete.New(log, db))
+				r.With(auditmiddleware.New(log, db, audit.ActionDelete), fileId, authmiddleware.RequireScope(apikey.ScopeFileDelete), authmiddleware.Authorize(pol, policy.ActionDelete)).
+					Delete("/", delete.New(log, db, storage))
+				r.With(auditmiddleware.New(log, db, audit.ActionRestore), fileId, authmiddleware.RequireScope(apikey.ScopeFileWrite), authmiddleware.Authorize(pol, policy.ActionRestore)).
+					Post("/restore", restore.New(log, db))
+				r.With(fileId, authmiddleware.RequireScope(apikey.ScopeFileRead), authmiddleware.Authorize(pol, policy.ActionRead)).
+					Get("/acl", listaccess.New(log, db))
+				r.With(fileId, authmiddleware.RequireScope(apikey.ScopeFileWrite), authmiddleware.Authorize(pol, policy.ActionShare)).
+					Put("/acl", grantaccess.New(log, db))
+				r.With(fileId, authmiddleware.RequireScope(apikey.ScopeFileWrite), authmiddleware.Authorize(pol, policy.ActionShare)).
+					Delete("/acl", revokeaccess.New(log, db))
+				r.With(fileId, authmiddleware.RequireScope(apikey.ScopeFileRead), authmiddleware.Authorize(pol, policy.ActionRead)).
+					Get("/shares", listshares.New(log, db))
+				r.With(fileId, authmiddleware.RequireScope(apikey.ScopeFileWrite), authmiddleware.Authorize(pol, policy.ActionShare)).
+					Post("/shares", createshare.New(log, db, cfg.HttpServer.PublicURL))
+				r.With(fileId, authmiddleware.RequireScope(apikey.ScopeFileWrite), authmiddleware.Authorize(pol, policy.ActionShare)).
+					Delete("/shares/{shareID}", revokeshare.New(log, db))
+				if signer != nil {
+					r.With(fileId, authmiddleware.RequireScope(apikey.ScopeFileRead), authmiddleware.Authorize(pol, policy.ActionRead)).
+						Post("/signed-url", createsignedurl.New(log, db, signer, cfg.SignedURL, cfg.HttpServer.PublicURL))
+				}
+			})
+		})
+
+		r.With(authmiddleware.RequireScope(apikey.ScopeFileRead), authmiddleware.Authorize(pol, policy.ActionRead)).
+			Get("/usage", usage.New(log, db))
+		r.With(authmiddleware.RequireScope(apikey.ScopeFileRead), authmiddleware.Authorize(pol, policy.ActionRead)).
+			Get("/events", streamevents.New(log, db, broker, cfg.Events.HeartbeatInterval))
+
+		r.Route("/keys", func(r chi.Router) {
+			r.Use(authmiddleware.RequireScope(apikey.ScopeKeyManage))
+			// readers may see the keys they hold but not mint or revoke any
+			r.With(authmiddleware.Authorize(pol, policy.ActionManageKeys)).
+				Post("/", createapikey.New(log, db))
+			r.Get("/", listapikeys.New(log, db))
+			r.With(authmiddleware.Authorize(pol, policy.ActionManageKeys)).
+				Delete("/{keyID}", deleteapikey.New(log, db))
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(authmiddleware.RequireScope(apikey.ScopeAdmin))
+			r.Use(authmiddleware.Authorize(pol, policy.ActionManageUsers))
+			r.Post("/users", createuser.New(log, db))
+			r.Patch("/users/{username}", setuserdisabled.New(log, db))
+			r.Put("/users/{username}/password", resetpassword.New(log, db))
+			r.Put("/users/{username}/role", setuserrole.New(log, db))
+			r.Put("/users/{username}/quota", setquota.New(log, db))
+			r.Put("/groups/{group}/members/{username}", addgroupmember.New(log, db))
+			r.Delete("/groups/{group}/members/{username}", removegroupmember.New(log, db))
+			r.Get("/bandwidth", getbandwidthlimits.New(limits.throttle))
+			r.Put("/bandwidth", setbandwidthlimits.New(log, limits.throttle))
+			r.Get("/audit", listauditevents.New(log, db))
+			r.Route("/webhooks", func(r chi.Router) {
+				r.Post("/", createwebhook.New(log, db))
+				r.Get("/", listwebhooks.New(log, db))
+				r.Delete("/{webhookID}", deletewebhook.New(log, db))
+				r.Get("/{webhookID}/deliveries", listwebhookdeliveries.New(log, db))
+				r.Post("/{webhookID}/deliveries/{deliveryID}/retry", retrywebhookdelivery.New(log, db))
+			})
 		})
 	})
 
-	return router
+	// the probes are polled every few seconds, they bypass the rate limits,
+	// the request log and the metrics so as not to drown them out or be
+	// turned away under load
+	root := chi.NewRouter()
+	root.Use(middleware.Recoverer)
+	root.Get("/healthz", liveness.New())
+	root.Get("/readyz", readiness.New(log, checker))
+	root.Mount("/", router)
+
+	return root
 }
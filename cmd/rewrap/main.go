@@ -0,0 +1,91 @@
+// Command rewrap moves the data keys of encrypted files to the active master
+// key after a rotation. Only the wrapped keys in the files table change, file
+// contents are not re-encrypted. Retired master keys have to stay in
+// ENCRYPTION_MASTER_KEYS until the command has finished.
+package main
+
+import (
+	"context"
+	"errors"
+	"file-service/m/internal/config"
+	"file-service/m/internal/database"
+	"file-service/m/internal/database/postgres"
+	mwLogger "file-service/m/internal/logger"
+	encryptedstorage "file-service/m/internal/storage/encryptedStorage"
+	"log/slog"
+	"os"
+)
+
+const batchSize = 100
+
+func main() {
+	cfg := config.NewConfig()
+
+	logger := mwLogger.NewLogger(cfg.Environment)
+
+	keyring, err := encryptedstorage.NewKeyringFromConfig(cfg.EncryptionConfig)
+	if err != nil {
+		logger.Error("failed to load master keys", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	db, err := postgres.New(cfg.DatabaseConfig, cfg.Quota)
+	if err != nil {
+		logger.Error("failed to connect to database", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	defer db.Close()
+
+	rewrapped, err := rewrap(context.Background(), logger, db, keyring)
+	logger.Info("rewrap finished",
+		slog.Int("rewrapped", rewrapped),
+		slog.String("active_key_id", keyring.ActiveKeyId()),
+	)
+
+	if err != nil {
+		logger.Error("failed to rewrap keys", slog.String("error", err.Error()))
+		db.Close()
+		os.Exit(1)
+	}
+}
+
+func rewrap(ctx context.Context, logger *slog.Logger, db *postgres.Postgres, keyring *encryptedstorage.Keyring) (int, error) {
+	rewrapped := 0
+
+	for {
+		keys, err := db.GetFileKeys(ctx, keyring.ActiveKeyId(), batchSize)
+		if err != nil {
+			return rewrapped, err
+		}
+
+		if len(keys) == 0 {
+			return rewrapped, nil
+		}
+
+		for _, key := range keys {
+			keyId, wrappedKey, err := keyring.Rewrap(key.KeyId, key.WrappedKey)
+			if err != nil {
+				return rewrapped, err
+			}
+
+			_, err = db.UpdateFileKey(ctx, key.Id, key.KeyId, database.FileKey{
+				KeyId:      keyId,
+				WrappedKey: wrappedKey,
+			})
+			if errors.Is(err, database.ErrorNotFound) {
+				// deleted or rewrapped concurrently
+				continue
+			}
+			if err != nil {
+				return rewrapped, err
+			}
+
+			logger.Debug("file key rewrapped",
+				slog.Int64("file_id", key.Id),
+				slog.String("from_key_id", key.KeyId),
+			)
+			rewrapped++
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package access
+
+import (
+	"context"
+	"file-service/m/internal/database"
+	"slices"
+)
+
+type Db interface {
+	GetFilePermissions(ctx context.Context, fileId int64, username string, groups []string) ([]string, error)
+}
+
+// Allowed reports whether user may act on file with permission. Owners and
+// administrators may do anything, everybody else needs a grant on the file
+// for themselves or one of their groups. Write access includes read access.
+//
+// Handlers answer a denied request like one for a missing file, so that ids
+// of other users' files cannot be probed.
+func Allowed(ctx context.Context, db Db, user *database.User, file *database.File, permission string) (bool, error) {
+	if IsOwner(user, file) {
+		return true, nil
+	}
+
+	permissions, err := db.GetFilePermissions(ctx, file.Id, user.Username, user.Groups)
+	if err != nil {
+		return false, err
+	}
+
+	if slices.Contains(permissions, database.PermissionWrite) {
+		return true, nil
+	}
+
+	return permission == database.PermissionRead && slices.Contains(permissions, database.PermissionRead), nil
+}
+
+// IsOwner reports whether user owns file or is an administrator. Only they
+// may change who else has access.
+func IsOwner(user *database.User, file *database.File) bool {
+	return user.IsAdmin() || (file.Owner != "" && file.Owner == user.Username)
+}
@@ -0,0 +1,77 @@
+package access_test
+
+import (
+	"context"
+	"errors"
+	"file-service/m/internal/access"
+	"file-service/m/internal/database"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type grants map[string][]string
+
+func (g grants) GetFilePermissions(_ context.Context, fileId int64, username string, groups []string) ([]string, error) {
+	if username == "broken" {
+		return nil, errors.New("error")
+	}
+
+	permissions := g["user:"+username]
+	for _, group := range groups {
+		permissions = append(permissions, g["group:"+group]...)
+	}
+
+	return permissions, nil
+}
+
+func TestAllowed(t *testing.T) {
+	db := grants{
+		"user:reader":  {database.PermissionRead},
+		"user:writer":  {database.PermissionWrite},
+		"group:team":   {database.PermissionRead},
+		"group:admins": {database.PermissionWrite},
+	}
+
+	file := &database.File{Id: 1, Owner: "alice"}
+
+	tests := []struct {
+		name       string
+		user       *database.User
+		permission string
+		allowed    bool
+	}{
+		{"owner", &database.User{Username: "alice"}, database.PermissionWrite, true},
+		{"admin", &database.User{Username: "root", Role: database.RoleAdmin}, database.PermissionWrite, true},
+		{"stranger read", &database.User{Username: "mallory"}, database.PermissionRead, false},
+		{"reader read", &database.User{Username: "reader"}, database.PermissionRead, true},
+		{"reader write", &database.User{Username: "reader"}, database.PermissionWrite, false},
+		{"writer read", &database.User{Username: "writer"}, database.PermissionRead, true},
+		{"writer write", &database.User{Username: "writer"}, database.PermissionWrite, true},
+		{"group read", &database.User{Username: "bob", Groups: []string{"team"}}, database.PermissionRead, true},
+		{"group write", &database.User{Username: "bob", Groups: []string{"team"}}, database.PermissionWrite, false},
+		{"second group write", &database.User{Username: "bob", Groups: []string{"team", "admins"}}, database.PermissionWrite, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := access.Allowed(context.Background(), db, tt.user, file, tt.permission)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.allowed, allowed)
+		})
+	}
+
+	t.Run("unowned file", func(t *testing.T) {
+		allowed, err := access.Allowed(context.Background(), db, &database.User{Username: ""}, &database.File{Id: 2}, database.PermissionRead)
+
+		assert.NoError(t, err)
+		assert.False(t, allowed)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		_, err := access.Allowed(context.Background(), db, &database.User{Username: "broken"}, file, database.PermissionRead)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,99 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+)
+
+const (
+	ScopeFileRead   = "file:read"
+	ScopeFileWrite  = "file:write"
+	ScopeFileDelete = "file:delete"
+	ScopeKeyManage  = "key:manage"
+	ScopeAdmin      = "admin"
+
+	tokenPrefix  = "fsk_"
+	prefixSize   = 6
+	secretSize   = 32
+	prefixLength = 2 * prefixSize
+)
+
+var Scopes = []string{
+	ScopeFileRead,
+	ScopeFileWrite,
+	ScopeFileDelete,
+	ScopeKeyManage,
+	ScopeAdmin,
+}
+
+var (
+	ErrorInvalidKey   = errors.New("invalid api key")
+	ErrorInvalidScope = errors.New("invalid scope")
+)
+
+// Generate returns a new key in the "fsk_<prefix>_<secret>" form together
+// with the prefix it is looked up by and the hash that gets stored. The key
+// itself is only ever shown to the client.
+func Generate() (key string, prefix string, hash string, err error) {
+	buf := make([]byte, prefixSize+secretSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = hex.EncodeToString(buf[:prefixSize])
+	key = tokenPrefix + prefix + "_" + hex.EncodeToString(buf[prefixSize:])
+
+	return key, prefix, Hash(key), nil
+}
+
+// IsKey reports whether token looks like an API key rather than another kind
+// of bearer token.
+func IsKey(token string) bool {
+	return strings.HasPrefix(token, tokenPrefix)
+}
+
+// Prefix extracts the lookup prefix from a key.
+func Prefix(key string) (string, error) {
+	rest, ok := strings.CutPrefix(key, tokenPrefix)
+	if !ok {
+		return "", ErrorInvalidKey
+	}
+
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(prefix) != prefixLength || len(secret) != 2*secretSize {
+		return "", ErrorInvalidKey
+	}
+
+	return prefix, nil
+}
+
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Matches reports whether key hashes to hash.
+func Matches(key string, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(key)), []byte(hash)) == 1
+}
+
+// ValidateScopes checks that scopes is a non-empty list of known scopes.
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return ErrorInvalidScope
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return ErrorInvalidScope
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package apikey_test
+
+import (
+	"file-service/m/internal/apikey"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	key, prefix, hash, err := apikey.Generate()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(key, "fsk_"+prefix+"_"))
+	assert.NotContains(t, hash, key)
+	assert.True(t, apikey.Matches(key, hash))
+	assert.False(t, apikey.Matches(key+"0", hash))
+
+	parsed, err := apikey.Prefix(key)
+	require.NoError(t, err)
+	assert.Equal(t, prefix, parsed)
+
+	other, _, _, err := apikey.Generate()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
+
+func TestPrefix(t *testing.T) {
+	key, _, _, err := apikey.Generate()
+	require.NoError(t, err)
+
+	for _, invalid := range []string{
+		"",
+		"fsk_",
+		strings.TrimPrefix(key, "fsk_"),
+		key[:len(key)-1],
+		strings.Replace(key, "_", "-", 2),
+	} {
+		_, err := apikey.Prefix(invalid)
+		assert.ErrorIs(t, err, apikey.ErrorInvalidKey, invalid)
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	assert.NoError(t, apikey.ValidateScopes([]string{apikey.ScopeFileRead, apikey.ScopeFileWrite}))
+	assert.ErrorIs(t, apikey.ValidateScopes(nil), apikey.ErrorInvalidScope)
+	assert.ErrorIs(t, apikey.ValidateScopes([]string{"file:*"}), apikey.ErrorInvalidScope)
+}
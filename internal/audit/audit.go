@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+)
+
+// Actions of the audited file operations.
+const (
+	ActionUpload   = "upload"
+	ActionDownload = "download"
+	ActionTrash    = "trash"
+	ActionRestore  = "restore"
+	ActionDelete   = "delete"
+)
+
+// Results of the audited operations.
+const (
+	ResultSuccess  = "success"
+	ResultDenied   = "denied"
+	ResultNotFound = "not_found"
+	ResultFailure  = "failure"
+)
+
+// ActorAnonymous is the actor of requests without a user, such as those
+// made with signed URLs.
+const ActorAnonymous = "anonymous"
+
+// Result classifies the status of the response to an audited request.
+func Result(status int) string {
+	switch {
+	case status < http.StatusBadRequest:
+		return ResultSuccess
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ResultDenied
+	case status == http.StatusNotFound || status == http.StatusGone:
+		return ResultNotFound
+	default:
+		return ResultFailure
+	}
+}
+
+// Details is what only the middlewares and the handler inside the audit
+// middleware know: the caller they authenticated, the file the request acted
+// on and the bytes it transferred.
+type Details struct {
+	Actor        string
+	FileId       int64
+	FilePublicId string
+	Bytes        int64
+}
+
+type key struct{}
+
+// NewContext returns ctx with empty details for the middlewares and the
+// handler to fill in with SetActor, SetFile and SetBytes.
+func NewContext(ctx context.Context) (context.Context, *Details) {
+	details := &Details{}
+	return context.WithValue(ctx, key{}, details), details
+}
+
+// SetActor records the caller, for requests that are authenticated after the
+// audit middleware ran.
+func SetActor(ctx context.Context, actor string) {
+	if details, ok := ctx.Value(key{}).(*Details); ok {
+		details.Actor = actor
+	}
+}
+
+// SetFile records the file the request acted on. It does nothing for
+// requests that are not audited, as do the other setters.
+func SetFile(ctx context.Context, id int64, publicId string) {
+	if details, ok := ctx.Value(key{}).(*Details); ok {
+		details.FileId = id
+		details.FilePublicId = publicId
+	}
+}
+
+// SetBytes records the size of the file that was uploaded or downloaded.
+func SetBytes(ctx context.Context, n int64) {
+	if details, ok := ctx.Value(key{}).(*Details); ok {
+		details.Bytes = n
+	}
+}
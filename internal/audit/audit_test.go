@@ -0,0 +1,43 @@
+package audit_test
+
+import (
+	"context"
+	"file-service/m/internal/audit"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResult(t *testing.T) {
+	tests := []struct {
+		status int
+		result string
+	}{
+		{http.StatusOK, audit.ResultSuccess},
+		{http.StatusCreated, audit.ResultSuccess},
+		{http.StatusUnauthorized, audit.ResultDenied},
+		{http.StatusForbidden, audit.ResultDenied},
+		{http.StatusNotFound, audit.ResultNotFound},
+		{http.StatusBadRequest, audit.ResultFailure},
+		{http.StatusInsufficientStorage, audit.ResultFailure},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.result, audit.Result(tt.status), tt.status)
+	}
+}
+
+func TestDetails(t *testing.T) {
+	ctx, details := audit.NewContext(context.Background())
+
+	audit.SetFile(ctx, 7, "0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f")
+	audit.SetBytes(ctx, 42)
+
+	assert.Equal(t, &audit.Details{FileId: 7, FilePublicId: "0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f", Bytes: 42}, details)
+
+	assert.NotPanics(t, func() {
+		audit.SetFile(context.Background(), 7, "")
+		audit.SetBytes(context.Background(), 42)
+	})
+}
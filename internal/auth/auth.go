@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"errors"
+	"regexp"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	MinPasswordLength = 8
+	// bcrypt ignores everything after the first 72 bytes
+	MaxPasswordLength = 72
+)
+
+var (
+	ErrorInvalidUsername  = errors.New("invalid username")
+	ErrorInvalidGroupName = errors.New("invalid group name")
+	ErrorInvalidPassword  = errors.New("invalid password")
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// dummyHash is compared against when a user does not exist, so that unknown
+// and known usernames take the same time to reject.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("file-service"), bcrypt.DefaultCost)
+
+func ValidateUsername(username string) error {
+	if !namePattern.MatchString(username) {
+		return ErrorInvalidUsername
+	}
+
+	return nil
+}
+
+func ValidateGroupName(group string) error {
+	if !namePattern.MatchString(group) {
+		return ErrorInvalidGroupName
+	}
+
+	return nil
+}
+
+// HashPassword returns the bcrypt hash of password after checking its length.
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return "", ErrorInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches hash. An empty hash is
+// checked against a dummy hash and never matches.
+func CheckPassword(hash string, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,37 @@
+package auth_test
+
+import (
+	"file-service/m/internal/auth"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := auth.HashPassword("correct horse")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, "correct horse", hash)
+	assert.True(t, auth.CheckPassword(hash, "correct horse"))
+	assert.False(t, auth.CheckPassword(hash, "wrong horse"))
+	assert.False(t, auth.CheckPassword("", "correct horse"))
+	assert.False(t, auth.CheckPassword("", ""))
+
+	_, err = auth.HashPassword("short")
+	assert.ErrorIs(t, err, auth.ErrorInvalidPassword)
+
+	_, err = auth.HashPassword(strings.Repeat("a", auth.MaxPasswordLength+1))
+	assert.ErrorIs(t, err, auth.ErrorInvalidPassword)
+}
+
+func TestValidateUsername(t *testing.T) {
+	for _, name := range []string{"alice", "bob.smith", "ci-bot_1"} {
+		assert.NoError(t, auth.ValidateUsername(name), name)
+	}
+
+	for _, name := range []string{"", "alice smith", "alice:admin", "../alice", strings.Repeat("a", 65)} {
+		assert.ErrorIs(t, auth.ValidateUsername(name), auth.ErrorInvalidUsername, name)
+	}
+}
@@ -0,0 +1,215 @@
+package bandwidth
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// chunkSize is the most that is passed through a throttled stream at once,
+// so that large writes are spread out instead of waiting for the whole
+// write up front.
+const chunkSize = 32 << 10
+
+// Limits are byte rates in bytes per second. A zero rate is unlimited.
+type Limits struct {
+	Global    int64 `json:"global"`
+	PerClient int64 `json:"per_client"`
+}
+
+// Throttle limits the bytes per second of every stream of a client, and of
+// all streams together. The limits can be changed while streams are open.
+type Throttle struct {
+	mu      sync.Mutex
+	limits  Limits
+	global  *rate.Limiter
+	clients map[string]*client
+}
+
+type client struct {
+	limiter *rate.Limiter
+	streams int
+}
+
+func New(limits Limits) *Throttle {
+	return &Throttle{
+		limits:  limits,
+		global:  newLimiter(limits.Global),
+		clients: map[string]*client{},
+	}
+}
+
+func (t *Throttle) Limits() Limits {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.limits
+}
+
+// SetLimits changes the limits, open streams continue with the new ones.
+func (t *Throttle) SetLimits(limits Limits) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.limits = limits
+	setLimit(t.global, limits.Global)
+	for _, c := range t.clients {
+		setLimit(c.limiter, limits.PerClient)
+	}
+}
+
+// Open starts a stream of the client key. The client limit is shared by
+// all open streams of the key and dropped when the last one is closed.
+func (t *Throttle) Open(ctx context.Context, key string) *Stream {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.clients[key]
+	if !ok {
+		c = &client{limiter: newLimiter(t.limits.PerClient)}
+		t.clients[key] = c
+	}
+	c.streams++
+
+	return &Stream{
+		ctx:      ctx,
+		throttle: t,
+		key:      key,
+		limiters: []*rate.Limiter{c.limiter, t.global},
+	}
+}
+
+func (t *Throttle) close(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.clients[key]
+	if !ok {
+		return
+	}
+
+	c.streams--
+	if c.streams <= 0 {
+		delete(t.clients, key)
+	}
+}
+
+// Stream is a throttled transfer. Reads and writes block until the limits
+// allow them or the context of the stream is done.
+type Stream struct {
+	ctx      context.Context
+	throttle *Throttle
+	key      string
+	limiters []*rate.Limiter
+	once     sync.Once
+}
+
+// Close releases the client limit of the stream.
+func (s *Stream) Close() {
+	s.once.Do(func() { s.throttle.close(s.key) })
+}
+
+func (s *Stream) Reader(r io.Reader) io.Reader {
+	return &reader{stream: s, r: r}
+}
+
+func (s *Stream) Writer(w io.Writer) io.Writer {
+	return &writer{stream: s, w: w}
+}
+
+func (s *Stream) wait(n int) error {
+	for _, limiter := range s.limiters {
+		if err := waitN(s.ctx, limiter, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type reader struct {
+	stream *Stream
+	r      io.Reader
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) > chunkSize {
+		p = p[:chunkSize]
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.stream.wait(n); werr != nil {
+			return n, werr
+		}
+	}
+
+	return n, err
+}
+
+type writer struct {
+	stream *Stream
+	w      io.Writer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), chunkSize)]
+
+		if err := w.stream.wait(len(chunk)); err != nil {
+			return written, err
+		}
+
+		n, err := w.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+
+		p = p[n:]
+	}
+
+	return written, nil
+}
+
+// waitN waits for n bytes, in portions of at most the burst of the limiter
+// since the limits may have changed since the bytes were counted.
+func waitN(ctx context.Context, limiter *rate.Limiter, n int) error {
+	for n > 0 {
+		if limiter.Limit() == rate.Inf {
+			return nil
+		}
+
+		k := min(n, limiter.Burst())
+		if err := limiter.WaitN(ctx, k); err != nil {
+			return err
+		}
+
+		n -= k
+	}
+
+	return nil
+}
+
+// newLimiter returns a limiter of bytesPerSecond with a burst of one second
+// worth of bytes.
+func newLimiter(bytesPerSecond int64) *rate.Limiter {
+	if bytesPerSecond <= 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(bytesPerSecond))
+}
+
+func setLimit(limiter *rate.Limiter, bytesPerSecond int64) {
+	if bytesPerSecond <= 0 {
+		limiter.SetLimit(rate.Inf)
+		return
+	}
+
+	limiter.SetBurst(int(bytesPerSecond))
+	limiter.SetLimit(rate.Limit(bytesPerSecond))
+}
@@ -0,0 +1,101 @@
+package bandwidth
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamWriter(t *testing.T) {
+	throttle := New(Limits{PerClient: 64 << 10})
+	stream := throttle.Open(context.Background(), "alice")
+	defer stream.Close()
+
+	var buf bytes.Buffer
+	start := time.Now()
+
+	// the first second is covered by the burst, the rest has to wait
+	n, err := stream.Writer(&buf).Write(make([]byte, 96<<10))
+	require.NoError(t, err)
+
+	assert.Equal(t, 96<<10, n)
+	assert.Equal(t, 96<<10, buf.Len())
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
+
+func TestStreamReader(t *testing.T) {
+	throttle := New(Limits{Global: 64 << 10})
+	stream := throttle.Open(context.Background(), "alice")
+	defer stream.Close()
+
+	start := time.Now()
+
+	data, err := io.ReadAll(stream.Reader(bytes.NewReader(make([]byte, 96<<10))))
+	require.NoError(t, err)
+
+	assert.Len(t, data, 96<<10)
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
+
+func TestUnlimited(t *testing.T) {
+	throttle := New(Limits{})
+	stream := throttle.Open(context.Background(), "alice")
+	defer stream.Close()
+
+	start := time.Now()
+
+	_, err := stream.Writer(io.Discard).Write(make([]byte, 8<<20))
+	require.NoError(t, err)
+
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestSetLimits(t *testing.T) {
+	throttle := New(Limits{PerClient: 1 << 10})
+	stream := throttle.Open(context.Background(), "alice")
+	defer stream.Close()
+
+	throttle.SetLimits(Limits{Global: 1 << 20})
+	assert.Equal(t, Limits{Global: 1 << 20}, throttle.Limits())
+
+	// the open stream is no longer limited per client
+	start := time.Now()
+
+	_, err := stream.Writer(io.Discard).Write(make([]byte, 512<<10))
+	require.NoError(t, err)
+
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestContextCanceled(t *testing.T) {
+	throttle := New(Limits{PerClient: 1 << 10})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	stream := throttle.Open(ctx, "alice")
+	defer stream.Close()
+
+	_, err := stream.Writer(io.Discard).Write(make([]byte, 4<<10))
+	assert.Error(t, err)
+}
+
+func TestClose(t *testing.T) {
+	throttle := New(Limits{PerClient: 1 << 10})
+
+	first := throttle.Open(context.Background(), "alice")
+	second := throttle.Open(context.Background(), "alice")
+	assert.Len(t, throttle.clients, 1)
+
+	first.Close()
+	first.Close()
+	assert.Len(t, throttle.clients, 1)
+
+	second.Close()
+	assert.Empty(t, throttle.clients)
+}
@@ -0,0 +1,86 @@
+// Package clientip finds the address of the client of a request, which
+// behind reverse proxies is not the peer of the connection.
+package clientip
+
+import (
+	"file-service/m/internal/requestctx"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Get returns the client address the client IP middleware resolved for r,
+// and the peer of the connection when it did not run. Addresses are in the
+// form signed URLs are bound to.
+func Get(r *http.Request) string {
+	if ip, ok := requestctx.ClientIP(r.Context()); ok {
+		return ip
+	}
+
+	return Resolve(r, nil)
+}
+
+// Resolve returns the address of the client of r. The X-Forwarded-For and
+// X-Real-IP headers are only believed when the peer is one of the trusted
+// proxies, as anyone else can set them. X-Forwarded-For is read from the
+// right, each trusted proxy vouching for the entry before it, up to the
+// first address that is not a trusted proxy.
+func Resolve(r *http.Request, trusted []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	peer, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+
+	peer = peer.Unmap()
+	if !isTrusted(peer, trusted) {
+		return peer.String()
+	}
+
+	if forwarded := forwardedFor(r); len(forwarded) > 0 {
+		client := peer
+		for i := len(forwarded) - 1; i >= 0; i-- {
+			ip, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+			if err != nil {
+				break
+			}
+
+			client = ip.Unmap()
+			if !isTrusted(client, trusted) {
+				break
+			}
+		}
+
+		return client.String()
+	}
+
+	if ip, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return ip.Unmap().String()
+	}
+
+	return peer.String()
+}
+
+func forwardedFor(r *http.Request) []string {
+	var forwarded []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(value, ",")...)
+	}
+
+	return forwarded
+}
+
+func isTrusted(ip netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
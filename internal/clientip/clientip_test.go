@@ -0,0 +1,67 @@
+package clientip_test
+
+import (
+	"file-service/m/internal/clientip"
+	"file-service/m/internal/requestctx"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		realIP       string
+		expected     string
+	}{
+		{"direct", "198.51.100.7:1234", nil, "", "198.51.100.7"},
+		{"untrusted peer", "198.51.100.7:1234", []string{"203.0.113.9"}, "203.0.113.9", "198.51.100.7"},
+		{"trusted proxy", "192.0.2.1:1234", []string{"203.0.113.9"}, "", "203.0.113.9"},
+		{"proxy chain", "10.0.0.2:1234", []string{"203.0.113.9, 10.0.0.3"}, "", "203.0.113.9"},
+		{"spoofed entry", "10.0.0.2:1234", []string{"1.2.3.4, 203.0.113.9"}, "", "203.0.113.9"},
+		{"several headers", "10.0.0.2:1234", []string{"1.2.3.4", "203.0.113.9, 10.0.0.3"}, "", "203.0.113.9"},
+		{"invalid entry", "10.0.0.2:1234", []string{"203.0.113.9, garbage, 10.0.0.3"}, "", "10.0.0.3"},
+		{"only proxies", "10.0.0.2:1234", []string{"10.0.0.4, 10.0.0.3"}, "", "10.0.0.4"},
+		{"real ip", "192.0.2.1:1234", nil, "203.0.113.9", "203.0.113.9"},
+		{"invalid real ip", "192.0.2.1:1234", nil, "garbage", "192.0.2.1"},
+		{"mapped address", "[::ffff:198.51.100.7]:1234", nil, "", "198.51.100.7"},
+		{"no port", "198.51.100.7", nil, "", "198.51.100.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			assert.Equal(t, tt.expected, clientip.Resolve(r, trusted))
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.9")
+
+	// without the middleware nobody is trusted
+	assert.Equal(t, "192.0.2.1", clientip.Get(r))
+
+	r = r.WithContext(requestctx.WithClientIP(r.Context(), "203.0.113.9"))
+	assert.Equal(t, "203.0.113.9", clientip.Get(r))
+}
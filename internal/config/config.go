@@ -2,38 +2,223 @@ package config
 
 import (
 	"log"
+	"net/netip"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// DatabaseConfig configures the connection. QueryTimeout bounds every
+// database operation of a request, zero leaves them unbounded.
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	Name     string
+	Host         string
+	Port         string
+	User         string
+	Password     string
+	Name         string
+	QueryTimeout time.Duration
+}
+
+// StorageTimeoutConfig bounds the operations on the storage backend, zero
+// leaves them unbounded. Saves and reads copy whole files, so their limits
+// have to fit the largest files that are expected.
+type StorageTimeoutConfig struct {
+	Save   time.Duration
+	Get    time.Duration
+	Delete time.Duration
 }
 
+// HTTPServerConfig configures the listener. PublicURL is where clients reach
+// the service, e.g. "https://files.example.com", and is put in front of the
+// links it hands out. Without it the links are relative.
 type HTTPServerConfig struct {
 	Address         string
 	Timeout         time.Duration
 	IdleTimeout     time.Duration
 	ShutdownTimeout time.Duration
+	PublicURL       string
+}
+
+// AdminServerConfig is the listener of operational endpoints such as
+// metrics, kept apart from the API. An empty address disables it.
+type AdminServerConfig struct {
+	Address string
 }
 
+// HealthConfig configures the readiness checks. The free space thresholds
+// apply to the file system of the storage path, zero disables them.
+// ShutdownDelay is how long the service reports not ready before it stops
+// accepting connections, so that load balancers can take it out first.
+type HealthConfig struct {
+	CheckTimeout   time.Duration
+	MinFreeBytes   int64
+	MinFreePercent float64
+	ShutdownDelay  time.Duration
+}
+
+// TracingConfig configures the export of traces over OTLP/HTTP. An empty
+// endpoint disables the export, trace context is still propagated.
+type TracingConfig struct {
+	Endpoint    string
+	Insecure    bool
+	ServiceName string
+	SampleRatio float64
+}
+
+const (
+	AuthModeBasic = "basic"
+	AuthModeJWT   = "jwt"
+)
+
+// AuthConfig selects how callers authenticate besides API keys: with the
+// passwords of the users table in basic mode or with JWTs of an external
+// identity provider in jwt mode.
+//
+// User and Password are the bootstrap administrator. It is created on start
+// when User is set and no user of that name exists yet, later changes of
+// Password are not applied.
 type AuthConfig struct {
+	Mode     string
 	User     string
 	Password string
+	JWT      JWTConfig
+}
+
+// JWTConfig describes the tokens accepted in jwt mode. The signing keys are
+// read from JWKSFile or fetched from JWKSURL and refreshed every
+// JWKSRefreshInterval. Issuer and Audience are only checked when set. The
+// names in the roles claim are mapped to the service roles with AdminRole,
+// WriterRole and ReaderRole, tokens without any of them get the reader role.
+type JWTConfig struct {
+	JWKSURL             string
+	JWKSFile            string
+	JWKSRefreshInterval time.Duration
+	Issuer              string
+	Audience            string
+	UserClaim           string
+	RolesClaim          string
+	AdminRole           string
+	WriterRole          string
+	ReaderRole          string
+}
+
+// EncryptionConfig enables encryption at rest when MasterKeys is set.
+// MasterKeys has the "id:base64key,id:base64key" form and ActiveKeyId picks
+// the key new files are wrapped with.
+type EncryptionConfig struct {
+	MasterKeys  string
+	ActiveKeyId string
+}
+
+// CompressionConfig enables gzip compression of compressible files that are
+// at least MinSize bytes large.
+type CompressionConfig struct {
+	Enabled bool
+	MinSize int64
+}
+
+// QuotaConfig is the default quota of owners that have no limits of their
+// own in the quotas table. Zero means unlimited.
+type QuotaConfig struct {
+	MaxBytes int64
+	MaxFiles int64
+}
+
+// PolicyConfig overrides the role required for permanent deletion and for
+// restoring files from the trash, both admin by default.
+type PolicyConfig struct {
+	DeleteRole  string
+	RestoreRole string
+}
+
+// FileIdConfig controls how files are addressed in URLs. They are addressed
+// by their public UUID, AcceptLegacyIds additionally accepts the sequential
+// ids used before until clients have migrated.
+type FileIdConfig struct {
+	AcceptLegacyIds bool
+}
+
+// SignedURLConfig enables signed download and upload URLs when Key, a base64 encoded
+// secret of at least 32 bytes, is set. URLs expire after DefaultTTL unless
+// the client asks for another lifetime of at most MaxTTL.
+type SignedURLConfig struct {
+	Key        string
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+}
+
+// RateLimitConfig limits requests with token buckets that refill at the
+// given rate per second and hold up to the burst. IP limits every request
+// by client address, User every authenticated request by user, and Upload
+// and Download apply on top of them to file transfers. MaxConcurrentUploads
+// caps the uploads a client runs at once. A zero rate or maximum disables
+// the respective limit. Clients are told apart by the address the
+// TrustedProxies forwarded for, or by the peer address when it is not one
+// of them.
+type RateLimitConfig struct {
+	IPRate               float64
+	IPBurst              int
+	UserRate             float64
+	UserBurst            int
+	UploadRate           float64
+	UploadBurst          int
+	DownloadRate         float64
+	DownloadBurst        int
+	MaxConcurrentUploads int
+	TrustedProxies       []netip.Prefix
+}
+
+// BandwidthConfig are the initial byte rate limits of uploads and
+// downloads in bytes per second, per client and across all of them. Zero
+// is unlimited. The limits can be changed at runtime.
+type BandwidthConfig struct {
+	Global    int64
+	PerClient int64
+}
+
+// WebhookConfig configures the delivery of file events to webhooks. Events
+// are picked up every PollInterval in batches of BatchSize, each request
+// is bounded by Timeout. Failed deliveries are retried after BackoffBase,
+// doubled with every attempt up to BackoffMax, and given up after
+// MaxAttempts.
+type WebhookConfig struct {
+	PollInterval time.Duration
+	Timeout      time.Duration
+	MaxAttempts  int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	BatchSize    int
+}
+
+// EventsConfig configures the stream of file events. A comment is sent
+// every HeartbeatInterval so that proxies keep idle streams open.
+type EventsConfig struct {
+	HeartbeatInterval time.Duration
 }
 
 type Config struct {
-	Environment    string
-	HttpServer     HTTPServerConfig
-	DatabaseConfig DatabaseConfig
-	StoragePath    string
-	AuthConfig     AuthConfig
+	Environment      string
+	HttpServer       HTTPServerConfig
+	DatabaseConfig   DatabaseConfig
+	StoragePath      string
+	StorageTimeout   StorageTimeoutConfig
+	AuthConfig       AuthConfig
+	EncryptionConfig EncryptionConfig
+	Compression      CompressionConfig
+	Quota            QuotaConfig
+	Policy           PolicyConfig
+	FileId           FileIdConfig
+	SignedURL        SignedURLConfig
+	RateLimit        RateLimitConfig
+	Bandwidth        BandwidthConfig
+	AdminServer      AdminServerConfig
+	Tracing          TracingConfig
+	Health           HealthConfig
+	Webhook          WebhookConfig
+	Events           EventsConfig
 }
 
 func NewConfig() *Config {
@@ -60,18 +245,104 @@ func NewConfig() *Config {
 			Timeout:         parseTimeDurationFromEnv("HTTP_SERVER_TIMEOUT", "10s"),
 			IdleTimeout:     parseTimeDurationFromEnv("HTTP_SERVER_IDLE_TIMEOUT", "120s"),
 			ShutdownTimeout: parseTimeDurationFromEnv("HTTP_SERVER_SHUTDOWN_TIMEOUT", "10s"),
+			PublicURL:       getOptionalEnv("HTTP_SERVER_PUBLIC_URL"),
 		},
 		DatabaseConfig: DatabaseConfig{
-			Host:     getEnv("POSTGRES_HOST", "localhost"),
-			Port:     getEnv("POSTGRES_PORT", "5432"),
-			User:     getEnv("POSTGRES_USER", ""),
-			Password: getEnv("POSTGRES_PASSWORD", ""),
-			Name:     getEnv("POSTGRES_NAME", "file-service"),
+			Host:         getEnv("POSTGRES_HOST", "localhost"),
+			Port:         getEnv("POSTGRES_PORT", "5432"),
+			User:         getEnv("POSTGRES_USER", ""),
+			Password:     getEnv("POSTGRES_PASSWORD", ""),
+			Name:         getEnv("POSTGRES_NAME", "file-service"),
+			QueryTimeout: parseTimeDurationFromEnv("POSTGRES_QUERY_TIMEOUT", "5s"),
 		},
 		StoragePath: getEnv("STORAGE_PATH", "./data/files"),
+		StorageTimeout: StorageTimeoutConfig{
+			Save:   parseTimeDurationFromEnv("STORAGE_SAVE_TIMEOUT", "5m"),
+			Get:    parseTimeDurationFromEnv("STORAGE_GET_TIMEOUT", "5m"),
+			Delete: parseTimeDurationFromEnv("STORAGE_DELETE_TIMEOUT", "30s"),
+		},
 		AuthConfig: AuthConfig{
-			User:     getEnv("AUTH_USER", ""),
-			Password: getEnv("AUTH_PASSWORD", ""),
+			Mode:     getEnv("AUTH_MODE", AuthModeBasic),
+			User:     getOptionalEnv("AUTH_USER"),
+			Password: getOptionalEnv("AUTH_PASSWORD"),
+			JWT: JWTConfig{
+				JWKSURL:             getOptionalEnv("AUTH_JWKS_URL"),
+				JWKSFile:            getOptionalEnv("AUTH_JWKS_FILE"),
+				JWKSRefreshInterval: parseTimeDurationFromEnv("AUTH_JWKS_REFRESH_INTERVAL", "15m"),
+				Issuer:              getOptionalEnv("AUTH_JWT_ISSUER"),
+				Audience:            getOptionalEnv("AUTH_JWT_AUDIENCE"),
+				UserClaim:           getEnv("AUTH_JWT_USER_CLAIM", "sub"),
+				RolesClaim:          getEnv("AUTH_JWT_ROLES_CLAIM", "roles"),
+				AdminRole:           getEnv("AUTH_JWT_ADMIN_ROLE", "admin"),
+				WriterRole:          getEnv("AUTH_JWT_WRITER_ROLE", "writer"),
+				ReaderRole:          getEnv("AUTH_JWT_READER_ROLE", "reader"),
+			},
+		},
+		EncryptionConfig: EncryptionConfig{
+			MasterKeys:  getOptionalEnv("ENCRYPTION_MASTER_KEYS"),
+			ActiveKeyId: getOptionalEnv("ENCRYPTION_ACTIVE_KEY_ID"),
+		},
+		Compression: CompressionConfig{
+			Enabled: parseBoolFromEnv("COMPRESSION_ENABLED", "false"),
+			MinSize: parseInt64FromEnv("COMPRESSION_MIN_SIZE", "1024"),
+		},
+		Quota: QuotaConfig{
+			MaxBytes: parseInt64FromEnv("QUOTA_DEFAULT_MAX_BYTES", "0"),
+			MaxFiles: parseInt64FromEnv("QUOTA_DEFAULT_MAX_FILES", "0"),
+		},
+		Policy: PolicyConfig{
+			DeleteRole:  getEnv("POLICY_DELETE_ROLE", "admin"),
+			RestoreRole: getEnv("POLICY_RESTORE_ROLE", "admin"),
+		},
+		FileId: FileIdConfig{
+			AcceptLegacyIds: parseBoolFromEnv("FILE_ID_ACCEPT_LEGACY", "true"),
+		},
+		SignedURL: SignedURLConfig{
+			Key:        getOptionalEnv("SIGNED_URL_KEY"),
+			DefaultTTL: parseTimeDurationFromEnv("SIGNED_URL_DEFAULT_TTL", "15m"),
+			MaxTTL:     parseTimeDurationFromEnv("SIGNED_URL_MAX_TTL", "24h"),
+		},
+		RateLimit: RateLimitConfig{
+			IPRate:               parseFloat64FromEnv("RATE_LIMIT_IP_RATE", "50"),
+			IPBurst:              parseIntFromEnv("RATE_LIMIT_IP_BURST", "100"),
+			UserRate:             parseFloat64FromEnv("RATE_LIMIT_USER_RATE", "20"),
+			UserBurst:            parseIntFromEnv("RATE_LIMIT_USER_BURST", "40"),
+			UploadRate:           parseFloat64FromEnv("RATE_LIMIT_UPLOAD_RATE", "2"),
+			UploadBurst:          parseIntFromEnv("RATE_LIMIT_UPLOAD_BURST", "10"),
+			DownloadRate:         parseFloat64FromEnv("RATE_LIMIT_DOWNLOAD_RATE", "10"),
+			DownloadBurst:        parseIntFromEnv("RATE_LIMIT_DOWNLOAD_BURST", "20"),
+			MaxConcurrentUploads: parseIntFromEnv("RATE_LIMIT_MAX_CONCURRENT_UPLOADS", "4"),
+			TrustedProxies:       parsePrefixesFromEnv("RATE_LIMIT_TRUSTED_PROXIES"),
+		},
+		Bandwidth: BandwidthConfig{
+			Global:    parseInt64FromEnv("BANDWIDTH_GLOBAL", "0"),
+			PerClient: parseInt64FromEnv("BANDWIDTH_PER_CLIENT", "0"),
+		},
+		AdminServer: AdminServerConfig{
+			Address: getEnv("ADMIN_SERVER_ADDRESS", "localhost:9090"),
+		},
+		Tracing: TracingConfig{
+			Endpoint:    getOptionalEnv("TRACING_OTLP_ENDPOINT"),
+			Insecure:    parseBoolFromEnv("TRACING_OTLP_INSECURE", "false"),
+			ServiceName: getEnv("TRACING_SERVICE_NAME", "file-service"),
+			SampleRatio: parseFloat64FromEnv("TRACING_SAMPLE_RATIO", "1"),
+		},
+		Health: HealthConfig{
+			CheckTimeout:   parseTimeDurationFromEnv("HEALTH_CHECK_TIMEOUT", "2s"),
+			MinFreeBytes:   parseInt64FromEnv("HEALTH_MIN_FREE_BYTES", "0"),
+			MinFreePercent: parseFloat64FromEnv("HEALTH_MIN_FREE_PERCENT", "5"),
+			ShutdownDelay:  parseTimeDurationFromEnv("HEALTH_SHUTDOWN_DELAY", "5s"),
+		},
+		Webhook: WebhookConfig{
+			PollInterval: parseTimeDurationFromEnv("WEBHOOK_POLL_INTERVAL", "2s"),
+			Timeout:      parseTimeDurationFromEnv("WEBHOOK_TIMEOUT", "10s"),
+			MaxAttempts:  parseIntFromEnv("WEBHOOK_MAX_ATTEMPTS", "10"),
+			BackoffBase:  parseTimeDurationFromEnv("WEBHOOK_BACKOFF_BASE", "30s"),
+			BackoffMax:   parseTimeDurationFromEnv("WEBHOOK_BACKOFF_MAX", "6h"),
+			BatchSize:    parseIntFromEnv("WEBHOOK_BATCH_SIZE", "20"),
+		},
+		Events: EventsConfig{
+			HeartbeatInterval: parseTimeDurationFromEnv("EVENTS_HEARTBEAT_INTERVAL", "15s"),
 		},
 	}
 }
@@ -87,6 +358,82 @@ func parseTimeDurationFromEnv(key string, defaultValue string) time.Duration {
 	return parsedValue
 }
 
+func parseBoolFromEnv(key string, defaultValue string) bool {
+	value := getEnv(key, defaultValue)
+
+	parsedValue, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("failed to parse %s, err: %v", key, err)
+	}
+
+	return parsedValue
+}
+
+func parseInt64FromEnv(key string, defaultValue string) int64 {
+	value := getEnv(key, defaultValue)
+
+	parsedValue, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Fatalf("failed to parse %s, err: %v", key, err)
+	}
+
+	return parsedValue
+}
+
+func parseFloat64FromEnv(key string, defaultValue string) float64 {
+	value := getEnv(key, defaultValue)
+
+	parsedValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Fatalf("failed to parse %s, err: %v", key, err)
+	}
+
+	return parsedValue
+}
+
+func parseIntFromEnv(key string, defaultValue string) int {
+	value := getEnv(key, defaultValue)
+
+	parsedValue, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("failed to parse %s, err: %v", key, err)
+	}
+
+	return parsedValue
+}
+
+// parsePrefixesFromEnv parses an optional comma separated list of addresses
+// and CIDR prefixes, an address standing for itself alone.
+func parsePrefixesFromEnv(key string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, value := range strings.Split(getOptionalEnv(key), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		if !strings.Contains(value, "/") {
+			ip, err := netip.ParseAddr(value)
+			if err != nil {
+				log.Fatalf("failed to parse %s, err: %v", key, err)
+			}
+
+			ip = ip.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			log.Fatalf("failed to parse %s, err: %v", key, err)
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes
+}
+
 func getEnv(key string, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
@@ -98,3 +445,7 @@ func getEnv(key string, defaultValue string) string {
 
 	return defaultValue
 }
+
+func getOptionalEnv(key string) string {
+	return os.Getenv(key)
+}
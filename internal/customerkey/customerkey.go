@@ -0,0 +1,68 @@
+package customerkey
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+)
+
+const (
+	HeaderAlgorithm = "X-Encryption-Customer-Algorithm"
+	HeaderKey       = "X-Encryption-Customer-Key"
+	HeaderKeySHA256 = "X-Encryption-Customer-Key-SHA256"
+
+	AlgorithmAES256 = "AES256"
+	keySize         = 32
+)
+
+var ErrorInvalidKey = errors.New("invalid customer encryption key")
+
+// FromRequest returns the customer supplied encryption key, or nil when the
+// request carries none. The optional SHA256 header lets clients detect a key
+// that was mangled in transit.
+func FromRequest(r *http.Request) ([]byte, error) {
+	algorithm := r.Header.Get(HeaderAlgorithm)
+	encoded := r.Header.Get(HeaderKey)
+
+	if algorithm == "" && encoded == "" {
+		return nil, nil
+	}
+
+	if algorithm != AlgorithmAES256 {
+		return nil, ErrorInvalidKey
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != keySize {
+		return nil, ErrorInvalidKey
+	}
+
+	if checksum := r.Header.Get(HeaderKeySHA256); checksum != "" {
+		sum := sha256.Sum256(key)
+		if subtle.ConstantTimeCompare([]byte(checksum), []byte(base64.StdEncoding.EncodeToString(sum[:]))) != 1 {
+			return nil, ErrorInvalidKey
+		}
+	}
+
+	return key, nil
+}
+
+// Fingerprint identifies a customer key without revealing it. It is what
+// gets stored with the file.
+func Fingerprint(key []byte) string {
+	sum := sha256.Sum256(append([]byte("file-service/customer-key:"), key...))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Matches reports whether key is the one fingerprint was made from.
+func Matches(key []byte, fingerprint string) bool {
+	if key == nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(Fingerprint(key)), []byte(fingerprint)) == 1
+}
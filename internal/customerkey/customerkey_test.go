@@ -0,0 +1,69 @@
+package customerkey_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"file-service/m/internal/customerkey"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromRequest(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+	encoded := base64.StdEncoding.EncodeToString(key)
+	sum := sha256.Sum256(key)
+	checksum := base64.StdEncoding.EncodeToString(sum[:])
+
+	tests := []struct {
+		name      string
+		algorithm string
+		key       string
+		checksum  string
+		expected  []byte
+		err       error
+	}{
+		{name: "no key"},
+		{name: "key", algorithm: "AES256", key: encoded, expected: key},
+		{name: "key with checksum", algorithm: "AES256", key: encoded, checksum: checksum, expected: key},
+		{name: "wrong checksum", algorithm: "AES256", key: encoded, checksum: encoded, err: customerkey.ErrorInvalidKey},
+		{name: "missing algorithm", key: encoded, err: customerkey.ErrorInvalidKey},
+		{name: "unknown algorithm", algorithm: "DES", key: encoded, err: customerkey.ErrorInvalidKey},
+		{name: "missing key", algorithm: "AES256", err: customerkey.ErrorInvalidKey},
+		{name: "short key", algorithm: "AES256", key: base64.StdEncoding.EncodeToString(key[:16]), err: customerkey.ErrorInvalidKey},
+		{name: "not base64", algorithm: "AES256", key: "!!", err: customerkey.ErrorInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.algorithm != "" {
+				r.Header.Set(customerkey.HeaderAlgorithm, tt.algorithm)
+			}
+			if tt.key != "" {
+				r.Header.Set(customerkey.HeaderKey, tt.key)
+			}
+			if tt.checksum != "" {
+				r.Header.Set(customerkey.HeaderKeySHA256, tt.checksum)
+			}
+
+			got, err := customerkey.FromRequest(r)
+
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+	fingerprint := customerkey.Fingerprint(key)
+
+	require.NotContains(t, fingerprint, base64.StdEncoding.EncodeToString(key))
+	assert.True(t, customerkey.Matches(key, fingerprint))
+	assert.False(t, customerkey.Matches(bytes.Repeat([]byte("x"), 32), fingerprint))
+	assert.False(t, customerkey.Matches(nil, fingerprint))
+}
@@ -8,23 +8,307 @@ import (
 var (
 	ErrorNotFound      = errors.New("not found")
 	ErrorAlreadyExists = errors.New("already exists")
+	ErrorQuotaExceeded = errors.New("quota exceeded")
+	ErrorUploadUrlUsed = errors.New("upload url already used")
 )
 
 type FileToSave struct {
-	OriginalName string
-	Name         string
-	Path         string
-	Size         int64
-	StorageType  string
+	Owner          string
+	OriginalName   string
+	Name           string
+	Path           string
+	Size           int64
+	StorageType    string
+	KeyId          string
+	WrappedKey     []byte
+	KeyFingerprint string
+	Encoding       string
+	// UploadNonce identifies the signed upload URL the file was sent with,
+	// which saving the file uses up. It is remembered until UploadExpires.
+	UploadNonce   string
+	UploadExpires time.Time
 }
 
 type File struct {
+	Id             int64
+	PublicId       string
+	Owner          string
+	OriginalName   string
+	Name           string
+	Path           string
+	Size           int
+	StrorageType   string
+	Timestamp      time.Time
+	IsDeleted      bool
+	KeyId          string
+	WrappedKey     []byte
+	KeyFingerprint string
+	Encoding       string
+}
+
+const (
+	RoleReader = "reader"
+	RoleWriter = "writer"
+	RoleAdmin  = "admin"
+)
+
+// User is an account that can authenticate against the service.
+type User struct {
 	Id           int64
-	OriginalName string
+	Username     string
+	PasswordHash string
+	Role         string
+	IsDisabled   bool
+	CreatedAt    time.Time
+	Groups       []string
+}
+
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+const (
+	PermissionRead  = "read"
+	PermissionWrite = "write"
+
+	GranteeUser  = "user"
+	GranteeGroup = "group"
+)
+
+// Grant gives a user or the members of a group access to a file that is not
+// theirs. Write access includes read access.
+type Grant struct {
+	FileId      int64
+	GranteeType string
+	Grantee     string
+	Permission  string
+}
+
+// ApiKey is a credential for non-interactive clients that acts on behalf of
+// a user, limited to its scopes. Only the hash of the key is stored.
+type ApiKey struct {
+	Id         int64
+	UserId     int64
+	Name       string
+	Prefix     string
+	Hash       string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// Expired reports whether the key can no longer be used at now.
+func (k *ApiKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// Share is a public link to a file. Only the hash of its token is stored.
+// MaxDownloads of nil and an empty PasswordHash mean no limit and no
+// password.
+type Share struct {
+	Id             int64
+	FileId         int64
+	TokenHash      string
+	CreatedBy      string
+	PasswordHash   string
+	ExpiresAt      *time.Time
+	MaxDownloads   *int64
+	DownloadCount  int64
+	ViewCount      int64
+	LastAccessedAt *time.Time
+	CreatedAt      time.Time
+}
+
+// Expired reports whether the share can no longer be used at now.
+func (s *Share) Expired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
+}
+
+// Exhausted reports whether the share was downloaded as often as allowed.
+func (s *Share) Exhausted() bool {
+	return s.MaxDownloads != nil && s.DownloadCount >= *s.MaxDownloads
+}
+
+// FileKey is the wrapped data key of an encrypted file.
+type FileKey struct {
+	Id         int64
+	KeyId      string
+	WrappedKey []byte
+}
+
+// Quota limits the storage of an owner. Zero means unlimited.
+type Quota struct {
+	MaxBytes int64
+	MaxFiles int64
+}
+
+// Usage is the storage consumed by an owner and the quota that applies.
+type Usage struct {
+	Quota
+	UsedBytes int64
+	UsedFiles int64
+}
+
+// RemainingBytes returns how many bytes the owner may still store, or -1
+// when there is no byte limit.
+func (u *Usage) RemainingBytes() int64 {
+	if u.MaxBytes == 0 {
+		return -1
+	}
+
+	return max(u.MaxBytes-u.UsedBytes, 0)
+}
+
+// Exhausted reports whether the owner cannot store any further file.
+func (u *Usage) Exhausted() bool {
+	return u.RemainingBytes() == 0 || (u.MaxFiles != 0 && u.UsedFiles >= u.MaxFiles)
+}
+
+// FileStats are the totals of all stored files, those in the trash are
+// counted separately.
+type FileStats struct {
+	Files        int64
+	Bytes        int64
+	TrashedFiles int64
+	TrashedBytes int64
+}
+
+// AuditEvent records a file operation: who performed it, from where, on
+// which file and with what result. FileId and FilePublicId are zero when
+// the request did not get as far as resolving the file.
+type AuditEvent struct {
+	Id           int64
+	Time         time.Time
+	Actor        string
+	Action       string
+	FileId       int64
+	FilePublicId string
+	IP           string
+	UserAgent    string
+	RequestId    string
+	Result       string
+	Status       int
+	Bytes        int64
+}
+
+// AuditFilter selects audit events, empty fields match all of them. Events
+// are returned newest first, before BeforeId when it is set, or oldest
+// first when Chronological is set. A Limit of zero returns all events.
+type AuditFilter struct {
+	Actor         string
+	Action        string
+	Result        string
+	FileId        int64
+	FilePublicId  string
+	Since         time.Time
+	Until         time.Time
+	BeforeId      int64
+	Limit         int
+	Chronological bool
+}
+
+// Types of file events.
+const (
+	FileEventCreated  = "file.created"
+	FileEventTrashed  = "file.trashed"
+	FileEventRestored = "file.restored"
+	FileEventDeleted  = "file.deleted"
+)
+
+var FileEventTypes = []string{
+	FileEventCreated,
+	FileEventTrashed,
+	FileEventRestored,
+	FileEventDeleted,
+}
+
+// FileEvent is a change of a file. Events are written in the transaction of
+// the change, so that they are published if and only if it is committed.
+type FileEvent struct {
+	Id           int64
+	Type         string
+	FileId       int64
+	FilePublicId string
+	Owner        string
 	Name         string
-	Path         string
-	Size         int
-	StrorageType string
-	Timestamp    time.Time
-	IsDeleted    bool
+	Size         int64
+	CreatedAt    time.Time
+}
+
+// FileEventFilter selects the events after AfterId, of Owner unless it is
+// empty. Limit bounds the number of events.
+type FileEventFilter struct {
+	AfterId int64
+	Owner   string
+	Limit   int
+}
+
+// Types of notifications.
+const (
+	NotificationFileEvent  = "file_event"
+	NotificationInvalidate = "invalidate"
+)
+
+// Notification is a message broadcast to every replica. EventId is set for
+// file events and Key for cache invalidations, where an empty Key stands for
+// all cached data.
+type Notification struct {
+	Type    string
+	EventId int64
+	Key     string
+}
+
+// Webhook is a subscription of an URL to file events. An empty EventTypes
+// subscribes to all of them. Secret is the key payloads are signed with.
+type Webhook struct {
+	Id         int64
+	URL        string
+	Secret     string
+	EventTypes []string
+	CreatedBy  string
+	CreatedAt  time.Time
+}
+
+// States of webhook deliveries. Pending deliveries are retried until they
+// succeed or run out of attempts and are dead.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// WebhookDelivery is the delivery of an event to a webhook and the outcome
+// of its last attempt.
+type WebhookDelivery struct {
+	Id             int64
+	WebhookId      int64
+	EventId        int64
+	EventType      string
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// PendingDelivery is a delivery claimed for an attempt, with what it takes to
+// make it.
+type PendingDelivery struct {
+	Id       int64
+	Attempts int
+	URL      string
+	Secret   string
+	Event    FileEvent
+}
+
+// DeliveryAttempt is the outcome of an attempt to deliver. NextAttemptAt is
+// when a pending delivery is retried.
+type DeliveryAttempt struct {
+	Status        string
+	StatusCode    int
+	Error         string
+	NextAttemptAt time.Time
 }
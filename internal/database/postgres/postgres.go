@@ -1,21 +1,206 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"file-service/m/internal/config"
 	"file-service/m/internal/database"
+	"file-service/m/internal/tracing"
 	"fmt"
+	"log/slog"
 	"sync"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// migrations are applied in order on every start, so each statement must be
+// idempotent.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS files (
+		id SERIAL PRIMARY KEY,
+		original_name TEXT NOT NULL,
+		name TEXT NOT NULL UNIQUE,
+		path TEXT NOT NULL,
+		size BIGINT NOT NULL,
+		storage_type TEXT NOT NULL,
+		timestamp TIMESTAMP NOT NULL DEFAULT NOW(),
+		is_deleted BOOLEAN NOT NULL DEFAULT FALSE
+	);`,
+	`CREATE INDEX IF NOT EXISTS files_name_idx ON files (name);`,
+	`ALTER TABLE files
+		ADD COLUMN IF NOT EXISTS key_id TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS wrapped_key BYTEA;`,
+	`CREATE INDEX IF NOT EXISTS files_key_id_idx ON files (key_id);`,
+	`ALTER TABLE files ADD COLUMN IF NOT EXISTS key_fingerprint TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE files ADD COLUMN IF NOT EXISTS encoding TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE files ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT '';`,
+	`CREATE INDEX IF NOT EXISTS files_owner_idx ON files (owner);`,
+	`CREATE TABLE IF NOT EXISTS quotas (
+		owner TEXT PRIMARY KEY,
+		max_bytes BIGINT,
+		max_files BIGINT,
+		used_bytes BIGINT NOT NULL DEFAULT 0,
+		used_files BIGINT NOT NULL DEFAULT 0
+	);`,
+	`CREATE TABLE IF NOT EXISTS users (
+		id BIGSERIAL PRIMARY KEY,
+		username TEXT NOT NULL UNIQUE,
+		password_hash TEXT NOT NULL,
+		is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+		is_disabled BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE TABLE IF NOT EXISTS api_keys (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL UNIQUE,
+		hash TEXT NOT NULL,
+		scopes TEXT[] NOT NULL,
+		expires_at TIMESTAMPTZ,
+		last_used_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);`,
+	`CREATE TABLE IF NOT EXISTS group_members (
+		group_name TEXT NOT NULL,
+		username TEXT NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+		PRIMARY KEY (group_name, username)
+	);`,
+	`CREATE INDEX IF NOT EXISTS group_members_username_idx ON group_members (username);`,
+	`CREATE TABLE IF NOT EXISTS file_acl (
+		file_id INTEGER NOT NULL REFERENCES files (id) ON DELETE CASCADE,
+		grantee_type TEXT NOT NULL CHECK (grantee_type IN ('user', 'group')),
+		grantee TEXT NOT NULL,
+		permission TEXT NOT NULL CHECK (permission IN ('read', 'write')),
+		PRIMARY KEY (file_id, grantee_type, grantee)
+	);`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'writer'
+		CHECK (role IN ('reader', 'writer', 'admin'));`,
+	`DO $$ BEGIN
+		IF EXISTS (SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'users' and column_name = 'is_admin') THEN
+			UPDATE users SET role = 'admin' WHERE is_admin;
+			ALTER TABLE users DROP COLUMN is_admin;
+		END IF;
+	END $$;`,
+	// public_id is what the API exposes instead of the sequential id. Rows
+	// that predate it are backfilled with random ids, new ones get UUIDv7.
+	`ALTER TABLE files ADD COLUMN IF NOT EXISTS public_id UUID NOT NULL DEFAULT gen_random_uuid();`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS files_public_id_idx ON files (public_id);`,
+	`CREATE TABLE IF NOT EXISTS shares (
+		id BIGSERIAL PRIMARY KEY,
+		file_id INTEGER NOT NULL REFERENCES files (id) ON DELETE CASCADE,
+		token_hash TEXT NOT NULL UNIQUE,
+		created_by TEXT NOT NULL,
+		password_hash TEXT NOT NULL DEFAULT '',
+		expires_at TIMESTAMPTZ,
+		max_downloads BIGINT CHECK (max_downloads > 0),
+		download_count BIGINT NOT NULL DEFAULT 0,
+		view_count BIGINT NOT NULL DEFAULT 0,
+		last_accessed_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE INDEX IF NOT EXISTS shares_file_id_idx ON shares (file_id);`,
+	// audit_events has no foreign keys, events outlive the files and users
+	// they refer to
+	`CREATE TABLE IF NOT EXISTS audit_events (
+		id BIGSERIAL PRIMARY KEY,
+		time TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		file_id BIGINT,
+		file_public_id UUID,
+		ip TEXT NOT NULL,
+		user_agent TEXT NOT NULL,
+		request_id TEXT NOT NULL,
+		result TEXT NOT NULL,
+		status INTEGER NOT NULL,
+		bytes BIGINT NOT NULL DEFAULT 0
+	);`,
+	`CREATE INDEX IF NOT EXISTS audit_events_time_idx ON audit_events (time);`,
+	`CREATE INDEX IF NOT EXISTS audit_events_actor_idx ON audit_events (actor);`,
+	`CREATE INDEX IF NOT EXISTS audit_events_file_id_idx ON audit_events (file_id);`,
+	`CREATE INDEX IF NOT EXISTS audit_events_file_public_id_idx ON audit_events (file_public_id);`,
+	`CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+	BEGIN
+		RAISE EXCEPTION 'audit_events is append-only';
+	END $$ LANGUAGE plpgsql;`,
+	`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'audit_events_append_only') THEN
+			CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_events
+				FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
+		END IF;
+	END $$;`,
+	// file_events is the outbox of file changes, dispatched_at is set once
+	// the event was handed to the webhooks
+	`CREATE TABLE IF NOT EXISTS file_events (
+		id BIGSERIAL PRIMARY KEY,
+		type TEXT NOT NULL,
+		file_id BIGINT NOT NULL,
+		file_public_id UUID NOT NULL,
+		owner TEXT NOT NULL,
+		name TEXT NOT NULL,
+		size BIGINT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		dispatched_at TIMESTAMPTZ
+	);`,
+	`CREATE INDEX IF NOT EXISTS file_events_undispatched_idx ON file_events (id) WHERE dispatched_at IS NULL;`,
+	`CREATE INDEX IF NOT EXISTS file_events_owner_idx ON file_events (owner, id);`,
+	`CREATE TABLE IF NOT EXISTS webhooks (
+		id BIGSERIAL PRIMARY KEY,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		event_types TEXT[] NOT NULL,
+		created_by TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id BIGSERIAL PRIMARY KEY,
+		webhook_id BIGINT NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+		event_id BIGINT NOT NULL REFERENCES file_events (id),
+		status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'dead')),
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_status_code INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		UNIQUE (webhook_id, event_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at)
+		WHERE status = 'pending';`,
+	`CREATE TABLE IF NOT EXISTS used_upload_urls (
+		nonce TEXT PRIMARY KEY,
+		expires_at TIMESTAMPTZ NOT NULL
+	);`,
+	`CREATE INDEX IF NOT EXISTS used_upload_urls_expires_idx ON used_upload_urls (expires_at);`,
+}
+
+// fileEventsLock is the advisory lock that orders the file events.
+const fileEventsLock int64 = 0x66696c655f657673
+
+// postgres error codes
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
 )
 
 type Postgres struct {
-	db   *sql.DB
-	once sync.Once
+	db           *sql.DB
+	once         sync.Once
+	defaultQuota config.QuotaConfig
+	queryTimeout time.Duration
+	connString   string
 }
 
-func New(cfg config.DatabaseConfig) (*Postgres, error) {
+// New connects to the database and applies migrations. defaultQuota applies
+// to owners without their own limits in the quotas table.
+func New(cfg config.DatabaseConfig, defaultQuota config.QuotaConfig) (*Postgres, error) {
 	connString := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
 		cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Name)
 
@@ -31,43 +216,31 @@ func New(cfg config.DatabaseConfig) (*Postgres, error) {
 		return nil, fmt.Errorf("failed to ping database: %v", err)
 	}
 
-	stmt, err := db.Prepare(`
-	CREATE TABLE IF NOT EXISTS files (
-		id SERIAL PRIMARY KEY,
-		original_name TEXT NOT NULL,
-		name TEXT NOT NULL UNIQUE,
-		path TEXT NOT NULL,
-		size BIGINT NOT NULL,
-		storage_type TEXT NOT NULL,
-		timestamp TIMESTAMP NOT NULL DEFAULT NOW(),
-		is_deleted BOOLEAN NOT NULL DEFAULT FALSE
-	);`)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to create table: %v", err)
-	}
-
-	_, err = stmt.Exec()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create table: %v", err)
-	}
-
-	stmt, err = db.Prepare(`CREATE INDEX IF NOT EXISTS files_name_idx ON files (name);`)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to create index: %v", err)
-	}
-
-	_, err = stmt.Exec()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create index: %v", err)
+	for _, migration := range migrations {
+		_, err = db.Exec(migration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate database: %v", err)
+		}
 	}
 
 	return &Postgres{
-		db: db,
+		db:           db,
+		defaultQuota: defaultQuota,
+		queryTimeout: cfg.QueryTimeout,
+		connString:   connString,
 	}, nil
 }
 
+// Ping checks that the database is reachable.
+func (p *Postgres) Ping(ctx context.Context) error {
+	return p.db.PingContext(ctx)
+}
+
+// PoolStats returns the statistics of the connection pool.
+func (p *Postgres) PoolStats() sql.DBStats {
+	return p.db.Stats()
+}
+
 func (p *Postgres) Close() error {
 	var errorOnClose error
 
@@ -81,40 +254,146 @@ func (p *Postgres) Close() error {
 	return errorOnClose
 }
 
-func (p *Postgres) SaveFile(file database.FileToSave) (int64, error) {
-	const op = "postgres.InsertFile"
+// SaveFile stores the file metadata and returns the public id of the file.
+func (p *Postgres) SaveFile(ctx context.Context, file database.FileToSave) (_ string, err error) {
+	const op = "postgres.SaveFile"
 
-	query := `INSERT INTO files (name, original_name, path, size, storage_type) VALUES ($1, $2, $3, $4, $5)`
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
 
-	tx, err := p.db.Begin()
+	query := `INSERT INTO files (public_id, owner, name, original_name, path, size, storage_type,
+		key_id, wrapped_key, key_fingerprint, encoding)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id`
+
+	publicId, err := uuid.NewV7()
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(query)
-	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+	if file.UploadNonce != "" {
+		if err := useUploadUrl(ctx, tx, file.UploadNonce, file.UploadExpires); err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
 	}
 
-	_, err = stmt.Exec(file.Name, file.OriginalName, file.Path, file.Size, file.StorageType)
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	var id int64
-	stmt, err = tx.Prepare("SELECT id FROM files WHERE name = $1")
+	err = stmt.QueryRowContext(ctx, publicId, file.Owner, file.Name, file.OriginalName, file.Path, file.Size, file.StorageType,
+		file.KeyId, file.WrappedKey, file.KeyFingerprint, file.Encoding).Scan(&id)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = stmt.QueryRow(file.Name).Scan(&id)
+	// The row is seeded first so that the limits below also apply to the
+	// first file of an owner; concurrent seeds wait for each other.
+	_, err = tx.ExecContext(ctx, `INSERT INTO quotas (owner) VALUES ($1) ON CONFLICT (owner) DO NOTHING`, file.Owner)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The usage is only bumped while the quota still allows it, which keeps
+	// concurrent uploads from overshooting the limits checked by the handler.
+	r, err := tx.ExecContext(ctx, `UPDATE quotas SET
+			used_bytes = used_bytes + $2,
+			used_files = used_files + 1
+		WHERE owner = $1
+			AND (COALESCE(max_bytes, $3) = 0 OR used_bytes + $2 <= COALESCE(max_bytes, $3))
+			AND (COALESCE(max_files, $4) = 0 OR used_files < COALESCE(max_files, $4))`,
+		file.Owner, file.Size, p.defaultQuota.MaxBytes, p.defaultQuota.MaxFiles)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	resultRowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if resultRowsAffected == 0 {
+		return "", fmt.Errorf("%s: %w", op, database.ErrorQuotaExceeded)
+	}
+
+	if err := insertFileEvent(ctx, tx, database.FileEventCreated, id); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	err = tx.Commit()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return publicId.String(), nil
+}
+
+// useUploadUrl marks the signed upload URL with nonce as used, as part of tx.
+// A concurrent upload with the same URL waits for tx and then fails, so at
+// most one file is saved per URL. Nonces are only kept until their URL
+// expires, when it would be rejected anyway.
+func useUploadUrl(ctx context.Context, tx *sql.Tx, nonce string, expires time.Time) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM used_upload_urls WHERE expires_at < NOW()`)
+	if err != nil {
+		return err
+	}
+
+	r, err := tx.ExecContext(ctx, `INSERT INTO used_upload_urls (nonce, expires_at) VALUES ($1, $2)
+		ON CONFLICT (nonce) DO NOTHING`, nonce, expires)
+	if err != nil {
+		return err
+	}
+
+	resultRowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if resultRowsAffected == 0 {
+		return database.ErrorUploadUrlUsed
+	}
+
+	return nil
+}
+
+// IsUploadUrlUsed reports whether a file was saved with the signed upload URL
+// with nonce.
+func (p *Postgres) IsUploadUrlUsed(ctx context.Context, nonce string) (_ bool, err error) {
+	const op = "postgres.IsUploadUrlUsed"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	var used bool
+	err = p.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM used_upload_urls WHERE nonce = $1)`, nonce).Scan(&used)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return used, nil
+}
+
+// GetFileId resolves the public id of a file, trashed or not, to its id.
+func (p *Postgres) GetFileId(ctx context.Context, publicId string) (_ int64, err error) {
+	const op = "postgres.GetFileId"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	var id int64
+	err = p.db.QueryRowContext(ctx, `SELECT id FROM files WHERE public_id = $1`, publicId).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s: %w", op, database.ErrorNotFound)
+	}
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
@@ -122,28 +401,35 @@ func (p *Postgres) SaveFile(file database.FileToSave) (int64, error) {
 	return id, nil
 }
 
-func (p *Postgres) GetFile(id int64, isDeleted bool) (*database.File, error) {
+func (p *Postgres) GetFile(ctx context.Context, id int64, isDeleted bool) (_ *database.File, err error) {
 	const op = "postgres.GetFile"
 
-	query := `SELECT * FROM files WHERE id = $1 and is_deleted = $2`
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `SELECT id, public_id, owner, original_name, name, path, size, storage_type, timestamp, is_deleted,
+		key_id, wrapped_key, key_fingerprint, encoding
+		FROM files WHERE id = $1 and is_deleted = $2`
 
-	tx, err := p.db.Begin()
+	tx, err := p.db.BeginTx(ctx, nil)
 	if err != nil {
 		return &database.File{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(query)
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
 		return &database.File{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	var file database.File
 	err = stmt.
-		QueryRow(id, isDeleted).
+		QueryRowContext(ctx, id, isDeleted).
 		Scan(
 			&file.Id,
+			&file.PublicId,
+			&file.Owner,
 			&file.OriginalName,
 			&file.Name,
 			&file.Path,
@@ -151,8 +437,15 @@ func (p *Postgres) GetFile(id int64, isDeleted bool) (*database.File, error) {
 			&file.StrorageType,
 			&file.Timestamp,
 			&file.IsDeleted,
+			&file.KeyId,
+			&file.WrappedKey,
+			&file.KeyFingerprint,
+			&file.Encoding,
 		)
 
+	if errors.Is(err, sql.ErrNoRows) {
+		return &database.File{}, fmt.Errorf("%s: %w", op, database.ErrorNotFound)
+	}
 	if err != nil {
 		return &database.File{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -165,25 +458,27 @@ func (p *Postgres) GetFile(id int64, isDeleted bool) (*database.File, error) {
 	return &file, nil
 }
 
-func (p *Postgres) SetFileIsDeleted(id int64) (int64, error) {
+func (p *Postgres) SetFileIsDeleted(ctx context.Context, id int64) (_ int64, err error) {
+	const op = "postgres.SetFileIsDeleted"
 
-	const op = "postgres.DeleteFile"
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
 
 	query := `UPDATE files SET is_deleted = true WHERE id = $1 and is_deleted = false`
 
-	tx, err := p.db.Begin()
+	tx, err := p.db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(query)
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	r, err := stmt.Exec(id)
+	r, err := stmt.ExecContext(ctx, id)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
@@ -194,44 +489,381 @@ func (p *Postgres) SetFileIsDeleted(id int64) (int64, error) {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	tx.Commit()
+	if resultRowsAffected == 0 {
+		return 0, fmt.Errorf("%s: %w", op, database.ErrorNotFound)
+	}
+
+	if err := insertFileEvent(ctx, tx, database.FileEventTrashed, id); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return resultRowsAffected, nil
+}
+
+// RestoreFile moves a file out of the trash.
+func (p *Postgres) RestoreFile(ctx context.Context, id int64) (_ int64, err error) {
+	const op = "postgres.RestoreFile"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer tx.Rollback()
+
+	r, err := tx.ExecContext(ctx, `UPDATE files SET is_deleted = false WHERE id = $1 and is_deleted = true`, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resultRowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
 
 	if resultRowsAffected == 0 {
 		return 0, fmt.Errorf("%s: %w", op, database.ErrorNotFound)
 	}
 
+	if err := insertFileEvent(ctx, tx, database.FileEventRestored, id); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
 	return resultRowsAffected, nil
 }
 
-func (p *Postgres) DeleteFile(id int64) (int64, error) {
+func (p *Postgres) DeleteFile(ctx context.Context, id int64) (_ int64, err error) {
 	const op = "postgres.DeleteFile"
 
-	query := `DELETE FROM files WHERE id = $1 and is_deleted = true`
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `DELETE FROM files WHERE id = $1 and is_deleted = true RETURNING owner, size`
 
-	tx, err := p.db.Begin()
+	tx, err := p.db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
 	defer tx.Rollback()
 
-	stmt, err := p.db.Prepare(query)
+	// written first, the row is gone afterwards; rolled back with the
+	// transaction when the file is not in the trash
+	if err := insertFileEvent(ctx, tx, database.FileEventDeleted, id); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	r, err := stmt.Exec(id)
+	var owner string
+	var size int64
+	err = stmt.QueryRowContext(ctx, id).Scan(&owner, &size)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s: %w", op, database.ErrorNotFound)
+	}
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	resultRowsAffected, err := r.RowsAffected()
+	_, err = tx.ExecContext(ctx, `UPDATE quotas SET
+		used_bytes = GREATEST(used_bytes - $2, 0),
+		used_files = GREATEST(used_files - 1, 0)
+		WHERE owner = $1`, owner, size)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return 1, nil
+}
+
+const (
+	// notifyChannel carries the notifications between replicas.
+	notifyChannel = "file_service"
+
+	listenMinReconnect = time.Second
+	listenMaxReconnect = time.Minute
+	// listenPingInterval detects connections that were lost without an
+	// error, which would otherwise leave the listener waiting forever.
+	listenPingInterval = 90 * time.Second
+)
+
+// notification is the payload of a notification.
+type notification struct {
+	Type    string `json:"type"`
+	EventId int64  `json:"event_id,omitempty"`
+	Key     string `json:"key,omitempty"`
+}
+
+// Notify broadcasts n to the listeners of every replica, this one
+// included.
+func (p *Postgres) Notify(ctx context.Context, n database.Notification) (err error) {
+	const op = "postgres.Notify"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	payload, err := json.Marshal(notification(n))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = p.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, notifyChannel, string(payload))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Listen calls fn with the notifications of all replicas until ctx is
+// cancelled, on a connection of its own. The connection is re-established
+// when it is lost. Notifications sent meanwhile are lost, so fn is then
+// called with a file event without id and an invalidation of all keys.
+func (p *Postgres) Listen(ctx context.Context, logger *slog.Logger, fn func(n database.Notification)) error {
+	const op = "postgres.Listen"
+
+	listener := pq.NewListener(p.connString, listenMinReconnect, listenMaxReconnect,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				logger.Warn("notification listener connection failed", slog.String("op", op), slog.Any("error", err))
+			}
+		})
+	defer listener.Close()
+
+	// Listen waits for the connection until the listener is closed
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stop()
+
+	if err := listener.Listen(notifyChannel); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	receive(ctx, logger, listener.Notify, listener.Ping, listenPingInterval, fn)
+
+	return nil
+}
+
+// receive calls fn with the notifications from ch until ctx is cancelled.
+// A nil notification means the connection was re-established. ping checks
+// the connection every pingInterval, in the background so that it cannot
+// hold up the notifications.
+func receive(ctx context.Context, logger *slog.Logger, ch <-chan *pq.Notification, ping func() error, pingInterval time.Duration, fn func(n database.Notification)) {
+	const op = "postgres.receive"
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-ch:
+			if n == nil {
+				// reconnected
+				fn(database.Notification{Type: database.NotificationFileEvent})
+				fn(database.Notification{Type: database.NotificationInvalidate})
+				continue
+			}
+
+			msg, err := decodeNotification(n.Extra)
+			if err != nil {
+				logger.Warn("invalid notification", slog.String("op", op), slog.Any("error", err))
+				continue
+			}
+
+			fn(msg)
+		case <-ticker.C:
+			go ping()
+		}
+	}
+}
+
+func decodeNotification(payload string) (database.Notification, error) {
+	var msg notification
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		return database.Notification{}, err
+	}
+
+	return database.Notification(msg), nil
+}
+
+// insertFileEvent adds an event about the file with the given id to the
+// outbox, as part of tx, and notifies the replicas once tx commits.
+//
+// Event ids are drawn under a lock that is held until tx ends, so that
+// they become visible in the order of their ids. Otherwise an event could
+// commit after one with a greater id, and be skipped by the readers of
+// the log that already moved past it.
+func insertFileEvent(ctx context.Context, tx *sql.Tx, eventType string, id int64) error {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, fileEventsLock); err != nil {
+		return err
+	}
 
+	var eventId int64
+	err := tx.QueryRowContext(ctx, `INSERT INTO file_events (type, file_id, file_public_id, owner, name, size)
+		SELECT $1, id, public_id, owner, original_name, size FROM files WHERE id = $2
+		RETURNING id`, eventType, id).Scan(&eventId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(notification{Type: database.NotificationFileEvent, EventId: eventId})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, notifyChannel, string(payload))
+
+	return err
+}
+
+// GetUsage returns the storage used by owner together with the quota that
+// applies to them.
+func (p *Postgres) GetUsage(ctx context.Context, owner string) (_ *database.Usage, err error) {
+	const op = "postgres.GetUsage"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `SELECT COALESCE(max_bytes, $2), COALESCE(max_files, $3), used_bytes, used_files
+		FROM quotas WHERE owner = $1`
+
+	usage := database.Usage{
+		Quota: database.Quota{
+			MaxBytes: p.defaultQuota.MaxBytes,
+			MaxFiles: p.defaultQuota.MaxFiles,
+		},
+	}
+
+	err = p.db.QueryRowContext(ctx, query, owner, p.defaultQuota.MaxBytes, p.defaultQuota.MaxFiles).
+		Scan(&usage.MaxBytes, &usage.MaxFiles, &usage.UsedBytes, &usage.UsedFiles)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &usage, nil
+}
+
+// SetQuota replaces the limits of the user username, so that the default
+// quota no longer applies to them. The usage is kept.
+func (p *Postgres) SetQuota(ctx context.Context, username string, quota database.Quota) (_ int64, err error) {
+	const op = "postgres.SetQuota"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	return p.exec(ctx, op, `INSERT INTO quotas (owner, max_bytes, max_files)
+		SELECT username, $2, $3 FROM users WHERE username = $1
+		ON CONFLICT (owner) DO UPDATE SET max_bytes = EXCLUDED.max_bytes, max_files = EXCLUDED.max_files`,
+		username, quota.MaxBytes, quota.MaxFiles)
+}
+
+// GetFileStats returns the number and size of all files.
+func (p *Postgres) GetFileStats(ctx context.Context) (_ *database.FileStats, err error) {
+	const op = "postgres.GetFileStats"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `SELECT
+			COUNT(*) FILTER (WHERE NOT is_deleted),
+			COALESCE(SUM(size) FILTER (WHERE NOT is_deleted), 0),
+			COUNT(*) FILTER (WHERE is_deleted),
+			COALESCE(SUM(size) FILTER (WHERE is_deleted), 0)
+		FROM files`
+
+	var stats database.FileStats
+	err = p.db.QueryRowContext(ctx, query).Scan(&stats.Files, &stats.Bytes, &stats.TrashedFiles, &stats.TrashedBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &stats, nil
+}
+
+// GetFileKeys returns the wrapped data keys of encrypted files whose key is
+// not wrapped with activeKeyId, for re-wrapping after a master key rotation.
+// Files encrypted with a customer supplied key are skipped.
+func (p *Postgres) GetFileKeys(ctx context.Context, activeKeyId string, limit int) (_ []database.FileKey, err error) {
+	const op = "postgres.GetFileKeys"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `SELECT id, key_id, wrapped_key FROM files
+		WHERE key_id <> '' and key_id <> $1 and key_fingerprint = ''
+		ORDER BY id LIMIT $2`
+
+	rows, err := p.db.QueryContext(ctx, query, activeKeyId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer rows.Close()
+
+	var keys []database.FileKey
+	for rows.Next() {
+		var key database.FileKey
+		if err := rows.Scan(&key.Id, &key.KeyId, &key.WrappedKey); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// UpdateFileKey replaces the wrapped data key of a file, provided it is
+// still wrapped with oldKeyId.
+func (p *Postgres) UpdateFileKey(ctx context.Context, id int64, oldKeyId string, key database.FileKey) (_ int64, err error) {
+	const op = "postgres.UpdateFileKey"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `UPDATE files SET key_id = $1, wrapped_key = $2 WHERE id = $3 and key_id = $4`
+
+	r, err := p.db.ExecContext(ctx, query, key.KeyId, key.WrappedKey, id, oldKeyId)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	tx.Commit()
+	resultRowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
 
 	if resultRowsAffected == 0 {
 		return 0, fmt.Errorf("%s: %w", op, database.ErrorNotFound)
@@ -239,3 +871,940 @@ func (p *Postgres) DeleteFile(id int64) (int64, error) {
 
 	return resultRowsAffected, nil
 }
+
+// CreateUser inserts a new user and returns its id. An existing username
+// results in database.ErrorAlreadyExists.
+func (p *Postgres) CreateUser(ctx context.Context, user database.User) (_ int64, err error) {
+	const op = "postgres.CreateUser"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `INSERT INTO users (username, password_hash, role) VALUES ($1, $2, $3) RETURNING id`
+
+	var id int64
+	err = p.db.QueryRowContext(ctx, query, user.Username, user.PasswordHash, user.Role).Scan(&id)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return 0, fmt.Errorf("%s: %w", op, database.ErrorAlreadyExists)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (p *Postgres) GetUserByUsername(ctx context.Context, username string) (_ *database.User, err error) {
+	const op = "postgres.GetUserByUsername"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `SELECT id, username, password_hash, role, is_disabled, created_at,
+		ARRAY(SELECT group_name FROM group_members WHERE username = users.username ORDER BY group_name)
+		FROM users WHERE username = $1`
+
+	var user database.User
+	err = p.db.QueryRowContext(ctx, query, username).Scan(
+		&user.Id,
+		&user.Username,
+		&user.PasswordHash,
+		&user.Role,
+		&user.IsDisabled,
+		&user.CreatedAt,
+		pq.Array(&user.Groups),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, database.ErrorNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &user, nil
+}
+
+func (p *Postgres) SetUserDisabled(ctx context.Context, username string, disabled bool) (_ int64, err error) {
+	const op = "postgres.SetUserDisabled"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	return p.exec(ctx, op, `UPDATE users SET is_disabled = $2 WHERE username = $1`, username, disabled)
+}
+
+func (p *Postgres) SetUserRole(ctx context.Context, username string, role string) (_ int64, err error) {
+	const op = "postgres.SetUserRole"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	return p.exec(ctx, op, `UPDATE users SET role = $2 WHERE username = $1`, username, role)
+}
+
+func (p *Postgres) SetUserPassword(ctx context.Context, username string, passwordHash string) (_ int64, err error) {
+	const op = "postgres.SetUserPassword"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	return p.exec(ctx, op, `UPDATE users SET password_hash = $2 WHERE username = $1`, username, passwordHash)
+}
+
+// begin starts the span of op and bounds it by the query timeout. The
+// returned function ends both and records err on the span.
+func (p *Postgres) begin(ctx context.Context, op string) (context.Context, func(err *error)) {
+	ctx, span := tracing.Start(ctx, op)
+
+	cancel := context.CancelFunc(func() {})
+	if p.queryTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, p.queryTimeout)
+	}
+
+	return ctx, func(err *error) {
+		cancel()
+		tracing.End(span, err)
+	}
+}
+
+// exec runs a statement that is expected to affect at least one row and
+// returns database.ErrorNotFound when it does not.
+func (p *Postgres) exec(ctx context.Context, op string, query string, args ...any) (int64, error) {
+	r, err := p.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resultRowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if resultRowsAffected == 0 {
+		return 0, fmt.Errorf("%s: %w", op, database.ErrorNotFound)
+	}
+
+	return resultRowsAffected, nil
+}
+
+func (p *Postgres) CreateApiKey(ctx context.Context, key database.ApiKey) (_ int64, err error) {
+	const op = "postgres.CreateApiKey"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `INSERT INTO api_keys (user_id, name, prefix, hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+
+	var id int64
+	err = p.db.QueryRowContext(ctx, query, key.UserId, key.Name, key.Prefix, key.Hash, pq.Array(key.Scopes), key.ExpiresAt).
+		Scan(&id)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return 0, fmt.Errorf("%s: %w", op, database.ErrorAlreadyExists)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetApiKey returns the key with the given prefix and the user it belongs to.
+func (p *Postgres) GetApiKey(ctx context.Context, prefix string) (_ *database.ApiKey, _ *database.User, err error) {
+	const op = "postgres.GetApiKey"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `SELECT k.id, k.user_id, k.name, k.prefix, k.hash, k.scopes, k.expires_at, k.last_used_at, k.created_at,
+		u.id, u.username, u.password_hash, u.role, u.is_disabled, u.created_at,
+		ARRAY(SELECT group_name FROM group_members WHERE username = u.username ORDER BY group_name)
+		FROM api_keys k JOIN users u ON u.id = k.user_id
+		WHERE k.prefix = $1`
+
+	var key database.ApiKey
+	var user database.User
+	err = p.db.QueryRowContext(ctx, query, prefix).Scan(
+		&key.Id,
+		&key.UserId,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		pq.Array(&key.Scopes),
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.CreatedAt,
+		&user.Id,
+		&user.Username,
+		&user.PasswordHash,
+		&user.Role,
+		&user.IsDisabled,
+		&user.CreatedAt,
+		pq.Array(&user.Groups),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, fmt.Errorf("%s: %w", op, database.ErrorNotFound)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &key, &user, nil
+}
+
+// ListApiKeys returns the keys of a user, without their hashes.
+func (p *Postgres) ListApiKeys(ctx context.Context, userId int64) (_ []database.ApiKey, err error) {
+	const op = "postgres.ListApiKeys"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+		FROM api_keys WHERE user_id = $1 ORDER BY id`
+
+	rows, err := p.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer rows.Close()
+
+	keys := []database.ApiKey{}
+	for rows.Next() {
+		var key database.ApiKey
+		err := rows.Scan(
+			&key.Id,
+			&key.UserId,
+			&key.Name,
+			&key.Prefix,
+			pq.Array(&key.Scopes),
+			&key.ExpiresAt,
+			&key.LastUsedAt,
+			&key.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// DeleteApiKey revokes a key of the given user.
+func (p *Postgres) DeleteApiKey(ctx context.Context, userId int64, id int64) (_ int64, err error) {
+	const op = "postgres.DeleteApiKey"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	return p.exec(ctx, op, `DELETE FROM api_keys WHERE user_id = $1 and id = $2`, userId, id)
+}
+
+// TouchApiKey records that a key was used. The timestamp only has minute
+// precision to spare a write on every request.
+func (p *Postgres) TouchApiKey(ctx context.Context, id int64) (err error) {
+	const op = "postgres.TouchApiKey"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 and (last_used_at IS NULL or last_used_at < NOW() - INTERVAL '1 minute')`
+
+	if _, err := p.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetFilePermissions returns the permissions granted on a file to username
+// directly or through one of groups.
+func (p *Postgres) GetFilePermissions(ctx context.Context, fileId int64, username string, groups []string) (_ []string, err error) {
+	const op = "postgres.GetFilePermissions"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `SELECT DISTINCT permission FROM file_acl WHERE file_id = $1 and (
+		(grantee_type = 'user' and grantee = $2) or
+		(grantee_type = 'group' and grantee = ANY($3)))`
+
+	rows, err := p.db.QueryContext(ctx, query, fileId, username, pq.Array(groups))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer rows.Close()
+
+	var permissions []string
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return permissions, nil
+}
+
+// GrantFileAccess adds a grant or changes the permission of an existing one.
+func (p *Postgres) GrantFileAccess(ctx context.Context, grant database.Grant) (err error) {
+	const op = "postgres.GrantFileAccess"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `INSERT INTO file_acl (file_id, grantee_type, grantee, permission) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (file_id, grantee_type, grantee) DO UPDATE SET permission = EXCLUDED.permission`
+
+	_, err = p.db.ExecContext(ctx, query, grant.FileId, grant.GranteeType, grant.Grantee, grant.Permission)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return fmt.Errorf("%s: %w", op, database.ErrorNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (p *Postgres) RevokeFileAccess(ctx context.Context, fileId int64, granteeType string, grantee string) (_ int64, err error) {
+	const op = "postgres.RevokeFileAccess"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	return p.exec(ctx, op, `DELETE FROM file_acl WHERE file_id = $1 and grantee_type = $2 and grantee = $3`,
+		fileId, granteeType, grantee)
+}
+
+func (p *Postgres) ListFileGrants(ctx context.Context, fileId int64) (_ []database.Grant, err error) {
+	const op = "postgres.ListFileGrants"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `SELECT file_id, grantee_type, grantee, permission FROM file_acl
+		WHERE file_id = $1 ORDER BY grantee_type, grantee`
+
+	rows, err := p.db.QueryContext(ctx, query, fileId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer rows.Close()
+
+	grants := []database.Grant{}
+	for rows.Next() {
+		var grant database.Grant
+		if err := rows.Scan(&grant.FileId, &grant.GranteeType, &grant.Grantee, &grant.Permission); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		grants = append(grants, grant)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return grants, nil
+}
+
+func (p *Postgres) CreateShare(ctx context.Context, share database.Share) (_ int64, err error) {
+	const op = "postgres.CreateShare"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `INSERT INTO shares (file_id, token_hash, created_by, password_hash, expires_at, max_downloads)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+
+	var id int64
+	err = p.db.QueryRowContext(ctx, query, share.FileId, share.TokenHash, share.CreatedBy, share.PasswordHash,
+		share.ExpiresAt, share.MaxDownloads).Scan(&id)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return 0, fmt.Errorf("%s: %w", op, database.ErrorNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+const shareColumns = `id, file_id, token_hash, created_by, password_hash, expires_at, max_downloads,
+	download_count, view_count, last_accessed_at, created_at`
+
+func scanShare(row interface{ Scan(...any) error }) (database.Share, error) {
+	var share database.Share
+	err := row.Scan(
+		&share.Id,
+		&share.FileId,
+		&share.TokenHash,
+		&share.CreatedBy,
+		&share.PasswordHash,
+		&share.ExpiresAt,
+		&share.MaxDownloads,
+		&share.DownloadCount,
+		&share.ViewCount,
+		&share.LastAccessedAt,
+		&share.CreatedAt,
+	)
+
+	return share, err
+}
+
+// GetShare returns the share with the hash of its token.
+func (p *Postgres) GetShare(ctx context.Context, tokenHash string) (_ *database.Share, err error) {
+	const op = "postgres.GetShare"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	share, err := scanShare(p.db.QueryRowContext(ctx, `SELECT `+shareColumns+` FROM shares WHERE token_hash = $1`, tokenHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, database.ErrorNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &share, nil
+}
+
+func (p *Postgres) ListShares(ctx context.Context, fileId int64) (_ []database.Share, err error) {
+	const op = "postgres.ListShares"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	rows, err := p.db.QueryContext(ctx, `SELECT `+shareColumns+` FROM shares WHERE file_id = $1 ORDER BY id`, fileId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer rows.Close()
+
+	shares := []database.Share{}
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		shares = append(shares, share)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return shares, nil
+}
+
+func (p *Postgres) DeleteShare(ctx context.Context, fileId int64, id int64) (_ int64, err error) {
+	const op = "postgres.DeleteShare"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	return p.exec(ctx, op, `DELETE FROM shares WHERE file_id = $1 and id = $2`, fileId, id)
+}
+
+// RecordShareView counts a visit of the landing page of a share.
+func (p *Postgres) RecordShareView(ctx context.Context, id int64) (err error) {
+	const op = "postgres.RecordShareView"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	_, err = p.exec(ctx, op, `UPDATE shares SET view_count = view_count + 1, last_accessed_at = NOW() WHERE id = $1`, id)
+
+	return err
+}
+
+// RecordShareDownload counts a download of a share. The count is only bumped
+// while the share is neither expired nor exhausted, so concurrent downloads
+// cannot exceed the limit; ErrorNotFound is returned otherwise.
+func (p *Postgres) RecordShareDownload(ctx context.Context, id int64) (_ int64, err error) {
+	const op = "postgres.RecordShareDownload"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	return p.exec(ctx, op, `UPDATE shares SET download_count = download_count + 1, last_accessed_at = NOW()
+		WHERE id = $1
+			AND (expires_at IS NULL OR expires_at > NOW())
+			AND (max_downloads IS NULL OR download_count < max_downloads)`, id)
+}
+
+// AddGroupMember adds an existing user to a group. Groups exist as long as
+// they have members.
+func (p *Postgres) AddGroupMember(ctx context.Context, group string, username string) (err error) {
+	const op = "postgres.AddGroupMember"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `INSERT INTO group_members (group_name, username) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+
+	_, err = p.db.ExecContext(ctx, query, group, username)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return fmt.Errorf("%s: %w", op, database.ErrorNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (p *Postgres) RemoveGroupMember(ctx context.Context, group string, username string) (_ int64, err error) {
+	const op = "postgres.RemoveGroupMember"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	return p.exec(ctx, op, `DELETE FROM group_members WHERE group_name = $1 and username = $2`, group, username)
+}
+
+// CreateAuditEvent appends an event to the audit log. Whichever of the
+// file ids is missing is looked up, as long as the file still exists.
+func (p *Postgres) CreateAuditEvent(ctx context.Context, event database.AuditEvent) (err error) {
+	const op = "postgres.CreateAuditEvent"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `INSERT INTO audit_events (actor, action, file_id, file_public_id, ip, user_agent, request_id,
+		result, status, bytes)
+		VALUES ($1, $2,
+			COALESCE($3::bigint, (SELECT id FROM files WHERE public_id = $4::uuid)),
+			COALESCE($4::uuid, (SELECT public_id FROM files WHERE id = $3::bigint)),
+			$5, $6, $7, $8, $9, $10)`
+
+	fileId := sql.NullInt64{Int64: event.FileId, Valid: event.FileId != 0}
+	filePublicId := sql.NullString{String: event.FilePublicId, Valid: event.FilePublicId != ""}
+
+	_, err = p.db.ExecContext(ctx, query, event.Actor, event.Action, fileId, filePublicId, event.IP,
+		event.UserAgent, event.RequestId, event.Result, event.Status, event.Bytes)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ListAuditEvents calls fn with every event that matches filter, in the
+// order the filter asks for, and stops at the first error of fn. It is not
+// bounded by the query timeout so that the whole log can be exported.
+func (p *Postgres) ListAuditEvents(ctx context.Context, filter database.AuditFilter, fn func(event *database.AuditEvent) error) (err error) {
+	const op = "postgres.ListAuditEvents"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	order := "DESC"
+	if filter.Chronological {
+		order = "ASC"
+	}
+
+	query := `SELECT id, time, actor, action, file_id, file_public_id, ip, user_agent, request_id,
+		result, status, bytes
+		FROM audit_events
+		WHERE ($1::text = '' OR actor = $1)
+			AND ($2::text = '' OR action = $2)
+			AND ($3::text = '' OR result = $3)
+			AND ($4::bigint = 0 OR file_id = $4)
+			AND ($5::uuid IS NULL OR file_public_id = $5)
+			AND ($6::timestamptz IS NULL OR time >= $6)
+			AND ($7::timestamptz IS NULL OR time < $7)
+			AND ($8::bigint = 0 OR id < $8)
+		ORDER BY id ` + order + `
+		LIMIT $9`
+
+	filePublicId := sql.NullString{String: filter.FilePublicId, Valid: filter.FilePublicId != ""}
+	since := sql.NullTime{Time: filter.Since, Valid: !filter.Since.IsZero()}
+	until := sql.NullTime{Time: filter.Until, Valid: !filter.Until.IsZero()}
+	limit := sql.NullInt64{Int64: int64(filter.Limit), Valid: filter.Limit > 0}
+
+	rows, err := p.db.QueryContext(ctx, query, filter.Actor, filter.Action, filter.Result, filter.FileId,
+		filePublicId, since, until, filter.BeforeId, limit)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var event database.AuditEvent
+		var fileId sql.NullInt64
+		var publicId sql.NullString
+		err := rows.Scan(
+			&event.Id,
+			&event.Time,
+			&event.Actor,
+			&event.Action,
+			&fileId,
+			&publicId,
+			&event.IP,
+			&event.UserAgent,
+			&event.RequestId,
+			&event.Result,
+			&event.Status,
+			&event.Bytes,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		event.FileId = fileId.Int64
+		event.FilePublicId = publicId.String
+
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DispatchFileEvents creates a delivery of up to limit undispatched events
+// for every webhook subscribed to them and marks them dispatched. Events are
+// locked while they are dispatched, so that replicas can run concurrently.
+func (p *Postgres) DispatchFileEvents(ctx context.Context, limit int) (_ int64, err error) {
+	const op = "postgres.DispatchFileEvents"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `WITH events AS (
+			SELECT id, type FROM file_events
+			WHERE dispatched_at IS NULL
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), deliveries AS (
+			INSERT INTO webhook_deliveries (webhook_id, event_id)
+			SELECT w.id, e.id FROM events e
+			JOIN webhooks w ON cardinality(w.event_types) = 0 OR e.type = ANY (w.event_types)
+			ON CONFLICT (webhook_id, event_id) DO NOTHING
+		)
+		UPDATE file_events SET dispatched_at = NOW() WHERE id IN (SELECT id FROM events)`
+
+	r, err := p.db.ExecContext(ctx, query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	dispatched, err := r.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return dispatched, nil
+}
+
+// ClaimWebhookDeliveries returns up to limit pending deliveries that are due
+// and postpones them by lease, so that no other replica attempts them
+// meanwhile. A delivery whose attempt is never recorded is retried once the
+// lease ran out.
+func (p *Postgres) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) (_ []database.PendingDelivery, err error) {
+	const op = "postgres.ClaimWebhookDeliveries"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM due, webhooks w, file_events e
+		WHERE d.id = due.id AND w.id = d.webhook_id AND e.id = d.event_id
+		RETURNING d.id, d.attempts, w.url, w.secret,
+			e.id, e.type, e.file_id, e.file_public_id, e.owner, e.name, e.size, e.created_at`
+
+	rows, err := p.db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer rows.Close()
+
+	deliveries := []database.PendingDelivery{}
+	for rows.Next() {
+		var delivery database.PendingDelivery
+		err := rows.Scan(
+			&delivery.Id,
+			&delivery.Attempts,
+			&delivery.URL,
+			&delivery.Secret,
+			&delivery.Event.Id,
+			&delivery.Event.Type,
+			&delivery.Event.FileId,
+			&delivery.Event.FilePublicId,
+			&delivery.Event.Owner,
+			&delivery.Event.Name,
+			&delivery.Event.Size,
+			&delivery.Event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// UpdateWebhookDelivery records the outcome of an attempt to deliver.
+func (p *Postgres) UpdateWebhookDelivery(ctx context.Context, id int64, attempt database.DeliveryAttempt) (err error) {
+	const op = "postgres.UpdateWebhookDelivery"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `UPDATE webhook_deliveries SET attempts = attempts + 1, status = $2, last_status_code = $3,
+		last_error = $4, next_attempt_at = $5, updated_at = NOW()
+		WHERE id = $1`
+
+	_, err = p.exec(ctx, op, query, id, attempt.Status, attempt.StatusCode, attempt.Error, attempt.NextAttemptAt)
+
+	return err
+}
+
+func (p *Postgres) CreateWebhook(ctx context.Context, webhook database.Webhook) (_ int64, err error) {
+	const op = "postgres.CreateWebhook"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `INSERT INTO webhooks (url, secret, event_types, created_by) VALUES ($1, $2, $3, $4) RETURNING id`
+
+	var id int64
+	err = p.db.QueryRowContext(ctx, query, webhook.URL, webhook.Secret, pq.Array(webhook.EventTypes), webhook.CreatedBy).
+		Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// ListWebhooks returns all webhooks, without their secrets.
+func (p *Postgres) ListWebhooks(ctx context.Context) (_ []database.Webhook, err error) {
+	const op = "postgres.ListWebhooks"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	rows, err := p.db.QueryContext(ctx, `SELECT id, url, event_types, created_by, created_at FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer rows.Close()
+
+	webhooks := []database.Webhook{}
+	for rows.Next() {
+		var webhook database.Webhook
+		err := rows.Scan(
+			&webhook.Id,
+			&webhook.URL,
+			pq.Array(&webhook.EventTypes),
+			&webhook.CreatedBy,
+			&webhook.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook removes a webhook together with its deliveries.
+func (p *Postgres) DeleteWebhook(ctx context.Context, id int64) (_ int64, err error) {
+	const op = "postgres.DeleteWebhook"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	return p.exec(ctx, op, `DELETE FROM webhooks WHERE id = $1`, id)
+}
+
+// ListWebhookDeliveries returns the latest deliveries of a webhook, only
+// those in status unless it is empty.
+func (p *Postgres) ListWebhookDeliveries(ctx context.Context, webhookId int64, status string, limit int) (_ []database.WebhookDelivery, err error) {
+	const op = "postgres.ListWebhookDeliveries"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `SELECT d.id, d.webhook_id, d.event_id, e.type, d.status, d.attempts, d.next_attempt_at,
+		d.last_status_code, d.last_error, d.created_at, d.updated_at
+		FROM webhook_deliveries d JOIN file_events e ON e.id = d.event_id
+		WHERE d.webhook_id = $1 AND ($2::text = '' OR d.status = $2)
+		ORDER BY d.id DESC
+		LIMIT $3`
+
+	rows, err := p.db.QueryContext(ctx, query, webhookId, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer rows.Close()
+
+	deliveries := []database.WebhookDelivery{}
+	for rows.Next() {
+		var delivery database.WebhookDelivery
+		err := rows.Scan(
+			&delivery.Id,
+			&delivery.WebhookId,
+			&delivery.EventId,
+			&delivery.EventType,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastStatusCode,
+			&delivery.LastError,
+			&delivery.CreatedAt,
+			&delivery.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// RetryWebhookDelivery schedules a dead delivery of a webhook for another
+// round of attempts.
+func (p *Postgres) RetryWebhookDelivery(ctx context.Context, webhookId int64, id int64) (_ int64, err error) {
+	const op = "postgres.RetryWebhookDelivery"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = NOW(),
+		updated_at = NOW()
+		WHERE webhook_id = $1 AND id = $2 AND status = 'dead'`
+
+	return p.exec(ctx, op, query, webhookId, id)
+}
+
+// GetLastFileEventId returns the id of the latest file event, zero when
+// there is none.
+func (p *Postgres) GetLastFileEventId(ctx context.Context) (_ int64, err error) {
+	const op = "postgres.GetLastFileEventId"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	var id int64
+	err = p.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM file_events`).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// ListFileEvents returns the events that match filter, oldest first.
+// Events commit in the order of their ids, see insertFileEvent, so no
+// event can turn up behind filter.AfterId later.
+func (p *Postgres) ListFileEvents(ctx context.Context, filter database.FileEventFilter) (_ []database.FileEvent, err error) {
+	const op = "postgres.ListFileEvents"
+
+	ctx, end := p.begin(ctx, op)
+	defer end(&err)
+
+	query := `SELECT id, type, file_id, file_public_id, owner, name, size, created_at
+		FROM file_events
+		WHERE id > $1 AND ($2::text = '' OR owner = $2)
+		ORDER BY id
+		LIMIT $3`
+
+	rows, err := p.db.QueryContext(ctx, query, filter.AfterId, filter.Owner, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer rows.Close()
+
+	events := []database.FileEvent{}
+	for rows.Next() {
+		var event database.FileEvent
+		err := rows.Scan(
+			&event.Id,
+			&event.Type,
+			&event.FileId,
+			&event.FilePublicId,
+			&event.Owner,
+			&event.Name,
+			&event.Size,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
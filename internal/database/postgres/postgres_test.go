@@ -0,0 +1,231 @@
+package postgres
+
+import (
+	"context"
+	"file-service/m/internal/config"
+	"file-service/m/internal/database"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPostgres connects to the database in the TEST_DB_* variables and
+// skips the test when there is none.
+func newTestPostgres(t *testing.T) *Postgres {
+	host := os.Getenv("TEST_DB_HOST")
+	if host == "" {
+		t.Skip("TEST_DB_HOST not set")
+	}
+
+	p, err := New(config.DatabaseConfig{
+		Host:     host,
+		Port:     os.Getenv("TEST_DB_PORT"),
+		User:     os.Getenv("TEST_DB_USER"),
+		Password: os.Getenv("TEST_DB_PASSWORD"),
+		Name:     os.Getenv("TEST_DB_NAME"),
+	}, config.QuotaConfig{})
+	require.NoError(t, err)
+
+	t.Cleanup(func() { p.db.Close() })
+
+	return p
+}
+
+func TestFileEventsCommitInOrder(t *testing.T) {
+	p := newTestPostgres(t)
+	ctx := context.Background()
+
+	owner := "events-" + uuid.NewString()
+	publicId, err := p.SaveFile(ctx, database.FileToSave{
+		Owner:        owner,
+		OriginalName: "a.txt",
+		Name:         uuid.NewString(),
+		Path:         "test",
+		Size:         1,
+		StorageType:  "local",
+	})
+	require.NoError(t, err)
+
+	var fileId int64
+	require.NoError(t, p.db.QueryRowContext(ctx, `SELECT id FROM files WHERE public_id = $1`, publicId).Scan(&fileId))
+
+	after, err := p.GetLastFileEventId(ctx)
+	require.NoError(t, err)
+
+	// the first transaction draws its id and stays open
+	first, err := p.db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer first.Rollback()
+
+	require.NoError(t, insertFileEvent(ctx, first, database.FileEventTrashed, fileId))
+
+	// the second one would commit a greater id before it
+	committed := make(chan error, 1)
+	go func() {
+		second, err := p.db.BeginTx(ctx, nil)
+		if err != nil {
+			committed <- err
+			return
+		}
+		defer second.Rollback()
+
+		if err := insertFileEvent(ctx, second, database.FileEventRestored, fileId); err != nil {
+			committed <- err
+			return
+		}
+
+		committed <- second.Commit()
+	}()
+
+	select {
+	case err := <-committed:
+		t.Fatalf("second event committed before the first: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	events, err := p.ListFileEvents(ctx, database.FileEventFilter{AfterId: after, Owner: owner, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	require.NoError(t, first.Commit())
+	require.NoError(t, <-committed)
+
+	events, err = p.ListFileEvents(ctx, database.FileEventFilter{AfterId: after, Owner: owner, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, database.FileEventTrashed, events[0].Type)
+	assert.Equal(t, database.FileEventRestored, events[1].Type)
+	assert.Less(t, events[0].Id, events[1].Id)
+}
+
+func TestSetQuota(t *testing.T) {
+	p := newTestPostgres(t)
+	ctx := context.Background()
+
+	username := "quota-" + uuid.NewString()
+	_, err := p.CreateUser(ctx, database.User{Username: username, PasswordHash: "x", Role: database.RoleWriter})
+	require.NoError(t, err)
+
+	_, err = p.SetQuota(ctx, username, database.Quota{MaxBytes: 10, MaxFiles: 1})
+	require.NoError(t, err)
+
+	_, err = p.SaveFile(ctx, database.FileToSave{
+		Owner:        username,
+		OriginalName: "a.txt",
+		Name:         uuid.NewString(),
+		Path:         "test",
+		Size:         4,
+		StorageType:  "local",
+	})
+	require.NoError(t, err)
+
+	// raising the limits keeps the usage
+	_, err = p.SetQuota(ctx, username, database.Quota{MaxBytes: 20, MaxFiles: 2})
+	require.NoError(t, err)
+
+	usage, err := p.GetUsage(ctx, username)
+	require.NoError(t, err)
+	assert.Equal(t, &database.Usage{Quota: database.Quota{MaxBytes: 20, MaxFiles: 2}, UsedBytes: 4, UsedFiles: 1}, usage)
+
+	_, err = p.SetQuota(ctx, "nobody-"+uuid.NewString(), database.Quota{MaxBytes: 10})
+	assert.ErrorIs(t, err, database.ErrorNotFound)
+}
+
+func TestUploadUrlUsedOnce(t *testing.T) {
+	p := newTestPostgres(t)
+	ctx := context.Background()
+
+	nonce := uuid.NewString()
+	file := database.FileToSave{
+		Owner:         "upload-" + uuid.NewString(),
+		OriginalName:  "a.txt",
+		Name:          uuid.NewString(),
+		Path:          "test",
+		Size:          1,
+		StorageType:   "local",
+		UploadNonce:   nonce,
+		UploadExpires: time.Now().Add(time.Minute),
+	}
+
+	used, err := p.IsUploadUrlUsed(ctx, nonce)
+	require.NoError(t, err)
+	assert.False(t, used)
+
+	_, err = p.SaveFile(ctx, file)
+	require.NoError(t, err)
+
+	used, err = p.IsUploadUrlUsed(ctx, nonce)
+	require.NoError(t, err)
+	assert.True(t, used)
+
+	file.Name = uuid.NewString()
+	_, err = p.SaveFile(ctx, file)
+	assert.ErrorIs(t, err, database.ErrorUploadUrlUsed)
+}
+
+func TestDecodeNotification(t *testing.T) {
+	n, err := decodeNotification(`{"type":"file_event","event_id":4}`)
+	require.NoError(t, err)
+	assert.Equal(t, database.Notification{Type: database.NotificationFileEvent, EventId: 4}, n)
+
+	n, err = decodeNotification(`{"type":"invalidate","key":"user:alice"}`)
+	require.NoError(t, err)
+	assert.Equal(t, database.Notification{Type: database.NotificationInvalidate, Key: "user:alice"}, n)
+
+	_, err = decodeNotification(`{"type":`)
+	assert.Error(t, err)
+}
+
+func TestReceive(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan *pq.Notification)
+	pings := make(chan struct{}, 1)
+	ping := func() error {
+		select {
+		case pings <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+
+	var got []database.Notification
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		receive(ctx, mockLogger.NewLogger(), ch, ping, time.Millisecond, func(n database.Notification) {
+			got = append(got, n)
+		})
+	}()
+
+	ch <- &pq.Notification{Channel: notifyChannel, Extra: `{"type":"file_event","event_id":4}`}
+	ch <- &pq.Notification{Channel: notifyChannel, Extra: `{"type":"invalidate","key":"user:alice"}`}
+	ch <- &pq.Notification{Channel: notifyChannel, Extra: `not json`}
+	// sent after a reconnect, the notifications in between are lost
+	ch <- nil
+	ch <- &pq.Notification{Channel: notifyChannel, Extra: `{"type":"file_event","event_id":9}`}
+
+	select {
+	case <-pings:
+	case <-time.After(time.Second):
+		t.Fatal("connection not pinged")
+	}
+
+	cancel()
+	<-done
+
+	assert.Equal(t, []database.Notification{
+		{Type: database.NotificationFileEvent, EventId: 4},
+		{Type: database.NotificationInvalidate, Key: "user:alice"},
+		{Type: database.NotificationFileEvent},
+		{Type: database.NotificationInvalidate},
+		{Type: database.NotificationFileEvent, EventId: 9},
+	}, got)
+}
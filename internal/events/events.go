@@ -0,0 +1,101 @@
+package events
+
+import (
+	"file-service/m/internal/database"
+	"sync"
+)
+
+// Broker wakes the subscribers of file events whenever new events were
+// written. It carries no events itself, subscribers read them from the
+// event log, so a wake up that is missed while a subscriber is busy is
+// folded into the next one. It also hands cache invalidations to the
+// caches that registered for them.
+type Broker struct {
+	mu           sync.Mutex
+	subscribers  map[chan struct{}]struct{}
+	invalidators []func(key string)
+	closed       bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: map[chan struct{}]struct{}{},
+	}
+}
+
+// Subscribe returns a channel that receives a value after new events were
+// written and is closed when the broker is. The returned function ends the
+// subscription.
+func (b *Broker) Subscribe() (<-chan struct{}, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	b.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish wakes all subscribers. It never blocks.
+func (b *Broker) Publish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Close ends all subscriptions, so that streams finish on shutdown.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+
+	b.closed = true
+}
+
+// OnInvalidate registers fn to be called with the keys that are
+// invalidated, an empty key stands for all of them. fn must not block.
+func (b *Broker) OnInvalidate(fn func(key string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.invalidators = append(b.invalidators, fn)
+}
+
+// Notify handles a notification broadcast between the replicas.
+func (b *Broker) Notify(n database.Notification) {
+	switch n.Type {
+	case database.NotificationFileEvent:
+		b.Publish()
+	case database.NotificationInvalidate:
+		b.mu.Lock()
+		invalidators := b.invalidators
+		b.mu.Unlock()
+
+		for _, fn := range invalidators {
+			fn(n.Key)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package events_test
+
+import (
+	"file-service/m/internal/database"
+	"file-service/m/internal/events"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroker(t *testing.T) {
+	t.Run("publish wakes every subscriber once", func(t *testing.T) {
+		broker := events.NewBroker()
+
+		a, cancelA := broker.Subscribe()
+		defer cancelA()
+		b, cancelB := broker.Subscribe()
+		defer cancelB()
+
+		broker.Publish()
+		broker.Publish()
+
+		for _, ch := range []<-chan struct{}{a, b} {
+			assert.Len(t, ch, 1)
+			<-ch
+			assert.Len(t, ch, 0)
+		}
+	})
+
+	t.Run("cancelled subscriptions are closed", func(t *testing.T) {
+		broker := events.NewBroker()
+
+		ch, cancel := broker.Subscribe()
+		cancel()
+		cancel()
+
+		broker.Publish()
+
+		_, ok := <-ch
+		assert.False(t, ok)
+	})
+
+	t.Run("close ends subscriptions", func(t *testing.T) {
+		broker := events.NewBroker()
+
+		ch, cancel := broker.Subscribe()
+		defer cancel()
+
+		broker.Close()
+
+		_, ok := <-ch
+		assert.False(t, ok)
+
+		late, _ := broker.Subscribe()
+		_, ok = <-late
+		assert.False(t, ok)
+	})
+
+	t.Run("notifications", func(t *testing.T) {
+		broker := events.NewBroker()
+
+		ch, cancel := broker.Subscribe()
+		defer cancel()
+
+		var keys []string
+		broker.OnInvalidate(func(key string) {
+			keys = append(keys, key)
+		})
+
+		broker.Notify(database.Notification{Type: database.NotificationFileEvent, EventId: 4})
+		broker.Notify(database.Notification{Type: database.NotificationInvalidate, Key: "user:alice"})
+		broker.Notify(database.Notification{Type: database.NotificationInvalidate})
+		broker.Notify(database.Notification{Type: "unknown"})
+
+		assert.Len(t, ch, 1)
+		assert.Equal(t, []string{"user:alice", ""}, keys)
+	})
+}
@@ -0,0 +1,55 @@
+package filename
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the maximum length of a normalized file name in bytes.
+const MaxLength = 255
+
+var (
+	ErrorEmpty            = errors.New("file name is empty")
+	ErrorTooLong          = errors.New("file name is too long")
+	ErrorInvalidEncoding  = errors.New("file name is not valid utf-8")
+	ErrorInvalidCharacter = errors.New("file name contains invalid characters")
+)
+
+// Normalize turns a client supplied file name into a safe display name.
+// Any directory part is dropped, the result is NFC normalized and names
+// with control or bidirectional formatting characters are rejected.
+func Normalize(name string) (string, error) {
+	if !utf8.ValidString(name) {
+		return "", ErrorInvalidEncoding
+	}
+
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	name = strings.TrimSpace(norm.NFC.String(name))
+
+	if name == "" || name == "." || name == ".." {
+		return "", ErrorEmpty
+	}
+
+	if len(name) > MaxLength {
+		return "", ErrorTooLong
+	}
+
+	for _, r := range name {
+		if unicode.IsControl(r) || isBidiControl(r) || r == utf8.RuneError {
+			return "", ErrorInvalidCharacter
+		}
+	}
+
+	return name, nil
+}
+
+func isBidiControl(r rune) bool {
+	return (r >= '\u202a' && r <= '\u202e') || (r >= '\u2066' && r <= '\u2069') || r == '\u200e' || r == '\u200f'
+}
@@ -0,0 +1,46 @@
+package filename_test
+
+import (
+	"file-service/m/internal/filename"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		err      error
+	}{
+		{name: "plain", input: "report.pdf", expected: "report.pdf"},
+		{name: "surrounding spaces", input: "  report.pdf ", expected: "report.pdf"},
+		{name: "unix traversal", input: "../../etc/passwd", expected: "passwd"},
+		{name: "windows traversal", input: `..\..\windows\system.ini`, expected: "system.ini"},
+		{name: "windows absolute path", input: `C:\Users\me\photo.jpg`, expected: "photo.jpg"},
+		{name: "nfd is composed", input: "cafe\u0301.txt", expected: "caf\u00e9.txt"},
+		{name: "unicode", input: "отчёт 📄.txt", expected: "отчёт 📄.txt"},
+		{name: "empty", input: "", err: filename.ErrorEmpty},
+		{name: "only spaces", input: "   ", err: filename.ErrorEmpty},
+		{name: "dot dot", input: "..", err: filename.ErrorEmpty},
+		{name: "trailing slash", input: "dir/", err: filename.ErrorEmpty},
+		{name: "nul byte", input: "a\x00.txt", err: filename.ErrorInvalidCharacter},
+		{name: "newline", input: "a\n.txt", err: filename.ErrorInvalidCharacter},
+		{name: "escape sequence", input: "\x1b[31mred", err: filename.ErrorInvalidCharacter},
+		{name: "right to left override", input: "invoice\u202egpj.exe", err: filename.ErrorInvalidCharacter},
+		{name: "invalid utf-8", input: "a\xff.txt", err: filename.ErrorInvalidEncoding},
+		{name: "too long", input: strings.Repeat("a", filename.MaxLength+1), err: filename.ErrorTooLong},
+		{name: "too long multibyte", input: strings.Repeat("я", filename.MaxLength/2+1), err: filename.ErrorTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filename.Normalize(tt.input)
+
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
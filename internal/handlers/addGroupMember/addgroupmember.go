@@ -0,0 +1,58 @@
+package addgroupmember
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/auth"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	apiresponse.ApiResponse
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	AddGroupMember(ctx context.Context, group string, username string) error
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.addgroupmember.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		group := chi.URLParam(r, "group")
+		username := chi.URLParam(r, "username")
+
+		if err := auth.ValidateGroupName(group); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid group name"))
+			return
+		}
+
+		err := db.AddGroupMember(r.Context(), group, username)
+		if errors.Is(err, database.ErrorNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("user not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to add group member", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to add group member"))
+			return
+		}
+
+		log.Info("group member added", slog.String("group", group), slog.String("username", username))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{apiresponse.Success("group member added")})
+	}
+}
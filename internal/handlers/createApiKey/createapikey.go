@@ -0,0 +1,124 @@
+package createapikey
+
+import (
+	"context"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/apikey"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+const maxNameLength = 100
+
+type Request struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+	Id     int64  `json:"id,omitempty"`
+	Key    string `json:"key,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	CreateApiKey(ctx context.Context, key database.ApiKey) (int64, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.createapikey.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid request"))
+			return
+		}
+
+		if req.Name == "" || len(req.Name) > maxNameLength {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid name"))
+			return
+		}
+
+		if apikey.ValidateScopes(req.Scopes) != nil ||
+			(slices.Contains(req.Scopes, apikey.ScopeAdmin) && !user.IsAdmin()) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid scopes"))
+			return
+		}
+
+		// a key may only mint keys that can do no more than itself
+		if callerScopes, ok := requestctx.Scopes(r.Context()); ok {
+			for _, scope := range req.Scopes {
+				if !slices.Contains(callerScopes, scope) {
+					render.Status(r, http.StatusForbidden)
+					render.JSON(w, r, apiresponse.Error("scope not granted to caller"))
+					return
+				}
+			}
+		}
+
+		if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid expiry"))
+			return
+		}
+
+		key, prefix, hash, err := apikey.Generate()
+		if err != nil {
+			log.Error("failed to generate api key", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to create api key"))
+			return
+		}
+
+		id, err := db.CreateApiKey(r.Context(), database.ApiKey{
+			UserId:    user.Id,
+			Name:      req.Name,
+			Prefix:    prefix,
+			Hash:      hash,
+			Scopes:    req.Scopes,
+			ExpiresAt: req.ExpiresAt,
+		})
+		if err != nil {
+			log.Error("failed to create api key", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to create api key"))
+			return
+		}
+
+		log.Info("api key created",
+			slog.Int64("key_id", id),
+			slog.String("prefix", prefix),
+		)
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			ApiResponse: apiresponse.Success("api key created"),
+			Id:          id,
+			Key:         key,
+			Prefix:      prefix,
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package createapikey_test
+
+import (
+	"encoding/json"
+	"file-service/m/internal/apikey"
+	"file-service/m/internal/database"
+	createapikey "file-service/m/internal/handlers/createApiKey"
+	"file-service/m/internal/handlers/createApiKey/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateApiKeyHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := createapikey.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		var stored database.ApiKey
+		db.On("CreateApiKey", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(database.ApiKey)
+		}).Return(int64(3), nil).Once()
+
+		expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		r, w := CreateRequestAndResponse(
+			fmt.Sprintf(`{"name":"ci","scopes":["file:read","file:write"],"expires_at":%q}`, expiresAt), false)
+
+		handler.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusCreated, w.Result().StatusCode)
+
+		var resp createapikey.Response
+		require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&resp))
+
+		assert.Equal(t, int64(3), resp.Id)
+		assert.Equal(t, int64(7), stored.UserId)
+		assert.Equal(t, "ci", stored.Name)
+		assert.Equal(t, []string{apikey.ScopeFileRead, apikey.ScopeFileWrite}, stored.Scopes)
+		assert.Equal(t, resp.Prefix, stored.Prefix)
+		assert.True(t, apikey.Matches(resp.Key, stored.Hash))
+		assert.NotContains(t, stored.Hash, resp.Key)
+		require.NotNil(t, stored.ExpiresAt)
+		assert.Equal(t, expiresAt, stored.ExpiresAt.UTC().Format(time.RFC3339))
+	})
+
+	t.Run("admin scope by admin", func(t *testing.T) {
+		db.On("CreateApiKey", mock.Anything, mock.Anything).Return(int64(4), nil).Once()
+
+		r, w := CreateRequestAndResponse(`{"name":"ops","scopes":["admin"]}`, true)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+	})
+
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"invalid json", `{"name":`, "invalid request"},
+		{"missing name", `{"scopes":["file:read"]}`, "invalid name"},
+		{"missing scopes", `{"name":"ci"}`, "invalid scopes"},
+		{"unknown scope", `{"name":"ci","scopes":["file:*"]}`, "invalid scopes"},
+		{"admin scope by user", `{"name":"ci","scopes":["admin"]}`, "invalid scopes"},
+		{"expired", `{"name":"ci","scopes":["file:read"],"expires_at":"2000-01-01T00:00:00Z"}`, "invalid expiry"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w := CreateRequestAndResponse(tt.body, false)
+
+			handler.ServeHTTP(w, r)
+
+			resp := w.Result()
+			var body createapikey.Response
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Equal(t, tt.message, body.Message)
+		})
+	}
+
+	t.Run("scopes beyond the calling key", func(t *testing.T) {
+		r, w := CreateRequestAndResponse(`{"name":"ci","scopes":["file:delete"]}`, false)
+		r = r.WithContext(requestctx.WithScopes(r.Context(), []string{apikey.ScopeKeyManage}))
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("scopes held by the calling key", func(t *testing.T) {
+		db.On("CreateApiKey", mock.Anything, mock.Anything).Return(int64(5), nil).Once()
+
+		r, w := CreateRequestAndResponse(`{"name":"ci","scopes":["file:read"]}`, false)
+		r = r.WithContext(requestctx.WithScopes(r.Context(), []string{apikey.ScopeKeyManage, apikey.ScopeFileRead}))
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("CreateApiKey", mock.Anything, mock.Anything).Return(int64(0), fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse(`{"name":"ci","scopes":["file:read"]}`, false)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(body string, isAdmin bool) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodPost, "/keys", strings.NewReader(body))
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	role := database.RoleWriter
+	if isAdmin {
+		role = database.RoleAdmin
+	}
+	ctx = requestctx.WithUser(ctx, &database.User{Id: 7, Username: "alice", Role: role})
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,178 @@
+package createshare
+
+import (
+	"context"
+	"errors"
+	"file-service/m/internal/access"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/auth"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/sharetoken"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	Password string `json:"password"`
+	// ExpiresIn is the lifetime of the share in seconds, zero never expires.
+	ExpiresIn    int64  `json:"expires_in"`
+	MaxDownloads *int64 `json:"max_downloads"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+	Id        int64      `json:"id,omitempty"`
+	Token     string     `json:"token,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	GetFile(ctx context.Context, id int64, isDeleted bool) (*database.File, error)
+	CreateShare(ctx context.Context, share database.Share) (int64, error)
+}
+
+// New creates a public link to a file. Only the owner of the file and
+// administrators can share it.
+func New(logger *slog.Logger, db Db, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.createshare.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		fileIdStr, ok := requestctx.FileId(r.Context())
+		if !ok || fileIdStr == "" {
+			log.Error("file id is empty")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("file id is empty"))
+			return
+		}
+
+		fileId, err := strconv.ParseInt(fileIdStr, 10, 64)
+		if err != nil {
+			log.Error("failed to parse file id", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid file id"))
+			return
+		}
+
+		// the body is optional, a share without it never expires
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil && !errors.Is(err, io.EOF) {
+			log.Error("failed to decode request", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid request"))
+			return
+		}
+
+		if req.ExpiresIn < 0 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid expiry"))
+			return
+		}
+
+		if req.MaxDownloads != nil && *req.MaxDownloads <= 0 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid max downloads"))
+			return
+		}
+
+		share := database.Share{
+			FileId:       fileId,
+			CreatedBy:    user.Username,
+			MaxDownloads: req.MaxDownloads,
+		}
+
+		if req.Password != "" {
+			share.PasswordHash, err = auth.HashPassword(req.Password)
+			if errors.Is(err, auth.ErrorInvalidPassword) {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, apiresponse.Error("invalid password"))
+				return
+			}
+			if err != nil {
+				log.Error("failed to hash password", slog.Any("error", err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, apiresponse.Error("failed to create share"))
+				return
+			}
+		}
+
+		if req.ExpiresIn > 0 {
+			expiresAt := time.Now().Add(time.Duration(req.ExpiresIn) * time.Second).Truncate(time.Second).UTC()
+			share.ExpiresAt = &expiresAt
+		}
+
+		file, err := db.GetFile(r.Context(), fileId, false)
+		if err != nil && !errors.Is(err, database.ErrorNotFound) {
+			log.Error("failed to get file", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to create share"))
+			return
+		}
+
+		if err != nil || !access.IsOwner(user, file) {
+			log.Info("file not found or not owned")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
+
+		// the key of the client is never stored, so the share could not
+		// decrypt the file
+		if file.KeyFingerprint != "" {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("file is encrypted with a customer key"))
+			return
+		}
+
+		token, hash, err := sharetoken.Generate()
+		if err != nil {
+			log.Error("failed to generate share token", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to create share"))
+			return
+		}
+
+		share.TokenHash = hash
+
+		id, err := db.CreateShare(r.Context(), share)
+		if errors.Is(err, database.ErrorNotFound) {
+			// deleted concurrently
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to create share", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to create share"))
+			return
+		}
+
+		log.Info("share created", slog.Int64("id", id))
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			ApiResponse: apiresponse.Success("share created"),
+			Id:          id,
+			Token:       token,
+			URL:         baseURL + "/s/" + token,
+			ExpiresAt:   share.ExpiresAt,
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package createshare_test
+
+import (
+	"encoding/json"
+	"file-service/m/internal/auth"
+	"file-service/m/internal/database"
+	createshare "file-service/m/internal/handlers/createShare"
+	"file-service/m/internal/handlers/createShare/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/sharetoken"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateShareHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := createshare.New(log, db, "https://files.example.com")
+	file := &database.File{Id: 1, Owner: "alice"}
+
+	t.Run("success", func(t *testing.T) {
+		var saved database.Share
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+		db.On("CreateShare", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(1).(database.Share)
+		}).Return(int64(7), nil).Once()
+
+		r, w := CreateRequestAndResponse("alice", `{"password":"password123","expires_in":3600,"max_downloads":3}`)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		var body createshare.Response
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, int64(7), body.Id)
+		assert.Equal(t, "https://files.example.com/s/"+body.Token, body.URL)
+		require.NotNil(t, body.ExpiresAt)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), *body.ExpiresAt, 2*time.Second)
+
+		assert.Equal(t, int64(1), saved.FileId)
+		assert.Equal(t, "alice", saved.CreatedBy)
+		assert.Equal(t, sharetoken.Hash(body.Token), saved.TokenHash)
+		assert.True(t, auth.CheckPassword(saved.PasswordHash, "password123"))
+		require.NotNil(t, saved.MaxDownloads)
+		assert.Equal(t, int64(3), *saved.MaxDownloads)
+	})
+
+	t.Run("without options", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+		db.On("CreateShare", mock.Anything, mock.MatchedBy(func(share database.Share) bool {
+			return share.PasswordHash == "" && share.ExpiresAt == nil && share.MaxDownloads == nil
+		})).Return(int64(8), nil).Once()
+
+		r, w := CreateRequestAndResponse("alice", "")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+	})
+
+	t.Run("not owner", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+
+		r, w := CreateRequestAndResponse("bob", "")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("customer key", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, Owner: "alice", KeyFingerprint: "abc"}, nil).Once()
+
+		r, w := CreateRequestAndResponse("alice", "")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+		db.On("CreateShare", mock.Anything, mock.Anything).Return(int64(0), fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse("alice", "")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"negative expiry", `{"expires_in":-1}`, "invalid expiry"},
+		{"zero downloads", `{"max_downloads":0}`, "invalid max downloads"},
+		{"short password", `{"password":"short"}`, "invalid password"},
+		{"invalid json", `{`, "invalid request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w := CreateRequestAndResponse("alice", tt.body)
+
+			handler.ServeHTTP(w, r)
+
+			resp := w.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Equal(t, fmt.Sprintf("{\"status\":\"error\",\"message\":\"%s\"}\n", tt.message), string(body))
+		})
+	}
+}
+
+func CreateRequestAndResponse(username string, body string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodPost, "/file/1/shares", strings.NewReader(body))
+
+	ctx := requestctx.WithFileId(r.Context(), "1")
+	ctx = requestctx.WithRequestId(ctx, "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Username: username, Role: database.RoleWriter})
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
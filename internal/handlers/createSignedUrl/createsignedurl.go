@@ -0,0 +1,181 @@
+package createsignedurl
+
+import (
+	"context"
+	"errors"
+	"file-service/m/internal/access"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/config"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/signedurl"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	// ExpiresIn is the lifetime of the URL in seconds.
+	ExpiresIn          int64  `json:"expires_in"`
+	IP                 string `json:"ip"`
+	ContentDisposition string `json:"content_disposition"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+	URL       string    `json:"url,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	GetFile(ctx context.Context, id int64, isDeleted bool) (*database.File, error)
+	GetFilePermissions(ctx context.Context, fileId int64, username string, groups []string) ([]string, error)
+}
+
+func New(logger *slog.Logger, db Db, signer *signedurl.Signer, cfg config.SignedURLConfig, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.createsignedurl.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		fileIdStr, ok := requestctx.FileId(r.Context())
+		if !ok || fileIdStr == "" {
+			log.Error("file id is empty")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("file id is empty"))
+			return
+		}
+
+		fileId, err := strconv.ParseInt(fileIdStr, 10, 64)
+		if err != nil {
+			log.Error("failed to parse file id", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid file id"))
+			return
+		}
+
+		// the body is optional, all fields have defaults
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil && !errors.Is(err, io.EOF) {
+			log.Error("failed to decode request", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid request"))
+			return
+		}
+
+		ttl := cfg.DefaultTTL
+		if req.ExpiresIn != 0 {
+			ttl = time.Duration(req.ExpiresIn) * time.Second
+		}
+
+		if req.ExpiresIn < 0 || ttl > cfg.MaxTTL {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid expiry"))
+			return
+		}
+
+		query := url.Values{}
+
+		if req.IP != "" {
+			ip, err := netip.ParseAddr(req.IP)
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, apiresponse.Error("invalid ip"))
+				return
+			}
+
+			query.Set(signedurl.ParamIP, ip.Unmap().String())
+		}
+
+		if req.ContentDisposition != "" {
+			disposition, ok := normalizeDisposition(req.ContentDisposition)
+			if !ok {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, apiresponse.Error("invalid content disposition"))
+				return
+			}
+
+			query.Set(signedurl.ParamDisposition, disposition)
+		}
+
+		file, err := db.GetFile(r.Context(), fileId, false)
+		if errors.Is(err, database.ErrorNotFound) {
+			log.Info("file not found")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get file", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to sign url"))
+			return
+		}
+
+		allowed, err := access.Allowed(r.Context(), db, user, file, database.PermissionRead)
+		if err != nil {
+			log.Error("failed to check access", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to sign url"))
+			return
+		}
+
+		if !allowed {
+			log.Info("access denied")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
+
+		// the key of the client is never stored, so the download could not
+		// decrypt the file
+		if file.KeyFingerprint != "" {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("file is encrypted with a customer key"))
+			return
+		}
+
+		expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+		path := signedurl.DownloadPath(file.PublicId)
+		signed := signer.Sign(path, query, expiresAt)
+
+		log.Info("signed url issued",
+			slog.Time("expires_at", expiresAt),
+		)
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			ApiResponse: apiresponse.Success("signed url created"),
+			URL:         baseURL + path + "?" + signed.Encode(),
+			ExpiresAt:   expiresAt.UTC(),
+		})
+	}
+}
+
+// normalizeDisposition accepts inline and attachment dispositions and
+// re-encodes them so that no raw client input ends up in a response header.
+func normalizeDisposition(value string) (string, bool) {
+	dispositionType, params, err := mime.ParseMediaType(value)
+	if err != nil || (dispositionType != "inline" && dispositionType != "attachment") {
+		return "", false
+	}
+
+	disposition := mime.FormatMediaType(dispositionType, params)
+
+	return disposition, disposition != ""
+}
@@ -0,0 +1,149 @@
+package createsignedurl_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"file-service/m/internal/config"
+	"file-service/m/internal/database"
+	createsignedurl "file-service/m/internal/handlers/createSignedUrl"
+	"file-service/m/internal/handlers/createSignedUrl/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/signedurl"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const publicId = "0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f"
+
+func TestCreateSignedUrlHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	signer, err := signedurl.New(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+
+	cfg := config.SignedURLConfig{
+		DefaultTTL: 15 * time.Minute,
+		MaxTTL:     time.Hour,
+	}
+	handler := createsignedurl.New(log, db, signer, cfg, "https://files.example.com")
+	file := &database.File{Id: 1, PublicId: publicId, Owner: "alice"}
+
+	t.Run("success", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+
+		r, w := CreateRequestAndResponse(`{"expires_in":60,"ip":"10.0.0.1","content_disposition":"attachment; filename=\"report.pdf\""}`)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		var body createsignedurl.Response
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), body.ExpiresAt, 2*time.Second)
+
+		u, err := url.Parse(body.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "files.example.com", u.Host)
+		assert.Equal(t, "/download/"+publicId, u.Path)
+		assert.Equal(t, "10.0.0.1", u.Query().Get("ip"))
+		assert.Equal(t, `attachment; filename=report.pdf`, u.Query().Get("disposition"))
+		assert.NoError(t, signer.Verify(u.Path, u.Query(), "10.0.0.1", time.Now()))
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+
+		r, w := CreateRequestAndResponse("")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		var body createsignedurl.Response
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.WithinDuration(t, time.Now().Add(cfg.DefaultTTL), body.ExpiresAt, 2*time.Second)
+	})
+
+	t.Run("access", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, PublicId: publicId, Owner: "bob"}, nil).Once()
+		db.On("GetFilePermissions", mock.Anything, int64(1), "alice", []string(nil)).Return(nil, nil).Once()
+
+		r, w := CreateRequestAndResponse("")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(nil, fmt.Errorf("postgres.GetFile: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("customer key", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, PublicId: publicId, Owner: "alice", KeyFingerprint: "abc"}, nil).Once()
+
+		r, w := CreateRequestAndResponse("")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"expiry too long", `{"expires_in":7200}`, "invalid expiry"},
+		{"negative expiry", `{"expires_in":-1}`, "invalid expiry"},
+		{"invalid ip", `{"ip":"10.0.0"}`, "invalid ip"},
+		{"invalid disposition", `{"content_disposition":"form-data"}`, "invalid content disposition"},
+		{"header injection", `{"content_disposition":"attachment\r\nSet-Cookie: a=b"}`, "invalid content disposition"},
+		{"invalid json", `{`, "invalid request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w := CreateRequestAndResponse(tt.body)
+
+			handler.ServeHTTP(w, r)
+
+			resp := w.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Equal(t, fmt.Sprintf("{\"status\":\"error\",\"message\":\"%s\"}\n", tt.message), string(body))
+		})
+	}
+}
+
+func CreateRequestAndResponse(body string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodPost, "/file/"+publicId+"/signed-url", strings.NewReader(body))
+
+	ctx := requestctx.WithFileId(r.Context(), "1")
+	ctx = requestctx.WithRequestId(ctx, "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Username: "alice", Role: database.RoleReader})
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
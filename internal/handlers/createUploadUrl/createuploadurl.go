@@ -0,0 +1,162 @@
+package createuploadurl
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/auth"
+	"file-service/m/internal/config"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/signedurl"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+const maxContentTypes = 32
+
+type Request struct {
+	// ExpiresIn is the lifetime of the URL in seconds.
+	ExpiresIn    int64    `json:"expires_in"`
+	MaxSize      int64    `json:"max_size"`
+	ContentTypes []string `json:"content_types"`
+	Owner        string   `json:"owner"`
+	IP           string   `json:"ip"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+	URL       string    `json:"url,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	GetUserByUsername(ctx context.Context, username string) (*database.User, error)
+}
+
+func New(logger *slog.Logger, db Db, signer *signedurl.Signer, cfg config.SignedURLConfig, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.createuploadurl.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		// the body is optional, all fields have defaults
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil && !errors.Is(err, io.EOF) {
+			log.Error("failed to decode request", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid request"))
+			return
+		}
+
+		ttl := cfg.DefaultTTL
+		if req.ExpiresIn != 0 {
+			ttl = time.Duration(req.ExpiresIn) * time.Second
+		}
+
+		if req.ExpiresIn < 0 || ttl > cfg.MaxTTL {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid expiry"))
+			return
+		}
+
+		if req.MaxSize < 0 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid max size"))
+			return
+		}
+
+		if len(req.ContentTypes) > maxContentTypes {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid content type"))
+			return
+		}
+
+		for _, contentType := range req.ContentTypes {
+			if !signedurl.ValidContentType(contentType) {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, apiresponse.Error("invalid content type"))
+				return
+			}
+		}
+
+		if req.Owner == "" {
+			req.Owner = user.Username
+		}
+
+		if req.Owner != user.Username {
+			if !user.IsAdmin() {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, apiresponse.Error("forbidden"))
+				return
+			}
+
+			if err := auth.ValidateUsername(req.Owner); err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, apiresponse.Error("invalid owner"))
+				return
+			}
+
+			_, err := db.GetUserByUsername(r.Context(), req.Owner)
+			if errors.Is(err, database.ErrorNotFound) {
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, apiresponse.Error("user not found"))
+				return
+			}
+			if err != nil {
+				log.Error("failed to get user", slog.Any("error", err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, apiresponse.Error("failed to sign url"))
+				return
+			}
+		}
+
+		constraints := signedurl.UploadConstraints{
+			Owner:        req.Owner,
+			MaxSize:      req.MaxSize,
+			ContentTypes: req.ContentTypes,
+			Nonce:        uuid.NewString(),
+		}
+		query := constraints.Query()
+
+		if req.IP != "" {
+			ip, err := netip.ParseAddr(req.IP)
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, apiresponse.Error("invalid ip"))
+				return
+			}
+
+			query.Set(signedurl.ParamIP, ip.Unmap().String())
+		}
+
+		expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+		signed := signer.Sign(signedurl.UploadPath, query, expiresAt)
+
+		log.Info("signed upload url issued",
+			slog.String("owner", req.Owner),
+			slog.Time("expires_at", expiresAt),
+		)
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			ApiResponse: apiresponse.Success("signed url created"),
+			URL:         baseURL + signedurl.UploadPath + "?" + signed.Encode(),
+			ExpiresAt:   expiresAt.UTC(),
+		})
+	}
+}
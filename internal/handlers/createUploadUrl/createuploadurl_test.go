@@ -0,0 +1,135 @@
+package createuploadurl_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"file-service/m/internal/config"
+	"file-service/m/internal/database"
+	createuploadurl "file-service/m/internal/handlers/createUploadUrl"
+	"file-service/m/internal/handlers/createUploadUrl/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/signedurl"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateUploadUrlHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	signer, err := signedurl.New(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+
+	cfg := config.SignedURLConfig{DefaultTTL: 15 * time.Minute, MaxTTL: time.Hour}
+	handler := createuploadurl.New(log, db, signer, cfg, "")
+
+	t.Run("success", func(t *testing.T) {
+		r, w := CreateRequestAndResponse(database.RoleWriter, `{"expires_in":60,"max_size":1024,"content_types":["image/*","application/pdf"]}`)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		var body createuploadurl.Response
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), body.ExpiresAt, 2*time.Second)
+
+		u, err := url.Parse(body.URL)
+		require.NoError(t, err)
+		assert.Equal(t, signedurl.UploadPath, u.Path)
+		require.NoError(t, signer.Verify(u.Path, u.Query(), "10.0.0.1", time.Now()))
+
+		constraints, err := signedurl.ParseUploadConstraints(u.Query())
+		require.NoError(t, err)
+		assert.NotEmpty(t, constraints.Nonce)
+		assert.Equal(t, &signedurl.UploadConstraints{
+			Owner:        "alice",
+			MaxSize:      1024,
+			ContentTypes: []string{"image/*", "application/pdf"},
+			Nonce:        constraints.Nonce,
+			Expires:      body.ExpiresAt.Local(),
+		}, constraints)
+	})
+
+	t.Run("other owner as admin", func(t *testing.T) {
+		db.On("GetUserByUsername", mock.Anything, "bob").Return(&database.User{Username: "bob"}, nil).Once()
+
+		r, w := CreateRequestAndResponse(database.RoleAdmin, `{"owner":"bob"}`)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		var body createuploadurl.Response
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Contains(t, body.URL, "owner=bob")
+	})
+
+	t.Run("other owner as writer", func(t *testing.T) {
+		r, w := CreateRequestAndResponse(database.RoleWriter, `{"owner":"bob"}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("unknown owner", func(t *testing.T) {
+		db.On("GetUserByUsername", mock.Anything, "bob").Return(nil, fmt.Errorf("postgres.GetUserByUsername: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse(database.RoleAdmin, `{"owner":"bob"}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"expiry too long", `{"expires_in":7200}`, "invalid expiry"},
+		{"negative max size", `{"max_size":-1}`, "invalid max size"},
+		{"invalid content type", `{"content_types":["image"]}`, "invalid content type"},
+		{"any content type", `{"content_types":["*/*"]}`, "invalid content type"},
+		{"invalid ip", `{"ip":"nope"}`, "invalid ip"},
+		{"invalid json", `{`, "invalid request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w := CreateRequestAndResponse(database.RoleWriter, tt.body)
+
+			handler.ServeHTTP(w, r)
+
+			resp := w.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Equal(t, fmt.Sprintf("{\"status\":\"error\",\"message\":\"%s\"}\n", tt.message), string(body))
+		})
+	}
+}
+
+func CreateRequestAndResponse(role string, body string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodPost, "/file/upload-url", strings.NewReader(body))
+
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Username: "alice", Role: role})
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,100 @@
+package createuser
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/auth"
+	"file-service/m/internal/database"
+	"file-service/m/internal/policy"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+	Id int64 `json:"id,omitempty"`
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	CreateUser(ctx context.Context, user database.User) (int64, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.createuser.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid request"))
+			return
+		}
+
+		if err := auth.ValidateUsername(req.Username); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid username"))
+			return
+		}
+
+		if req.Role == "" {
+			req.Role = database.RoleWriter
+		}
+
+		if !policy.ValidRole(req.Role) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid role"))
+			return
+		}
+
+		hash, err := auth.HashPassword(req.Password)
+		if errors.Is(err, auth.ErrorInvalidPassword) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid password"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to hash password", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to create user"))
+			return
+		}
+
+		id, err := db.CreateUser(r.Context(), database.User{
+			Username:     req.Username,
+			PasswordHash: hash,
+			Role:         req.Role,
+		})
+		if errors.Is(err, database.ErrorAlreadyExists) {
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, apiresponse.Error("user already exists"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to create user", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to create user"))
+			return
+		}
+
+		log.Info("user created", slog.String("username", req.Username), slog.String("role", req.Role))
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			ApiResponse: apiresponse.Success("user created"),
+			Id:          id,
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package createuser_test
+
+import (
+	"file-service/m/internal/auth"
+	"file-service/m/internal/database"
+	createuser "file-service/m/internal/handlers/createUser"
+	"file-service/m/internal/handlers/createUser/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateUserHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := createuser.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("CreateUser", mock.Anything, mock.MatchedBy(func(user database.User) bool {
+			return user.Username == "alice" && user.Role == database.RoleAdmin &&
+				auth.CheckPassword(user.PasswordHash, "password123")
+		})).Return(int64(2), nil).Once()
+
+		r, w := CreateRequestAndResponse(`{"username":"alice","password":"password123","role":"admin"}`)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"user created\",\"id\":2}\n", string(body))
+	})
+
+	t.Run("default role", func(t *testing.T) {
+		db.On("CreateUser", mock.Anything, mock.MatchedBy(func(user database.User) bool {
+			return user.Username == "bob" && user.Role == database.RoleWriter
+		})).Return(int64(3), nil).Once()
+
+		r, w := CreateRequestAndResponse(`{"username":"bob","password":"password123"}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+	})
+
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"invalid json", `{"username":`, "invalid request"},
+		{"invalid username", `{"username":"al ice","password":"password123"}`, "invalid username"},
+		{"short password", `{"username":"alice","password":"short"}`, "invalid password"},
+		{"invalid role", `{"username":"alice","password":"password123","role":"owner"}`, "invalid role"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w := CreateRequestAndResponse(tt.body)
+
+			handler.ServeHTTP(w, r)
+
+			resp := w.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Equal(t, fmt.Sprintf("{\"status\":\"error\",\"message\":\"%s\"}\n", tt.message), string(body))
+		})
+	}
+
+	t.Run("already exists", func(t *testing.T) {
+		db.On("CreateUser", mock.Anything, mock.Anything).
+			Return(int64(0), fmt.Errorf("postgres.CreateUser: %w", database.ErrorAlreadyExists)).Once()
+
+		r, w := CreateRequestAndResponse(`{"username":"alice","password":"password123"}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("CreateUser", mock.Anything, mock.Anything).Return(int64(0), fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse(`{"username":"alice","password":"password123"}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(body string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodPost, "/admin/users", strings.NewReader(body))
+	r = r.WithContext(
+		requestctx.WithRequestId(r.Context(), "123"),
+	)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
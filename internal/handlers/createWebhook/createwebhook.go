@@ -0,0 +1,134 @@
+package createwebhook
+
+import (
+	"context"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/webhook"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/go-chi/render"
+)
+
+const (
+	maxURLLength    = 2048
+	minSecretLength = 16
+)
+
+// Request subscribes URL to EventTypes, all of them when empty. A Secret is
+// generated unless one is given.
+type Request struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+	Id     int64  `json:"id,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	CreateWebhook(ctx context.Context, webhook database.Webhook) (int64, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.createwebhook.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid request"))
+			return
+		}
+
+		if !validURL(req.URL) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid url"))
+			return
+		}
+
+		for _, eventType := range req.EventTypes {
+			if !slices.Contains(database.FileEventTypes, eventType) {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, apiresponse.Error("invalid event types"))
+				return
+			}
+		}
+
+		if req.Secret != "" && len(req.Secret) < minSecretLength {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("secret too short"))
+			return
+		}
+
+		secret := req.Secret
+		if secret == "" {
+			var err error
+			secret, err = webhook.GenerateSecret()
+			if err != nil {
+				log.Error("failed to generate secret", slog.Any("error", err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, apiresponse.Error("failed to create webhook"))
+				return
+			}
+		}
+
+		eventTypes := req.EventTypes
+		if eventTypes == nil {
+			eventTypes = []string{}
+		}
+
+		id, err := db.CreateWebhook(r.Context(), database.Webhook{
+			URL:        req.URL,
+			Secret:     secret,
+			EventTypes: eventTypes,
+			CreatedBy:  user.Username,
+		})
+		if err != nil {
+			log.Error("failed to create webhook", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to create webhook"))
+			return
+		}
+
+		log.Info("webhook created", slog.Int64("webhook_id", id))
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			ApiResponse: apiresponse.Success("webhook created"),
+			Id:          id,
+			Secret:      secret,
+		})
+	}
+}
+
+func validURL(raw string) bool {
+	if raw == "" || len(raw) > maxURLLength {
+		return false
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
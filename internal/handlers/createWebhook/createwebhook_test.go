@@ -0,0 +1,111 @@
+package createwebhook_test
+
+import (
+	"encoding/json"
+	"file-service/m/internal/database"
+	createwebhook "file-service/m/internal/handlers/createWebhook"
+	"file-service/m/internal/handlers/createWebhook/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateWebhookHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := createwebhook.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		var stored database.Webhook
+		db.On("CreateWebhook", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(database.Webhook)
+		}).Return(int64(3), nil).Once()
+
+		r, w := CreateRequestAndResponse(`{"url":"https://example.com/hook","event_types":["file.created"]}`)
+
+		handler.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusCreated, w.Result().StatusCode)
+
+		var resp createwebhook.Response
+		require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&resp))
+
+		assert.Equal(t, int64(3), resp.Id)
+		assert.Len(t, resp.Secret, 64)
+		assert.Equal(t, resp.Secret, stored.Secret)
+		assert.Equal(t, "https://example.com/hook", stored.URL)
+		assert.Equal(t, []string{database.FileEventCreated}, stored.EventTypes)
+		assert.Equal(t, "alice", stored.CreatedBy)
+	})
+
+	t.Run("all events with own secret", func(t *testing.T) {
+		db.On("CreateWebhook", mock.Anything, database.Webhook{
+			URL:        "http://hooks.internal/files",
+			Secret:     "0123456789abcdef",
+			EventTypes: []string{},
+			CreatedBy:  "alice",
+		}).Return(int64(4), nil).Once()
+
+		r, w := CreateRequestAndResponse(`{"url":"http://hooks.internal/files","secret":"0123456789abcdef"}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+	})
+
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"invalid json", `{"url":`, "invalid request"},
+		{"missing url", `{}`, "invalid url"},
+		{"unsupported scheme", `{"url":"ftp://example.com"}`, "invalid url"},
+		{"missing host", `{"url":"https://"}`, "invalid url"},
+		{"unknown event", `{"url":"https://example.com","event_types":["file.renamed"]}`, "invalid event types"},
+		{"short secret", `{"url":"https://example.com","secret":"abc"}`, "secret too short"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w := CreateRequestAndResponse(tt.body)
+
+			handler.ServeHTTP(w, r)
+
+			resp := w.Result()
+			var body createwebhook.Response
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Equal(t, tt.message, body.Message)
+		})
+	}
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("CreateWebhook", mock.Anything, mock.Anything).Return(int64(0), fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse(`{"url":"https://example.com/hook"}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(body string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodPost, "/admin/webhooks", strings.NewReader(body))
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Id: 7, Username: "alice", Role: database.RoleAdmin})
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -1,8 +1,13 @@
 package delete
 
 import (
+	"context"
+	"errors"
+	"file-service/m/internal/access"
 	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/audit"
 	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -16,25 +21,31 @@ type Response struct {
 
 //go:generate mockery --name=Db
 type Db interface {
-	GetFile(id int64, isDeleted bool) (*database.File, error)
-	DeleteFile(id int64) (int64, error)
+	GetFile(ctx context.Context, id int64, isDeleted bool) (*database.File, error)
+	DeleteFile(ctx context.Context, id int64) (int64, error)
+	GetFilePermissions(ctx context.Context, fileId int64, username string, groups []string) ([]string, error)
 }
 
 //go:generate mockery --name=Storage
 type Storage interface {
-	DeleteFile(name string) error
+	DeleteFile(ctx context.Context, name string) error
 }
 
 func New(logger *slog.Logger, db Db, storage Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.delete.New"
 
-		log := *logger.With(
-			slog.String("op", op),
-			slog.String("request_id", r.Context().Value("requestId").(string)),
-		)
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
 
-		fileIdStr, ok := r.Context().Value("fileID").(string)
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		fileIdStr, ok := requestctx.FileId(r.Context())
 		if !ok {
 			log.Error("file id is empty")
 			render.Status(r, http.StatusBadRequest)
@@ -57,7 +68,13 @@ func New(logger *slog.Logger, db Db, storage Storage) http.HandlerFunc {
 			return
 		}
 
-		file, err := db.GetFile(fileId, true)
+		file, err := db.GetFile(r.Context(), fileId, true)
+		if errors.Is(err, database.ErrorNotFound) {
+			log.Info("file not found")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
 		if err != nil {
 			log.Error("failed to get file", slog.Any("error", err))
 			render.Status(r, http.StatusInternalServerError)
@@ -65,7 +82,24 @@ func New(logger *slog.Logger, db Db, storage Storage) http.HandlerFunc {
 			return
 		}
 
-		err = storage.DeleteFile(file.Name)
+		audit.SetFile(r.Context(), file.Id, file.PublicId)
+
+		allowed, err := access.Allowed(r.Context(), db, user, file, database.PermissionWrite)
+		if err != nil {
+			log.Error("failed to check access", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to delete file"))
+			return
+		}
+
+		if !allowed {
+			log.Info("access denied")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
+
+		err = storage.DeleteFile(r.Context(), file.Name)
 		if err != nil {
 			log.Error("failed to delete file", slog.Any("error", err))
 			render.Status(r, http.StatusInternalServerError)
@@ -73,7 +107,9 @@ func New(logger *slog.Logger, db Db, storage Storage) http.HandlerFunc {
 			return
 		}
 
-		affectedRows, err := db.DeleteFile(fileId)
+		// the blob is gone, a client that leaves now must not keep the row
+		// pointing at it
+		affectedRows, err := db.DeleteFile(context.WithoutCancel(r.Context()), fileId)
 		if err != nil {
 			log.Error("failed to set file as deleted", slog.Any("error", err))
 			render.Status(r, http.StatusInternalServerError)
@@ -88,7 +124,7 @@ func New(logger *slog.Logger, db Db, storage Storage) http.HandlerFunc {
 			return
 		}
 
-		log.Info("file deleted", slog.Int64("file_id", fileId))
+		log.Info("file deleted")
 		render.Status(r, http.StatusOK)
 		render.JSON(w, r, Response{apiresponse.Success("file deleted")})
 	}
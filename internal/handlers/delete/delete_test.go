@@ -6,6 +6,7 @@ import (
 	"file-service/m/internal/handlers/delete"
 	"file-service/m/internal/handlers/delete/mocks"
 	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
 	"fmt"
 	"io"
 	"net/http"
@@ -24,11 +25,11 @@ func TestDeleteHandler(t *testing.T) {
 	handler := delete.New(log, db, storage)
 
 	t.Run("success", func(t *testing.T) {
-		db.On("GetFile", mock.Anything, mock.Anything).Return(&database.File{}, nil).Once()
-		storage.On("DeleteFile", mock.Anything).Return(nil).Once()
-		db.On("DeleteFile", mock.Anything).Return(int64(1), nil).Once()
+		db.On("GetFile", mock.Anything, mock.Anything, mock.Anything).Return(&database.File{Owner: "alice"}, nil).Once()
+		storage.On("DeleteFile", mock.Anything, mock.Anything).Return(nil).Once()
+		db.On("DeleteFile", mock.Anything, mock.Anything).Return(int64(1), nil).Once()
 
-		r, w := CreateRequestAndResponse("fileID", "1")
+		r, w := CreateRequestAndResponse("1")
 
 		handler.ServeHTTP(w, r)
 
@@ -40,10 +41,61 @@ func TestDeleteHandler(t *testing.T) {
 		assert.Equal(t, "{\"status\":\"success\",\"message\":\"file deleted\"}\n", bodyResp)
 	})
 
+	t.Run("client gone after storage delete", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("1")
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		db.On("GetFile", mock.Anything, mock.Anything, mock.Anything).Return(&database.File{Owner: "alice"}, nil).Once()
+		storage.On("DeleteFile", mock.Anything, mock.Anything).Run(func(args mock.Arguments) { cancel() }).Return(nil).Once()
+		db.On("DeleteFile", mock.MatchedBy(func(ctx context.Context) bool { return ctx.Err() == nil }), int64(1)).
+			Return(int64(1), nil).Once()
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+
+	t.Run("access", func(t *testing.T) {
+		file := &database.File{Id: 1, Owner: "bob", Name: "name"}
+
+		db.On("GetFile", mock.Anything, int64(1), true).Return(file, nil).Once()
+		db.On("GetFilePermissions", mock.Anything, int64(1), "alice", []string(nil)).Return([]string{database.PermissionRead}, nil).Once()
+
+		r, w := CreateRequestAndResponse("1")
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"file not found\"}\n", string(body))
+
+		db.On("GetFile", mock.Anything, int64(1), true).Return(file, nil).Once()
+		db.On("GetFilePermissions", mock.Anything, int64(1), "alice", []string(nil)).Return([]string{database.PermissionWrite}, nil).Once()
+		storage.On("DeleteFile", mock.Anything, "name").Return(nil).Once()
+		db.On("DeleteFile", mock.Anything, int64(1)).Return(int64(1), nil).Once()
+
+		r, w = CreateRequestAndResponse("1")
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(2), true).Return(nil, fmt.Errorf("postgres.GetFile: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("2")
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
 	t.Run("db get file error", func(t *testing.T) {
-		db.On("GetFile", mock.Anything, mock.Anything).Return(nil, errorResp).Once()
+		db.On("GetFile", mock.Anything, mock.Anything, mock.Anything).Return(nil, errorResp).Once()
 
-		r, w := CreateRequestAndResponse("fileID", "1")
+		r, w := CreateRequestAndResponse("1")
 
 		handler.ServeHTTP(w, r)
 
@@ -56,10 +108,10 @@ func TestDeleteHandler(t *testing.T) {
 	})
 
 	t.Run("storage delete file error", func(t *testing.T) {
-		db.On("GetFile", mock.Anything, mock.Anything).Return(&database.File{}, nil).Once()
-		storage.On("DeleteFile", mock.Anything).Return(errorResp).Once()
+		db.On("GetFile", mock.Anything, mock.Anything, mock.Anything).Return(&database.File{Owner: "alice"}, nil).Once()
+		storage.On("DeleteFile", mock.Anything, mock.Anything).Return(errorResp).Once()
 
-		r, w := CreateRequestAndResponse("fileID", "1")
+		r, w := CreateRequestAndResponse("1")
 
 		handler.ServeHTTP(w, r)
 
@@ -72,11 +124,11 @@ func TestDeleteHandler(t *testing.T) {
 	})
 
 	t.Run("db delete file error", func(t *testing.T) {
-		db.On("GetFile", mock.Anything, mock.Anything).Return(&database.File{}, nil).Once()
-		storage.On("DeleteFile", mock.Anything).Return(nil).Once()
-		db.On("DeleteFile", mock.Anything).Return(int64(0), errorResp).Once()
+		db.On("GetFile", mock.Anything, mock.Anything, mock.Anything).Return(&database.File{Owner: "alice"}, nil).Once()
+		storage.On("DeleteFile", mock.Anything, mock.Anything).Return(nil).Once()
+		db.On("DeleteFile", mock.Anything, mock.Anything).Return(int64(0), errorResp).Once()
 
-		r, w := CreateRequestAndResponse("fileID", "1")
+		r, w := CreateRequestAndResponse("1")
 
 		handler.ServeHTTP(w, r)
 
@@ -89,7 +141,7 @@ func TestDeleteHandler(t *testing.T) {
 	})
 
 	t.Run("invalid file id", func(t *testing.T) {
-		r, w := CreateRequestAndResponse("fileID", "1asd")
+		r, w := CreateRequestAndResponse("1asd")
 
 		handler.ServeHTTP(w, r)
 
@@ -101,8 +153,8 @@ func TestDeleteHandler(t *testing.T) {
 		assert.Equal(t, "{\"status\":\"error\",\"message\":\"invalid file id\"}\n", bodyResp)
 	})
 
-	t.Run("invalid fileKey", func(t *testing.T) {
-		r, w := CreateRequestAndResponse("fileKey", "1")
+	t.Run("missing file id", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("")
 
 		handler.ServeHTTP(w, r)
 
@@ -115,13 +167,16 @@ func TestDeleteHandler(t *testing.T) {
 	})
 }
 
-func CreateRequestAndResponse(fileKey string, fileId string) (*http.Request, *httptest.ResponseRecorder) {
+func CreateRequestAndResponse(fileId string) (*http.Request, *httptest.ResponseRecorder) {
 	r := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/%s", fileId), nil)
+	if fileId != "" {
+		r = r.WithContext(requestctx.WithFileId(r.Context(), fileId))
+	}
 	r = r.WithContext(
-		context.WithValue(r.Context(), fileKey, fileId),
+		requestctx.WithRequestId(r.Context(), "123"),
 	)
 	r = r.WithContext(
-		context.WithValue(r.Context(), "requestId", "123"),
+		requestctx.WithUser(r.Context(), &database.User{Username: "alice"}),
 	)
 	w := httptest.NewRecorder()
 
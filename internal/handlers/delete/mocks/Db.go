@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	database "file-service/m/internal/database"
 
 	mock "github.com/stretchr/testify/mock"
@@ -13,9 +15,9 @@ type Db struct {
 	mock.Mock
 }
 
-// DeleteFile provides a mock function with given fields: id
-func (_m *Db) DeleteFile(id int64) (int64, error) {
-	ret := _m.Called(id)
+// DeleteFile provides a mock function with given fields: ctx, id
+func (_m *Db) DeleteFile(ctx context.Context, id int64) (int64, error) {
+	ret := _m.Called(ctx, id)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteFile")
@@ -23,17 +25,17 @@ func (_m *Db) DeleteFile(id int64) (int64, error) {
 
 	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int64) (int64, error)); ok {
-		return rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (int64, error)); ok {
+		return rf(ctx, id)
 	}
-	if rf, ok := ret.Get(0).(func(int64) int64); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) int64); ok {
+		r0 = rf(ctx, id)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func(int64) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -41,9 +43,9 @@ func (_m *Db) DeleteFile(id int64) (int64, error) {
 	return r0, r1
 }
 
-// GetFile provides a mock function with given fields: id, isDeleted
-func (_m *Db) GetFile(id int64, isDeleted bool) (*database.File, error) {
-	ret := _m.Called(id, isDeleted)
+// GetFile provides a mock function with given fields: ctx, id, isDeleted
+func (_m *Db) GetFile(ctx context.Context, id int64, isDeleted bool) (*database.File, error) {
+	ret := _m.Called(ctx, id, isDeleted)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetFile")
@@ -51,19 +53,49 @@ func (_m *Db) GetFile(id int64, isDeleted bool) (*database.File, error) {
 
 	var r0 *database.File
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int64, bool) (*database.File, error)); ok {
-		return rf(id, isDeleted)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, bool) (*database.File, error)); ok {
+		return rf(ctx, id, isDeleted)
 	}
-	if rf, ok := ret.Get(0).(func(int64, bool) *database.File); ok {
-		r0 = rf(id, isDeleted)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, bool) *database.File); ok {
+		r0 = rf(ctx, id, isDeleted)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*database.File)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int64, bool) error); ok {
-		r1 = rf(id, isDeleted)
+	if rf, ok := ret.Get(1).(func(context.Context, int64, bool) error); ok {
+		r1 = rf(ctx, id, isDeleted)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFilePermissions provides a mock function with given fields: ctx, fileId, username, groups
+func (_m *Db) GetFilePermissions(ctx context.Context, fileId int64, username string, groups []string) ([]string, error) {
+	ret := _m.Called(ctx, fileId, username, groups)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFilePermissions")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, []string) ([]string, error)); ok {
+		return rf(ctx, fileId, username, groups)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, []string) []string); ok {
+		r0 = rf(ctx, fileId, username, groups)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, []string) error); ok {
+		r1 = rf(ctx, fileId, username, groups)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -2,24 +2,28 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // Storage is an autogenerated mock type for the Storage type
 type Storage struct {
 	mock.Mock
 }
 
-// DeleteFile provides a mock function with given fields: name
-func (_m *Storage) DeleteFile(name string) error {
-	ret := _m.Called(name)
+// DeleteFile provides a mock function with given fields: ctx, name
+func (_m *Storage) DeleteFile(ctx context.Context, name string) error {
+	ret := _m.Called(ctx, name)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteFile")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(name)
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, name)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -0,0 +1,65 @@
+package deleteapikey
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	apiresponse.ApiResponse
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	DeleteApiKey(ctx context.Context, userId int64, id int64) (int64, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.deleteapikey.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		keyId, err := strconv.ParseInt(chi.URLParam(r, "keyID"), 10, 64)
+		if err != nil {
+			log.Error("failed to parse key id", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid key id"))
+			return
+		}
+
+		_, err = db.DeleteApiKey(r.Context(), user.Id, keyId)
+		if errors.Is(err, database.ErrorNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("api key not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete api key", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to delete api key"))
+			return
+		}
+
+		log.Info("api key deleted", slog.Int64("key_id", keyId))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{apiresponse.Success("api key deleted")})
+	}
+}
@@ -0,0 +1,83 @@
+package deleteapikey_test
+
+import (
+	"context"
+	"file-service/m/internal/database"
+	deleteapikey "file-service/m/internal/handlers/deleteApiKey"
+	"file-service/m/internal/handlers/deleteApiKey/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDeleteApiKeyHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := deleteapikey.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("DeleteApiKey", mock.Anything, int64(7), int64(3)).Return(int64(1), nil).Once()
+
+		r, w := CreateRequestAndResponse("3")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"api key deleted\"}\n", string(body))
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("abc")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db.On("DeleteApiKey", mock.Anything, int64(7), int64(4)).
+			Return(int64(0), fmt.Errorf("postgres.DeleteApiKey: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("4")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("DeleteApiKey", mock.Anything, int64(7), int64(3)).Return(int64(0), fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse("3")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(keyId string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodDelete, "/keys/"+keyId, nil)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("keyID", keyId)
+
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Id: 7, Username: "alice"})
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
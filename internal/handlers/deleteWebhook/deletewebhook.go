@@ -0,0 +1,57 @@
+package deletewebhook
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	apiresponse.ApiResponse
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	DeleteWebhook(ctx context.Context, id int64) (int64, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.deletewebhook.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		webhookId, err := strconv.ParseInt(chi.URLParam(r, "webhookID"), 10, 64)
+		if err != nil {
+			log.Error("failed to parse webhook id", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid webhook id"))
+			return
+		}
+
+		_, err = db.DeleteWebhook(r.Context(), webhookId)
+		if errors.Is(err, database.ErrorNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("webhook not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete webhook", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to delete webhook"))
+			return
+		}
+
+		log.Info("webhook deleted", slog.Int64("webhook_id", webhookId))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{apiresponse.Success("webhook deleted")})
+	}
+}
@@ -0,0 +1,82 @@
+package deletewebhook_test
+
+import (
+	"context"
+	"file-service/m/internal/database"
+	deletewebhook "file-service/m/internal/handlers/deleteWebhook"
+	"file-service/m/internal/handlers/deleteWebhook/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDeleteWebhookHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := deletewebhook.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("DeleteWebhook", mock.Anything, int64(3)).Return(int64(1), nil).Once()
+
+		r, w := CreateRequestAndResponse("3")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"webhook deleted\"}\n", string(body))
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("abc")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db.On("DeleteWebhook", mock.Anything, int64(4)).
+			Return(int64(0), fmt.Errorf("postgres.DeleteWebhook: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("4")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("DeleteWebhook", mock.Anything, int64(3)).Return(int64(0), fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse("3")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(webhookId string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodDelete, "/admin/webhooks/"+webhookId, nil)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("webhookID", webhookId)
+
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,118 @@
+package download
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/audit"
+	"file-service/m/internal/clientip"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/signedurl"
+	"file-service/m/internal/storage"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+//go:generate mockery --name=Db
+type Db interface {
+	GetFileId(ctx context.Context, publicId string) (int64, error)
+	GetFile(ctx context.Context, id int64, isDeleted bool) (*database.File, error)
+}
+
+//go:generate mockery --name=Storage
+type Storage interface {
+	GetFile(ctx context.Context, name string, meta storage.Meta) ([]byte, error)
+}
+
+// New serves files to holders of a signed URL. It runs without
+// authentication, the signature is the only credential.
+func New(logger *slog.Logger, db Db, fileStorage Storage, signer *signedurl.Signer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.download.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		publicId := chi.URLParam(r, "fileID")
+		query := r.URL.Query()
+
+		err := signer.Verify(signedurl.DownloadPath(publicId), query, clientip.Get(r), time.Now())
+		if errors.Is(err, signedurl.ErrorExpired) {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, apiresponse.Error("url expired"))
+			return
+		}
+		if err != nil {
+			log.Info("invalid signed url", slog.String("file_id", publicId), slog.Any("error", err))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, apiresponse.Error("invalid signature"))
+			return
+		}
+
+		fileId, err := db.GetFileId(r.Context(), publicId)
+		if errors.Is(err, database.ErrorNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to resolve file id", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to get file"))
+			return
+		}
+
+		file, err := db.GetFile(r.Context(), fileId, false)
+		if errors.Is(err, database.ErrorNotFound) {
+			log.Info("file not found", slog.Int64("file_id", fileId))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get file", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to get file"))
+			return
+		}
+
+		audit.SetFile(r.Context(), file.Id, file.PublicId)
+
+		if file.KeyFingerprint != "" {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, apiresponse.Error("file is encrypted with a customer key"))
+			return
+		}
+
+		meta := storage.Meta{
+			KeyId:      file.KeyId,
+			WrappedKey: file.WrappedKey,
+			Encoding:   file.Encoding,
+		}
+
+		data, err := fileStorage.GetFile(r.Context(), file.Name, meta)
+		if err != nil {
+			log.Error("failed to get file from storage", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to get file"))
+			return
+		}
+
+		// the disposition is covered by the signature and was normalized
+		// when the URL was issued
+		if disposition := query.Get(signedurl.ParamDisposition); disposition != "" {
+			w.Header().Set("Content-Disposition", disposition)
+		}
+
+		w.Header().Set("Cache-Control", "private, no-store")
+
+		log.Info("sending file", slog.Int64("file_id", fileId))
+		audit.SetBytes(r.Context(), int64(len(data)))
+		render.Status(r, http.StatusOK)
+		w.Write(data)
+	}
+}
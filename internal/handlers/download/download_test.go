@@ -0,0 +1,153 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"file-service/m/internal/database"
+	"file-service/m/internal/handlers/download"
+	"file-service/m/internal/handlers/download/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/signedurl"
+	fileStorage "file-service/m/internal/storage"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const publicId = "0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f"
+
+func TestDownloadHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	storage := mocks.NewStorage(t)
+	signer, err := signedurl.New(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+
+	handler := download.New(log, db, storage, signer)
+	path := signedurl.DownloadPath(publicId)
+	file := &database.File{Id: 1, PublicId: publicId, Name: "name", KeyId: "k1"}
+
+	t.Run("success", func(t *testing.T) {
+		db.On("GetFileId", mock.Anything, publicId).Return(int64(1), nil).Once()
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+		storage.On("GetFile", mock.Anything, "name", mock.MatchedBy(func(meta fileStorage.Meta) bool {
+			return meta.KeyId == "k1" && !meta.KeepEncoding
+		})).Return([]byte("test"), nil).Once()
+
+		query := signer.Sign(path, url.Values{"disposition": {"attachment; filename=a.txt"}}, time.Now().Add(time.Minute))
+		r, w := CreateRequestAndResponse(publicId, query)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []byte("test"), body)
+		assert.Equal(t, "attachment; filename=a.txt", resp.Header.Get("Content-Disposition"))
+	})
+
+	t.Run("bound ip", func(t *testing.T) {
+		db.On("GetFileId", mock.Anything, publicId).Return(int64(1), nil).Once()
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+		storage.On("GetFile", mock.Anything, "name", mock.Anything).Return([]byte("test"), nil).Once()
+
+		query := signer.Sign(path, url.Values{"ip": {"192.0.2.1"}}, time.Now().Add(time.Minute))
+		r, w := CreateRequestAndResponse(publicId, query)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+		query = signer.Sign(path, url.Values{"ip": {"192.0.2.2"}}, time.Now().Add(time.Minute))
+		r, w = CreateRequestAndResponse(publicId, query)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		query := signer.Sign(path, url.Values{}, time.Now().Add(-time.Minute))
+		r, w := CreateRequestAndResponse(publicId, query)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"url expired\"}\n", string(body))
+	})
+
+	t.Run("signed for another file", func(t *testing.T) {
+		query := signer.Sign(signedurl.DownloadPath("other"), url.Values{}, time.Now().Add(time.Minute))
+		r, w := CreateRequestAndResponse(publicId, query)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"invalid signature\"}\n", string(body))
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		r, w := CreateRequestAndResponse(publicId, url.Values{})
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("trashed", func(t *testing.T) {
+		db.On("GetFileId", mock.Anything, publicId).Return(int64(1), nil).Once()
+		db.On("GetFile", mock.Anything, int64(1), false).Return(nil, fmt.Errorf("postgres.GetFile: %w", database.ErrorNotFound)).Once()
+
+		query := signer.Sign(path, url.Values{}, time.Now().Add(time.Minute))
+		r, w := CreateRequestAndResponse(publicId, query)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		db.On("GetFileId", mock.Anything, publicId).Return(int64(1), nil).Once()
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+		storage.On("GetFile", mock.Anything, "name", mock.Anything).Return(nil, fmt.Errorf("error")).Once()
+
+		query := signer.Sign(path, url.Values{}, time.Now().Add(time.Minute))
+		r, w := CreateRequestAndResponse(publicId, query)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(fileId string, query url.Values) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodGet, signedurl.DownloadPath(fileId)+"?"+query.Encode(), nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("fileID", fileId)
+
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
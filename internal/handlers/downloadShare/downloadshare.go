@@ -0,0 +1,90 @@
+package downloadshare
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/audit"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/storage"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+//go:generate mockery --name=Db
+type Db interface {
+	RecordShareDownload(ctx context.Context, id int64) (int64, error)
+}
+
+//go:generate mockery --name=Storage
+type Storage interface {
+	GetFile(ctx context.Context, name string, meta storage.Meta) ([]byte, error)
+}
+
+// New serves the file of a public share and counts the download. It must run
+// after sharectxmiddleware.ShareCtx.
+func New(logger *slog.Logger, db Db, fileStorage Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.downloadshare.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		share, file, ok := requestctx.Share(r.Context())
+		if !ok {
+			log.Error("share is not resolved")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("share not found"))
+			return
+		}
+
+		if file.KeyFingerprint != "" {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, apiresponse.Error("file is encrypted with a customer key"))
+			return
+		}
+
+		// counted before sending so that concurrent downloads cannot exceed
+		// the limit
+		_, err := db.RecordShareDownload(r.Context(), share.Id)
+		if errors.Is(err, database.ErrorNotFound) {
+			render.Status(r, http.StatusGone)
+			render.JSON(w, r, apiresponse.Error("download limit reached"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to record share download", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to get file"))
+			return
+		}
+
+		meta := storage.Meta{
+			KeyId:      file.KeyId,
+			WrappedKey: file.WrappedKey,
+			Encoding:   file.Encoding,
+		}
+
+		data, err := fileStorage.GetFile(r.Context(), file.Name, meta)
+		if err != nil {
+			log.Error("failed to get file from storage", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to get file"))
+			return
+		}
+
+		if disposition := mime.FormatMediaType("attachment", map[string]string{"filename": file.OriginalName}); disposition != "" {
+			w.Header().Set("Content-Disposition", disposition)
+		}
+
+		w.Header().Set("Cache-Control", "private, no-store")
+
+		log.Info("sending shared file")
+		audit.SetBytes(r.Context(), int64(len(data)))
+		render.Status(r, http.StatusOK)
+		w.Write(data)
+	}
+}
@@ -0,0 +1,78 @@
+package downloadshare_test
+
+import (
+	"file-service/m/internal/database"
+	downloadshare "file-service/m/internal/handlers/downloadShare"
+	"file-service/m/internal/handlers/downloadShare/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDownloadShareHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	storage := mocks.NewStorage(t)
+	handler := downloadshare.New(log, db, storage)
+	share := &database.Share{Id: 7, FileId: 1}
+	file := &database.File{Id: 1, Name: "name", OriginalName: "report 1.pdf"}
+
+	t.Run("success", func(t *testing.T) {
+		db.On("RecordShareDownload", mock.Anything, int64(7)).Return(int64(1), nil).Once()
+		storage.On("GetFile", mock.Anything, "name", mock.Anything).Return([]byte("test"), nil).Once()
+
+		r, w := CreateRequestAndResponse(share, file)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []byte("test"), body)
+		assert.Equal(t, `attachment; filename="report 1.pdf"`, resp.Header.Get("Content-Disposition"))
+	})
+
+	t.Run("limit reached concurrently", func(t *testing.T) {
+		db.On("RecordShareDownload", mock.Anything, int64(7)).Return(int64(0), fmt.Errorf("postgres.RecordShareDownload: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse(share, file)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusGone, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"download limit reached\"}\n", string(body))
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		db.On("RecordShareDownload", mock.Anything, int64(7)).Return(int64(1), nil).Once()
+		storage.On("GetFile", mock.Anything, "name", mock.Anything).Return(nil, fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse(share, file)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(share *database.Share, file *database.File) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodGet, "/s/token/download", nil)
+
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = requestctx.WithShare(ctx, share, file)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
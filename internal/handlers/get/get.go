@@ -1,11 +1,21 @@
 package get
 
 import (
+	"context"
+	"errors"
+	"file-service/m/internal/access"
 	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/audit"
+	"file-service/m/internal/customerkey"
 	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/storage"
 	"log/slog"
+	"mime"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/render"
 )
@@ -16,24 +26,30 @@ type Response struct {
 
 //go:generate mockery --name=Db
 type Db interface {
-	GetFile(id int64, isDeleted bool) (*database.File, error)
+	GetFile(ctx context.Context, id int64, isDeleted bool) (*database.File, error)
+	GetFilePermissions(ctx context.Context, fileId int64, username string, groups []string) ([]string, error)
 }
 
 //go:generate mockery --name=Storage
 type Storage interface {
-	GetFile(name string) ([]byte, error)
+	GetFile(ctx context.Context, name string, meta storage.Meta) ([]byte, error)
 }
 
-func New(logger *slog.Logger, db Db, storage Storage) http.HandlerFunc {
+func New(logger *slog.Logger, db Db, fileStorage Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.get.New"
 
-		log := *logger.With(
-			slog.String("op", op),
-			slog.String("request_id", r.Context().Value("requestId").(string)),
-		)
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
 
-		fileIdStr, ok := r.Context().Value("fileID").(string)
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		fileIdStr, ok := requestctx.FileId(r.Context())
 		if !ok {
 			log.Error("file id is empty")
 			render.Status(r, http.StatusBadRequest)
@@ -56,7 +72,13 @@ func New(logger *slog.Logger, db Db, storage Storage) http.HandlerFunc {
 			return
 		}
 
-		file, err := db.GetFile(fileId, false)
+		file, err := db.GetFile(r.Context(), fileId, false)
+		if errors.Is(err, database.ErrorNotFound) {
+			log.Info("file not found")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
 		if err != nil {
 			log.Error("failed to get file", slog.Any("error", err))
 			render.Status(r, http.StatusInternalServerError)
@@ -64,7 +86,47 @@ func New(logger *slog.Logger, db Db, storage Storage) http.HandlerFunc {
 			return
 		}
 
-		data, err := storage.GetFile(file.Name)
+		audit.SetFile(r.Context(), file.Id, file.PublicId)
+
+		allowed, err := access.Allowed(r.Context(), db, user, file, database.PermissionRead)
+		if err != nil {
+			log.Error("failed to check access", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to get file"))
+			return
+		}
+
+		if !allowed {
+			log.Info("access denied")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
+
+		customerKey, err := customerkey.FromRequest(r)
+		if err != nil {
+			log.Error("invalid customer key", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid encryption key"))
+			return
+		}
+
+		if file.KeyFingerprint != "" && !customerkey.Matches(customerKey, file.KeyFingerprint) {
+			log.Error("customer key does not match")
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, apiresponse.Error("invalid encryption key"))
+			return
+		}
+
+		meta := storage.Meta{
+			KeyId:        file.KeyId,
+			WrappedKey:   file.WrappedKey,
+			Encoding:     file.Encoding,
+			CustomerKey:  customerKey,
+			KeepEncoding: file.Encoding != "" && acceptsEncoding(r, file.Encoding),
+		}
+
+		data, err := fileStorage.GetFile(r.Context(), file.Name, meta)
 		if err != nil {
 			log.Error("failed to get file from storage", slog.Any("error", err))
 			render.Status(r, http.StatusInternalServerError)
@@ -72,8 +134,67 @@ func New(logger *slog.Logger, db Db, storage Storage) http.HandlerFunc {
 			return
 		}
 
-		log.Info("sending file", slog.Int64("file_id", fileId))
+		if file.Encoding != "" {
+			w.Header().Add("Vary", "Accept-Encoding")
+		}
+
+		size := int64(len(data))
+
+		if meta.KeepEncoding {
+			// sniffing would find the encoded bytes, the type has to come
+			// from the name
+			w.Header().Set("Content-Type", contentType(file.OriginalName))
+			w.Header().Set("Content-Encoding", file.Encoding)
+			size = int64(file.Size)
+		}
+
+		log.Info("sending file")
+		audit.SetBytes(r.Context(), size)
 		render.Status(r, http.StatusOK)
 		w.Write(data)
 	}
 }
+
+// contentType returns the media type of a file by the extension of its
+// name.
+func contentType(name string) string {
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+
+	return "application/octet-stream"
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header of r allows
+// encoding, honouring q=0 exclusions and the "*" wildcard.
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	accepted := false
+
+	for _, value := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+
+			if name != encoding && name != "*" {
+				continue
+			}
+
+			excluded := false
+			for _, param := range strings.Split(params, ";") {
+				key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if key == "q" {
+					q, err := strconv.ParseFloat(value, 64)
+					excluded = err != nil || q == 0
+				}
+			}
+
+			if name == encoding {
+				return !excluded
+			}
+
+			accepted = !excluded
+		}
+	}
+
+	return accepted
+}
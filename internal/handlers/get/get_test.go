@@ -1,11 +1,15 @@
 package get_test
 
 import (
-	"context"
+	"bytes"
+	"encoding/base64"
+	"file-service/m/internal/customerkey"
 	"file-service/m/internal/database"
 	"file-service/m/internal/handlers/get"
 	"file-service/m/internal/handlers/get/mocks"
 	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	fileStorage "file-service/m/internal/storage"
 	"fmt"
 	"io"
 	"net/http"
@@ -24,10 +28,10 @@ func TestGetHandler(t *testing.T) {
 	handler := get.New(log, db, storage)
 
 	t.Run("success", func(t *testing.T) {
-		db.On("GetFile", mock.Anything, mock.Anything).Return(&database.File{Id: 1}, nil).Once()
-		storage.On("GetFile", mock.Anything).Return([]byte("test"), nil).Once()
+		db.On("GetFile", mock.Anything, mock.Anything, mock.Anything).Return(&database.File{Id: 1, Owner: "alice"}, nil).Once()
+		storage.On("GetFile", mock.Anything, mock.Anything, mock.Anything).Return([]byte("test"), nil).Once()
 
-		r, w := CreateRequestAndResponse("fileID", "1")
+		r, w := CreateRequestAndResponse("1")
 
 		handler.ServeHTTP(w, r)
 
@@ -38,10 +42,149 @@ func TestGetHandler(t *testing.T) {
 		assert.Equal(t, []byte("test"), body)
 	})
 
+	t.Run("access", func(t *testing.T) {
+		file := &database.File{Id: 1, Owner: "bob", Name: "name"}
+
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+		db.On("GetFilePermissions", mock.Anything, int64(1), "alice", []string(nil)).Return(nil, nil).Once()
+
+		r, w := CreateRequestAndResponse("1")
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"file not found\"}\n", string(body))
+
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+		db.On("GetFilePermissions", mock.Anything, int64(1), "alice", []string(nil)).Return([]string{database.PermissionRead}, nil).Once()
+		storage.On("GetFile", mock.Anything, "name", mock.Anything).Return([]byte("test"), nil).Once()
+
+		r, w = CreateRequestAndResponse("1")
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(2), false).Return(nil, fmt.Errorf("postgres.GetFile: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("2")
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"file not found\"}\n", string(body))
+	})
+
+	t.Run("storage meta is passed", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, mock.Anything, mock.Anything).Return(&database.File{
+			Id:         1,
+			Owner:      "alice",
+			Name:       "name",
+			KeyId:      "key",
+			WrappedKey: []byte("wrapped"),
+		}, nil).Once()
+		storage.On("GetFile", mock.Anything, "name", fileStorage.Meta{KeyId: "key", WrappedKey: []byte("wrapped")}).Return([]byte("test"), nil).Once()
+
+		r, w := CreateRequestAndResponse("1")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("customer key", func(t *testing.T) {
+		key := bytes.Repeat([]byte("k"), 32)
+		file := &database.File{
+			Id:             1,
+			Owner:          "alice",
+			Name:           "name",
+			KeyId:          "customer",
+			KeyFingerprint: customerkey.Fingerprint(key),
+		}
+
+		db.On("GetFile", mock.Anything, mock.Anything, mock.Anything).Return(file, nil).Once()
+		storage.On("GetFile", mock.Anything, "name", fileStorage.Meta{KeyId: "customer", CustomerKey: key}).Return([]byte("test"), nil).Once()
+
+		r, w := CreateRequestAndResponse("1")
+		SetCustomerKey(r, key)
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []byte("test"), body)
+
+		for name, key := range map[string][]byte{
+			"missing": nil,
+			"wrong":   bytes.Repeat([]byte("x"), 32),
+		} {
+			db.On("GetFile", mock.Anything, mock.Anything, mock.Anything).Return(file, nil).Once()
+
+			r, w := CreateRequestAndResponse("1")
+			if key != nil {
+				SetCustomerKey(r, key)
+			}
+			handler.ServeHTTP(w, r)
+
+			resp := w.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, http.StatusForbidden, resp.StatusCode, name)
+			assert.Equal(t, "{\"status\":\"error\",\"message\":\"invalid encryption key\"}\n", string(body), name)
+		}
+	})
+
+	t.Run("content encoding", func(t *testing.T) {
+		file := &database.File{Id: 1, Owner: "alice", Name: "name", OriginalName: "report.pdf", Encoding: "gzip"}
+
+		tests := []struct {
+			acceptEncoding string
+			keepEncoding   bool
+		}{
+			{acceptEncoding: "", keepEncoding: false},
+			{acceptEncoding: "gzip, deflate, br", keepEncoding: true},
+			{acceptEncoding: "br;q=1.0, gzip;q=0.5", keepEncoding: true},
+			{acceptEncoding: "*", keepEncoding: true},
+			{acceptEncoding: "gzip;q=0", keepEncoding: false},
+			{acceptEncoding: "*, gzip;q=0", keepEncoding: false},
+			{acceptEncoding: "br", keepEncoding: false},
+		}
+
+		for _, tt := range tests {
+			db.On("GetFile", mock.Anything, mock.Anything, mock.Anything).Return(file, nil).Once()
+			storage.On("GetFile", mock.Anything, "name", fileStorage.Meta{Encoding: "gzip", KeepEncoding: tt.keepEncoding}).Return([]byte("test"), nil).Once()
+
+			r, w := CreateRequestAndResponse("1")
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			handler.ServeHTTP(w, r)
+
+			resp := w.Result()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode, tt.acceptEncoding)
+			assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"), tt.acceptEncoding)
+			if tt.keepEncoding {
+				assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"), tt.acceptEncoding)
+				assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"), tt.acceptEncoding)
+			} else {
+				assert.Empty(t, resp.Header.Get("Content-Encoding"), tt.acceptEncoding)
+			}
+		}
+	})
+
 	t.Run("db error", func(t *testing.T) {
-		db.On("GetFile", mock.Anything, mock.Anything).Return(nil, errorResp).Once()
+		db.On("GetFile", mock.Anything, mock.Anything, mock.Anything).Return(nil, errorResp).Once()
 
-		r, w := CreateRequestAndResponse("fileID", "1")
+		r, w := CreateRequestAndResponse("1")
 
 		handler.ServeHTTP(w, r)
 
@@ -54,10 +197,10 @@ func TestGetHandler(t *testing.T) {
 	})
 
 	t.Run("storage error", func(t *testing.T) {
-		db.On("GetFile", mock.Anything, mock.Anything).Return(&database.File{Id: 1}, nil).Once()
-		storage.On("GetFile", mock.Anything, mock.Anything).Return(nil, errorResp).Once()
+		db.On("GetFile", mock.Anything, mock.Anything, mock.Anything).Return(&database.File{Id: 1, Owner: "alice"}, nil).Once()
+		storage.On("GetFile", mock.Anything, mock.Anything, mock.Anything).Return(nil, errorResp).Once()
 
-		r, w := CreateRequestAndResponse("fileID", "1")
+		r, w := CreateRequestAndResponse("1")
 
 		handler.ServeHTTP(w, r)
 
@@ -70,7 +213,7 @@ func TestGetHandler(t *testing.T) {
 	})
 
 	t.Run("invalid file id", func(t *testing.T) {
-		r, w := CreateRequestAndResponse("fileID", "1sdf")
+		r, w := CreateRequestAndResponse("1sdf")
 
 		handler.ServeHTTP(w, r)
 
@@ -82,8 +225,8 @@ func TestGetHandler(t *testing.T) {
 		assert.Equal(t, "{\"status\":\"error\",\"message\":\"invalid file id\"}\n", bodyResp)
 	})
 
-	t.Run("invalid file key", func(t *testing.T) {
-		r, w := CreateRequestAndResponse("fileKey", "1")
+	t.Run("missing file id", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("")
 
 		handler.ServeHTTP(w, r)
 
@@ -96,15 +239,23 @@ func TestGetHandler(t *testing.T) {
 	})
 }
 
-func CreateRequestAndResponse(fileKey string, fileId string) (*http.Request, *httptest.ResponseRecorder) {
+func CreateRequestAndResponse(fileId string) (*http.Request, *httptest.ResponseRecorder) {
 	r := httptest.NewRequest("Get", fmt.Sprintf("/%s", fileId), nil)
+	if fileId != "" {
+		r = r.WithContext(requestctx.WithFileId(r.Context(), fileId))
+	}
 	r = r.WithContext(
-		context.WithValue(r.Context(), fileKey, fileId),
+		requestctx.WithRequestId(r.Context(), "123"),
 	)
 	r = r.WithContext(
-		context.WithValue(r.Context(), "requestId", "123"),
+		requestctx.WithUser(r.Context(), &database.User{Username: "alice"}),
 	)
 	w := httptest.NewRecorder()
 
 	return r, w
 }
+
+func SetCustomerKey(r *http.Request, key []byte) {
+	r.Header.Set(customerkey.HeaderAlgorithm, customerkey.AlgorithmAES256)
+	r.Header.Set(customerkey.HeaderKey, base64.StdEncoding.EncodeToString(key))
+}
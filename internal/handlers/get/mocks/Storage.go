@@ -2,16 +2,22 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	storage "file-service/m/internal/storage"
+)
 
 // Storage is an autogenerated mock type for the Storage type
 type Storage struct {
 	mock.Mock
 }
 
-// GetFile provides a mock function with given fields: name
-func (_m *Storage) GetFile(name string) ([]byte, error) {
-	ret := _m.Called(name)
+// GetFile provides a mock function with given fields: ctx, name, meta
+func (_m *Storage) GetFile(ctx context.Context, name string, meta storage.Meta) ([]byte, error) {
+	ret := _m.Called(ctx, name, meta)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetFile")
@@ -19,19 +25,19 @@ func (_m *Storage) GetFile(name string) ([]byte, error) {
 
 	var r0 []byte
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) ([]byte, error)); ok {
-		return rf(name)
+	if rf, ok := ret.Get(0).(func(context.Context, string, storage.Meta) ([]byte, error)); ok {
+		return rf(ctx, name, meta)
 	}
-	if rf, ok := ret.Get(0).(func(string) []byte); ok {
-		r0 = rf(name)
+	if rf, ok := ret.Get(0).(func(context.Context, string, storage.Meta) []byte); ok {
+		r0 = rf(ctx, name, meta)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]byte)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(name)
+	if rf, ok := ret.Get(1).(func(context.Context, string, storage.Meta) error); ok {
+		r1 = rf(ctx, name, meta)
 	} else {
 		r1 = ret.Error(1)
 	}
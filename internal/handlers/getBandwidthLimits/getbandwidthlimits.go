@@ -0,0 +1,24 @@
+package getbandwidthlimits
+
+import (
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/bandwidth"
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	apiresponse.ApiResponse
+	Limits bandwidth.Limits `json:"limits"`
+}
+
+func New(throttle *bandwidth.Throttle) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			ApiResponse: apiresponse.Success("bandwidth limits"),
+			Limits:      throttle.Limits(),
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package getshare
+
+import (
+	"context"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	apiresponse.ApiResponse
+	Name               string     `json:"name,omitempty"`
+	Size               int        `json:"size,omitempty"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	DownloadsRemaining *int64     `json:"downloads_remaining,omitempty"`
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	RecordShareView(ctx context.Context, id int64) error
+}
+
+// New describes a public share to its visitors. It must run after
+// sharectxmiddleware.ShareCtx.
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.getshare.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		share, file, ok := requestctx.Share(r.Context())
+		if !ok {
+			log.Error("share is not resolved")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("share not found"))
+			return
+		}
+
+		if err := db.RecordShareView(r.Context(), share.Id); err != nil {
+			log.Warn("failed to record share view", slog.Any("error", err))
+		}
+
+		var remaining *int64
+		if share.MaxDownloads != nil {
+			left := *share.MaxDownloads - share.DownloadCount
+			remaining = &left
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			ApiResponse:        apiresponse.Success("share"),
+			Name:               file.OriginalName,
+			Size:               file.Size,
+			ExpiresAt:          share.ExpiresAt,
+			DownloadsRemaining: remaining,
+		})
+	}
+}
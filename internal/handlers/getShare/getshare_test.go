@@ -0,0 +1,60 @@
+package getshare_test
+
+import (
+	"file-service/m/internal/database"
+	getshare "file-service/m/internal/handlers/getShare"
+	"file-service/m/internal/handlers/getShare/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetShareHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := getshare.New(log, db)
+	file := &database.File{Id: 1, OriginalName: "report.pdf", Size: 42}
+
+	t.Run("success", func(t *testing.T) {
+		maxDownloads := int64(3)
+		db.On("RecordShareView", mock.Anything, int64(7)).Return(nil).Once()
+
+		r, w := CreateRequestAndResponse(&database.Share{Id: 7, MaxDownloads: &maxDownloads, DownloadCount: 1}, file)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, `{"status":"success","message":"share","name":"report.pdf","size":42,"downloads_remaining":2}`+"\n", string(body))
+	})
+
+	t.Run("view not recorded", func(t *testing.T) {
+		db.On("RecordShareView", mock.Anything, int64(7)).Return(fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse(&database.Share{Id: 7}, file)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(share *database.Share, file *database.File) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodGet, "/s/token", nil)
+
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = requestctx.WithShare(ctx, share, file)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
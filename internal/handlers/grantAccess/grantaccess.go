@@ -0,0 +1,117 @@
+package grantaccess
+
+import (
+	"context"
+	"errors"
+	"file-service/m/internal/access"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/render"
+)
+
+const maxGranteeLength = 255
+
+type Request struct {
+	GranteeType string `json:"grantee_type"`
+	Grantee     string `json:"grantee"`
+	Permission  string `json:"permission"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	GetFile(ctx context.Context, id int64, isDeleted bool) (*database.File, error)
+	GrantFileAccess(ctx context.Context, grant database.Grant) error
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.grantaccess.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		fileIdStr, ok := requestctx.FileId(r.Context())
+		if !ok || fileIdStr == "" {
+			log.Error("file id is empty")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("file id is empty"))
+			return
+		}
+
+		fileId, err := strconv.ParseInt(fileIdStr, 10, 64)
+		if err != nil {
+			log.Error("failed to parse file id", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid file id"))
+			return
+		}
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid request"))
+			return
+		}
+
+		if (req.GranteeType != database.GranteeUser && req.GranteeType != database.GranteeGroup) ||
+			req.Grantee == "" || len(req.Grantee) > maxGranteeLength ||
+			(req.Permission != database.PermissionRead && req.Permission != database.PermissionWrite) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid grant"))
+			return
+		}
+
+		file, err := db.GetFile(r.Context(), fileId, false)
+		if err != nil && !errors.Is(err, database.ErrorNotFound) {
+			log.Error("failed to get file", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to grant access"))
+			return
+		}
+
+		if err != nil || !access.IsOwner(user, file) {
+			log.Info("file not found or not owned")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
+
+		err = db.GrantFileAccess(r.Context(), database.Grant{
+			FileId:      fileId,
+			GranteeType: req.GranteeType,
+			Grantee:     req.Grantee,
+			Permission:  req.Permission,
+		})
+		if err != nil {
+			log.Error("failed to grant access", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to grant access"))
+			return
+		}
+
+		log.Info("access granted",
+			slog.String("grantee_type", req.GranteeType),
+			slog.String("grantee", req.Grantee),
+			slog.String("permission", req.Permission),
+		)
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{apiresponse.Success("access granted")})
+	}
+}
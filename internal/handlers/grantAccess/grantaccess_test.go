@@ -0,0 +1,105 @@
+package grantaccess_test
+
+import (
+	"file-service/m/internal/database"
+	grantaccess "file-service/m/internal/handlers/grantAccess"
+	"file-service/m/internal/handlers/grantAccess/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGrantAccessHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := grantaccess.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, Owner: "alice"}, nil).Once()
+		db.On("GrantFileAccess", mock.Anything, database.Grant{
+			FileId:      1,
+			GranteeType: database.GranteeUser,
+			Grantee:     "bob",
+			Permission:  database.PermissionRead,
+		}).Return(nil).Once()
+
+		r, w := CreateRequestAndResponse("1", `{"grantee_type":"user","grantee":"bob","permission":"read"}`, "alice")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"access granted\"}\n", string(body))
+	})
+
+	t.Run("not owner", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, Owner: "alice"}, nil).Once()
+
+		r, w := CreateRequestAndResponse("1", `{"grantee_type":"user","grantee":"bob","permission":"write"}`, "bob")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"file not found\"}\n", string(body))
+	})
+
+	t.Run("file not found", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(2), false).Return(nil, fmt.Errorf("postgres.GetFile: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("2", `{"grantee_type":"group","grantee":"team","permission":"read"}`, "alice")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	for name, body := range map[string]string{
+		"invalid json":       `{"grantee_type":`,
+		"invalid type":       `{"grantee_type":"role","grantee":"bob","permission":"read"}`,
+		"missing grantee":    `{"grantee_type":"user","permission":"read"}`,
+		"invalid permission": `{"grantee_type":"user","grantee":"bob","permission":"admin"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, w := CreateRequestAndResponse("1", body, "alice")
+
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, Owner: "alice"}, nil).Once()
+		db.On("GrantFileAccess", mock.Anything, mock.Anything).Return(fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse("1", `{"grantee_type":"user","grantee":"bob","permission":"read"}`, "alice")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(fileId string, body string, username string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/%s/acl", fileId), strings.NewReader(body))
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = requestctx.WithFileId(ctx, fileId)
+	ctx = requestctx.WithUser(ctx, &database.User{Username: username})
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,104 @@
+package listaccess
+
+import (
+	"context"
+	"errors"
+	"file-service/m/internal/access"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/render"
+)
+
+type Grant struct {
+	GranteeType string `json:"grantee_type"`
+	Grantee     string `json:"grantee"`
+	Permission  string `json:"permission"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+	Owner  string  `json:"owner,omitempty"`
+	Grants []Grant `json:"grants"`
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	GetFile(ctx context.Context, id int64, isDeleted bool) (*database.File, error)
+	ListFileGrants(ctx context.Context, fileId int64) ([]database.Grant, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.listaccess.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		fileIdStr, ok := requestctx.FileId(r.Context())
+		if !ok || fileIdStr == "" {
+			log.Error("file id is empty")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("file id is empty"))
+			return
+		}
+
+		fileId, err := strconv.ParseInt(fileIdStr, 10, 64)
+		if err != nil {
+			log.Error("failed to parse file id", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid file id"))
+			return
+		}
+
+		file, err := db.GetFile(r.Context(), fileId, false)
+		if err != nil && !errors.Is(err, database.ErrorNotFound) {
+			log.Error("failed to get file", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to list access"))
+			return
+		}
+
+		if err != nil || !access.IsOwner(user, file) {
+			log.Info("file not found or not owned")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
+
+		fileGrants, err := db.ListFileGrants(r.Context(), fileId)
+		if err != nil {
+			log.Error("failed to list access", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to list access"))
+			return
+		}
+
+		grants := make([]Grant, 0, len(fileGrants))
+		for _, grant := range fileGrants {
+			grants = append(grants, Grant{
+				GranteeType: grant.GranteeType,
+				Grantee:     grant.Grantee,
+				Permission:  grant.Permission,
+			})
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			ApiResponse: apiresponse.Success("access"),
+			Owner:       file.Owner,
+			Grants:      grants,
+		})
+	}
+}
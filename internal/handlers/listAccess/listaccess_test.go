@@ -0,0 +1,72 @@
+package listaccess_test
+
+import (
+	"file-service/m/internal/database"
+	listaccess "file-service/m/internal/handlers/listAccess"
+	"file-service/m/internal/handlers/listAccess/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestListAccessHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := listaccess.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, Owner: "alice"}, nil).Once()
+		db.On("ListFileGrants", mock.Anything, int64(1)).Return([]database.Grant{
+			{FileId: 1, GranteeType: "group", Grantee: "team", Permission: "read"},
+		}, nil).Once()
+
+		r, w := CreateRequestAndResponse("1", "alice")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"access\",\"owner\":\"alice\",\"grants\":[{\"grantee_type\":\"group\",\"grantee\":\"team\",\"permission\":\"read\"}]}\n", string(body))
+	})
+
+	t.Run("not owner", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, Owner: "alice"}, nil).Once()
+
+		r, w := CreateRequestAndResponse("1", "bob")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, Owner: "alice"}, nil).Once()
+		db.On("ListFileGrants", mock.Anything, int64(1)).Return(nil, fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse("1", "alice")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(fileId string, username string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/%s/acl", fileId), nil)
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = requestctx.WithFileId(ctx, fileId)
+	ctx = requestctx.WithUser(ctx, &database.User{Username: username})
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
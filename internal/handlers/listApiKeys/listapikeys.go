@@ -0,0 +1,76 @@
+package listapikeys
+
+import (
+	"context"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+type Key struct {
+	Id         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+	Keys []Key `json:"keys"`
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	ListApiKeys(ctx context.Context, userId int64) ([]database.ApiKey, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.listapikeys.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		apiKeys, err := db.ListApiKeys(r.Context(), user.Id)
+		if err != nil {
+			log.Error("failed to list api keys", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to list api keys"))
+			return
+		}
+
+		keys := make([]Key, 0, len(apiKeys))
+		for _, key := range apiKeys {
+			keys = append(keys, Key{
+				Id:         key.Id,
+				Name:       key.Name,
+				Prefix:     key.Prefix,
+				Scopes:     key.Scopes,
+				ExpiresAt:  key.ExpiresAt,
+				LastUsedAt: key.LastUsedAt,
+				CreatedAt:  key.CreatedAt,
+			})
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			ApiResponse: apiresponse.Success("api keys"),
+			Keys:        keys,
+		})
+	}
+}
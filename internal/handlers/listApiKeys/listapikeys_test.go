@@ -0,0 +1,81 @@
+package listapikeys_test
+
+import (
+	"file-service/m/internal/database"
+	listapikeys "file-service/m/internal/handlers/listApiKeys"
+	"file-service/m/internal/handlers/listApiKeys/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestListApiKeysHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := listapikeys.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		createdAt := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+		db.On("ListApiKeys", mock.Anything, int64(7)).Return([]database.ApiKey{{
+			Id:        1,
+			UserId:    7,
+			Name:      "ci",
+			Prefix:    "0123456789ab",
+			Hash:      "secret",
+			Scopes:    []string{"file:read"},
+			CreatedAt: createdAt,
+		}}, nil).Once()
+
+		r, w := CreateRequestAndResponse()
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"api keys\",\"keys\":[{\"id\":1,\"name\":\"ci\",\"prefix\":\"0123456789ab\",\"scopes\":[\"file:read\"],\"expires_at\":null,\"last_used_at\":null,\"created_at\":\"2024-07-01T12:00:00Z\"}]}\n", string(body))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		db.On("ListApiKeys", mock.Anything, int64(7)).Return([]database.ApiKey{}, nil).Once()
+
+		r, w := CreateRequestAndResponse()
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"api keys\",\"keys\":[]}\n", string(body))
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("ListApiKeys", mock.Anything, int64(7)).Return(nil, fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse()
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse() (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodGet, "/keys", nil)
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Id: 7, Username: "alice"})
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,237 @@
+package listauditevents
+
+import (
+	"context"
+	"encoding/json"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+
+	// FormatJSONLines exports all matching events, oldest first, one JSON
+	// object per line.
+	FormatJSONLines = "jsonl"
+
+	// flushInterval is the number of exported events after which they are
+	// sent on instead of piling up in the buffer.
+	flushInterval = 500
+)
+
+type Event struct {
+	Id        int64     `json:"id"`
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	FileId    string    `json:"file_id,omitempty"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	RequestId string    `json:"request_id"`
+	Result    string    `json:"result"`
+	Status    int       `json:"status"`
+	Bytes     int64     `json:"bytes"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+	Events []Event `json:"events"`
+	// NextBefore is the before parameter of the next page, set when there
+	// may be more events.
+	NextBefore int64 `json:"next_before,omitempty"`
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	ListAuditEvents(ctx context.Context, filter database.AuditFilter, fn func(event *database.AuditEvent) error) error
+}
+
+// New lists the audit log, newest first, filtered by the actor, action,
+// result, file_id, since and until query parameters. Pages hold limit
+// events and continue before the id given as before. With format=jsonl all
+// matching events are exported instead.
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.listauditevents.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		query := r.URL.Query()
+
+		filter, err := parseFilter(query)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error(err.Error()))
+			return
+		}
+
+		if query.Get("format") == FormatJSONLines {
+			export(log, db, w, r, filter)
+			return
+		}
+
+		events := []Event{}
+		err = db.ListAuditEvents(r.Context(), filter, func(event *database.AuditEvent) error {
+			events = append(events, newEvent(event))
+			return nil
+		})
+		if err != nil {
+			log.Error("failed to list audit events", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to list audit events"))
+			return
+		}
+
+		response := Response{
+			ApiResponse: apiresponse.Success("audit events"),
+			Events:      events,
+		}
+		if len(events) == filter.Limit {
+			response.NextBefore = events[len(events)-1].Id
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, response)
+	}
+}
+
+// export streams the events as they are read. Errors after the first event
+// can only end the response early.
+func export(log *slog.Logger, db Db, w http.ResponseWriter, r *http.Request, filter database.AuditFilter) {
+	filter.Limit = 0
+	filter.BeforeId = 0
+	filter.Chronological = true
+
+	rc := http.NewResponseController(w)
+
+	// the write timeout of the server would cut off large exports
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Warn("failed to clear write deadline", slog.Any("error", err))
+	}
+
+	encoder := json.NewEncoder(w)
+	written := false
+	count := 0
+
+	err := db.ListAuditEvents(r.Context(), filter, func(event *database.AuditEvent) error {
+		if !written {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Content-Disposition", `attachment; filename="audit.jsonl"`)
+			w.WriteHeader(http.StatusOK)
+			written = true
+		}
+
+		if err := encoder.Encode(newEvent(event)); err != nil {
+			return err
+		}
+
+		count++
+		if count%flushInterval == 0 {
+			return rc.Flush()
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Error("failed to export audit events", slog.Any("error", err))
+		if !written {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to export audit events"))
+		}
+		return
+	}
+
+	if !written {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if err := rc.Flush(); err != nil {
+		log.Warn("failed to flush audit events", slog.Any("error", err))
+	}
+}
+
+func parseFilter(query url.Values) (database.AuditFilter, error) {
+	filter := database.AuditFilter{
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+		Result: query.Get("result"),
+		Limit:  defaultLimit,
+	}
+
+	if fileId := query.Get("file_id"); fileId != "" {
+		if publicId, err := uuid.Parse(fileId); err == nil {
+			filter.FilePublicId = publicId.String()
+		} else if id, err := strconv.ParseInt(fileId, 10, 64); err == nil && id > 0 {
+			filter.FileId = id
+		} else {
+			return filter, errorInvalid("file_id")
+		}
+	}
+
+	for _, param := range []struct {
+		name string
+		time *time.Time
+	}{
+		{"since", &filter.Since},
+		{"until", &filter.Until},
+	} {
+		if value := query.Get(param.name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, errorInvalid(param.name)
+			}
+			*param.time = parsed
+		}
+	}
+
+	if before := query.Get("before"); before != "" {
+		id, err := strconv.ParseInt(before, 10, 64)
+		if err != nil || id <= 0 {
+			return filter, errorInvalid("before")
+		}
+		filter.BeforeId = id
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 || n > maxLimit {
+			return filter, errorInvalid("limit")
+		}
+		filter.Limit = n
+	}
+
+	return filter, nil
+}
+
+type errorInvalid string
+
+func (e errorInvalid) Error() string {
+	return "invalid " + string(e)
+}
+
+func newEvent(event *database.AuditEvent) Event {
+	return Event{
+		Id:        event.Id,
+		Time:      event.Time,
+		Actor:     event.Actor,
+		Action:    event.Action,
+		FileId:    event.FilePublicId,
+		IP:        event.IP,
+		UserAgent: event.UserAgent,
+		RequestId: event.RequestId,
+		Result:    event.Result,
+		Status:    event.Status,
+		Bytes:     event.Bytes,
+	}
+}
@@ -0,0 +1,231 @@
+package listauditevents_test
+
+import (
+	"errors"
+	"file-service/m/internal/database"
+	listauditevents "file-service/m/internal/handlers/listAuditEvents"
+	"file-service/m/internal/handlers/listAuditEvents/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const publicId = "0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f"
+
+var events = []database.AuditEvent{
+	{
+		Id:           2,
+		Time:         time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC),
+		Actor:        "alice",
+		Action:       "download",
+		FileId:       7,
+		FilePublicId: publicId,
+		IP:           "192.0.2.1",
+		UserAgent:    "curl",
+		RequestId:    "123",
+		Result:       "success",
+		Status:       200,
+		Bytes:        4,
+	},
+	{
+		Id:        1,
+		Time:      time.Date(2024, 7, 1, 11, 0, 0, 0, time.UTC),
+		Actor:     "mallory",
+		Action:    "delete",
+		FileId:    7,
+		IP:        "192.0.2.2",
+		UserAgent: "curl",
+		RequestId: "122",
+		Result:    "denied",
+		Status:    403,
+	},
+}
+
+// returnEvents makes the mock call fn with the given events.
+func returnEvents(events ...database.AuditEvent) func(args mock.Arguments) {
+	return func(args mock.Arguments) {
+		fn := args.Get(2).(func(*database.AuditEvent) error)
+		for _, event := range events {
+			if err := fn(&event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestListAuditEventsHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := listauditevents.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("ListAuditEvents", mock.Anything, database.AuditFilter{Limit: 100}, mock.Anything).
+			Run(returnEvents(events...)).Return(nil).Once()
+
+		r, w := CreateRequestAndResponse("/admin/audit")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"audit events\",\"events\":["+
+			"{\"id\":2,\"time\":\"2024-07-01T12:00:00Z\",\"actor\":\"alice\",\"action\":\"download\",\"file_id\":\""+publicId+"\",\"ip\":\"192.0.2.1\",\"user_agent\":\"curl\",\"request_id\":\"123\",\"result\":\"success\",\"status\":200,\"bytes\":4},"+
+			"{\"id\":1,\"time\":\"2024-07-01T11:00:00Z\",\"actor\":\"mallory\",\"action\":\"delete\",\"ip\":\"192.0.2.2\",\"user_agent\":\"curl\",\"request_id\":\"122\",\"result\":\"denied\",\"status\":403,\"bytes\":0}"+
+			"]}\n", string(body))
+	})
+
+	t.Run("filters and next page", func(t *testing.T) {
+		db.On("ListAuditEvents", mock.Anything, database.AuditFilter{
+			Actor:        "alice",
+			Action:       "download",
+			Result:       "success",
+			FilePublicId: publicId,
+			Since:        time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+			Until:        time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC),
+			BeforeId:     10,
+			Limit:        1,
+		}, mock.Anything).Run(returnEvents(events[0])).Return(nil).Once()
+
+		r, w := CreateRequestAndResponse("/admin/audit?actor=alice&action=download&result=success&file_id=" + publicId +
+			"&since=2024-07-01T00:00:00Z&until=2024-07-02T00:00:00Z&before=10&limit=1")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, string(body), "\"next_before\":2}")
+	})
+
+	t.Run("legacy file id", func(t *testing.T) {
+		db.On("ListAuditEvents", mock.Anything, database.AuditFilter{FileId: 7, Limit: 100}, mock.Anything).
+			Return(nil).Once()
+
+		r, w := CreateRequestAndResponse("/admin/audit?file_id=7")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"audit events\",\"events\":[]}\n", string(body))
+	})
+
+	t.Run("export", func(t *testing.T) {
+		db.On("ListAuditEvents", mock.Anything, database.AuditFilter{Actor: "alice", Chronological: true}, mock.Anything).
+			Run(returnEvents(events[1], events[0])).Return(nil).Once()
+
+		r, w := CreateRequestAndResponse("/admin/audit?format=jsonl&actor=alice&limit=1&before=10")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+		assert.Equal(t,
+			"{\"id\":1,\"time\":\"2024-07-01T11:00:00Z\",\"actor\":\"mallory\",\"action\":\"delete\",\"ip\":\"192.0.2.2\",\"user_agent\":\"curl\",\"request_id\":\"122\",\"result\":\"denied\",\"status\":403,\"bytes\":0}\n"+
+				"{\"id\":2,\"time\":\"2024-07-01T12:00:00Z\",\"actor\":\"alice\",\"action\":\"download\",\"file_id\":\""+publicId+"\",\"ip\":\"192.0.2.1\",\"user_agent\":\"curl\",\"request_id\":\"123\",\"result\":\"success\",\"status\":200,\"bytes\":4}\n",
+			string(body))
+	})
+
+	t.Run("large export is flushed as it goes", func(t *testing.T) {
+		flushes := 0
+		var written int
+		db.On("ListAuditEvents", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			fn := args.Get(2).(func(*database.AuditEvent) error)
+			for range 1000 {
+				fn(&events[0])
+			}
+		}).Return(nil).Once()
+
+		r, w := CreateRequestAndResponse("/admin/audit?format=jsonl")
+
+		handler.ServeHTTP(flushRecorder{ResponseRecorder: w, flushed: func() {
+			flushes++
+			written = w.Body.Len()
+		}}, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Equal(t, 1000, strings.Count(w.Body.String(), "\n"))
+		// after 500 and 1000 events, and once more at the end
+		assert.Equal(t, 3, flushes)
+		assert.Equal(t, w.Body.Len(), written)
+	})
+
+	t.Run("invalid filters", func(t *testing.T) {
+		for query, message := range map[string]string{
+			"file_id=abc":     "invalid file_id",
+			"since=yesterday": "invalid since",
+			"until=1":         "invalid until",
+			"before=-1":       "invalid before",
+			"limit=0":         "invalid limit",
+			"limit=1001":      "invalid limit",
+		} {
+			r, w := CreateRequestAndResponse("/admin/audit?" + query)
+
+			handler.ServeHTTP(w, r)
+
+			resp := w.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+			assert.Equal(t, "{\"status\":\"error\",\"message\":\""+message+"\"}\n", string(body), query)
+		}
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("ListAuditEvents", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("error")).Once()
+
+		r, w := CreateRequestAndResponse("/admin/audit")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"failed to list audit events\"}\n", string(body))
+	})
+
+	t.Run("export error before the first event", func(t *testing.T) {
+		db.On("ListAuditEvents", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("error")).Once()
+
+		r, w := CreateRequestAndResponse("/admin/audit?format=jsonl")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(target string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r = r.WithContext(requestctx.WithRequestId(r.Context(), "123"))
+	w := httptest.NewRecorder()
+
+	return r, w
+}
+
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed func()
+}
+
+func (w flushRecorder) Flush() {
+	w.flushed()
+	w.ResponseRecorder.Flush()
+}
@@ -0,0 +1,115 @@
+package listshares
+
+import (
+	"context"
+	"errors"
+	"file-service/m/internal/access"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+type Share struct {
+	Id               int64      `json:"id"`
+	CreatedBy        string     `json:"created_by"`
+	PasswordRequired bool       `json:"password_required"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	MaxDownloads     *int64     `json:"max_downloads,omitempty"`
+	DownloadCount    int64      `json:"download_count"`
+	ViewCount        int64      `json:"view_count"`
+	LastAccessedAt   *time.Time `json:"last_accessed_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+	Shares []Share `json:"shares"`
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	GetFile(ctx context.Context, id int64, isDeleted bool) (*database.File, error)
+	ListShares(ctx context.Context, fileId int64) ([]database.Share, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.listshares.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		fileIdStr, ok := requestctx.FileId(r.Context())
+		if !ok || fileIdStr == "" {
+			log.Error("file id is empty")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("file id is empty"))
+			return
+		}
+
+		fileId, err := strconv.ParseInt(fileIdStr, 10, 64)
+		if err != nil {
+			log.Error("failed to parse file id", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid file id"))
+			return
+		}
+
+		file, err := db.GetFile(r.Context(), fileId, false)
+		if err != nil && !errors.Is(err, database.ErrorNotFound) {
+			log.Error("failed to get file", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to list shares"))
+			return
+		}
+
+		if err != nil || !access.IsOwner(user, file) {
+			log.Info("file not found or not owned")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
+
+		fileShares, err := db.ListShares(r.Context(), fileId)
+		if err != nil {
+			log.Error("failed to list shares", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to list shares"))
+			return
+		}
+
+		shares := make([]Share, 0, len(fileShares))
+		for _, share := range fileShares {
+			shares = append(shares, Share{
+				Id:               share.Id,
+				CreatedBy:        share.CreatedBy,
+				PasswordRequired: share.PasswordHash != "",
+				ExpiresAt:        share.ExpiresAt,
+				MaxDownloads:     share.MaxDownloads,
+				DownloadCount:    share.DownloadCount,
+				ViewCount:        share.ViewCount,
+				LastAccessedAt:   share.LastAccessedAt,
+				CreatedAt:        share.CreatedAt,
+			})
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			ApiResponse: apiresponse.Success("shares"),
+			Shares:      shares,
+		})
+	}
+}
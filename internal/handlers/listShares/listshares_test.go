@@ -0,0 +1,79 @@
+package listshares_test
+
+import (
+	"file-service/m/internal/database"
+	listshares "file-service/m/internal/handlers/listShares"
+	"file-service/m/internal/handlers/listShares/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestListSharesHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := listshares.New(log, db)
+	file := &database.File{Id: 1, Owner: "alice"}
+
+	t.Run("success", func(t *testing.T) {
+		maxDownloads := int64(3)
+		createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+		db.On("ListShares", mock.Anything, int64(1)).Return([]database.Share{
+			{Id: 7, FileId: 1, TokenHash: "secret", CreatedBy: "alice", PasswordHash: "hash", MaxDownloads: &maxDownloads, DownloadCount: 1, ViewCount: 2, CreatedAt: createdAt},
+		}, nil).Once()
+
+		r, w := CreateRequestAndResponse("alice")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, `{"status":"success","message":"shares","shares":[{"id":7,"created_by":"alice","password_required":true,"max_downloads":3,"download_count":1,"view_count":2,"created_at":"2024-01-02T03:04:05Z"}]}`+"\n", string(body))
+		assert.NotContains(t, string(body), "secret")
+	})
+
+	t.Run("not owner", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+
+		r, w := CreateRequestAndResponse("bob")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+		db.On("ListShares", mock.Anything, int64(1)).Return(nil, fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse("alice")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(username string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodGet, "/file/1/shares", nil)
+
+	ctx := requestctx.WithFileId(r.Context(), "1")
+	ctx = requestctx.WithRequestId(ctx, "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Username: username, Role: database.RoleWriter})
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
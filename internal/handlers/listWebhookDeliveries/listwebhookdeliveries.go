@@ -0,0 +1,112 @@
+package listwebhookdeliveries
+
+import (
+	"context"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+type Delivery struct {
+	Id             int64     `json:"id"`
+	EventId        int64     `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	LastStatusCode int       `json:"last_status_code,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	ListWebhookDeliveries(ctx context.Context, webhookId int64, status string, limit int) ([]database.WebhookDelivery, error)
+}
+
+// New lists the latest deliveries of a webhook, up to the limit query
+// parameter and only those in the given status when it is set.
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.listwebhookdeliveries.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		webhookId, err := strconv.ParseInt(chi.URLParam(r, "webhookID"), 10, 64)
+		if err != nil {
+			log.Error("failed to parse webhook id", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid webhook id"))
+			return
+		}
+
+		query := r.URL.Query()
+
+		status := query.Get("status")
+		switch status {
+		case "", database.DeliveryPending, database.DeliverySucceeded, database.DeliveryDead:
+		default:
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid status"))
+			return
+		}
+
+		limit := defaultLimit
+		if raw := query.Get("limit"); raw != "" {
+			limit, err = strconv.Atoi(raw)
+			if err != nil || limit < 1 || limit > maxLimit {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, apiresponse.Error("invalid limit"))
+				return
+			}
+		}
+
+		deliveries, err := db.ListWebhookDeliveries(r.Context(), webhookId, status, limit)
+		if err != nil {
+			log.Error("failed to list webhook deliveries", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to list webhook deliveries"))
+			return
+		}
+
+		resp := make([]Delivery, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			resp = append(resp, Delivery{
+				Id:             delivery.Id,
+				EventId:        delivery.EventId,
+				EventType:      delivery.EventType,
+				Status:         delivery.Status,
+				Attempts:       delivery.Attempts,
+				NextAttemptAt:  delivery.NextAttemptAt,
+				LastStatusCode: delivery.LastStatusCode,
+				LastError:      delivery.LastError,
+				CreatedAt:      delivery.CreatedAt,
+				UpdatedAt:      delivery.UpdatedAt,
+			})
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			ApiResponse: apiresponse.Success("webhook deliveries"),
+			Deliveries:  resp,
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package listwebhookdeliveries_test
+
+import (
+	"context"
+	"encoding/json"
+	"file-service/m/internal/database"
+	listwebhookdeliveries "file-service/m/internal/handlers/listWebhookDeliveries"
+	"file-service/m/internal/handlers/listWebhookDeliveries/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListWebhookDeliveriesHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := listwebhookdeliveries.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("ListWebhookDeliveries", mock.Anything, int64(3), database.DeliveryDead, 10).
+			Return([]database.WebhookDelivery{{
+				Id:             5,
+				WebhookId:      3,
+				EventId:        9,
+				EventType:      database.FileEventDeleted,
+				Status:         database.DeliveryDead,
+				Attempts:       10,
+				LastStatusCode: http.StatusBadGateway,
+				LastError:      "unexpected status 502",
+			}}, nil).Once()
+
+		r, w := CreateRequestAndResponse("3", "?status=dead&limit=10")
+
+		handler.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+		var resp listwebhookdeliveries.Response
+		require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&resp))
+
+		require.Len(t, resp.Deliveries, 1)
+		assert.Equal(t, int64(9), resp.Deliveries[0].EventId)
+		assert.Equal(t, database.FileEventDeleted, resp.Deliveries[0].EventType)
+		assert.Equal(t, "unexpected status 502", resp.Deliveries[0].LastError)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		db.On("ListWebhookDeliveries", mock.Anything, int64(3), "", 100).
+			Return([]database.WebhookDelivery{}, nil).Once()
+
+		r, w := CreateRequestAndResponse("3", "")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+
+	tests := []struct {
+		name      string
+		webhookId string
+		query     string
+	}{
+		{"invalid id", "abc", ""},
+		{"invalid status", "3", "?status=failed"},
+		{"invalid limit", "3", "?limit=0"},
+		{"limit too large", "3", "?limit=1001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w := CreateRequestAndResponse(tt.webhookId, tt.query)
+
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("ListWebhookDeliveries", mock.Anything, int64(3), "", 100).Return(nil, fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse("3", "")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(webhookId string, query string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodGet, "/admin/webhooks/"+webhookId+"/deliveries"+query, nil)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("webhookID", webhookId)
+
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,64 @@
+package listwebhooks
+
+import (
+	"context"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+type Webhook struct {
+	Id         int64     `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	ListWebhooks(ctx context.Context) ([]database.Webhook, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.listwebhooks.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		webhooks, err := db.ListWebhooks(r.Context())
+		if err != nil {
+			log.Error("failed to list webhooks", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to list webhooks"))
+			return
+		}
+
+		resp := make([]Webhook, 0, len(webhooks))
+		for _, webhook := range webhooks {
+			resp = append(resp, Webhook{
+				Id:         webhook.Id,
+				URL:        webhook.URL,
+				EventTypes: webhook.EventTypes,
+				CreatedBy:  webhook.CreatedBy,
+				CreatedAt:  webhook.CreatedAt,
+			})
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			ApiResponse: apiresponse.Success("webhooks"),
+			Webhooks:    resp,
+		})
+	}
+}
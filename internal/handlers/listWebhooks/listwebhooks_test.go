@@ -0,0 +1,65 @@
+package listwebhooks_test
+
+import (
+	"file-service/m/internal/database"
+	listwebhooks "file-service/m/internal/handlers/listWebhooks"
+	"file-service/m/internal/handlers/listWebhooks/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestListWebhooksHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := listwebhooks.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("ListWebhooks", mock.Anything).Return([]database.Webhook{{
+			Id:         3,
+			URL:        "https://example.com/hook",
+			Secret:     "secret",
+			EventTypes: []string{database.FileEventCreated},
+			CreatedBy:  "alice",
+			CreatedAt:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		}}, nil).Once()
+
+		r, w := CreateRequestAndResponse()
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"webhooks\",\"webhooks\":[{\"id\":3,"+
+			"\"url\":\"https://example.com/hook\",\"event_types\":[\"file.created\"],\"created_by\":\"alice\","+
+			"\"created_at\":\"2024-01-02T03:04:05Z\"}]}\n", string(body))
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("ListWebhooks", mock.Anything).Return(nil, fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse()
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse() (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil)
+	r = r.WithContext(requestctx.WithRequestId(r.Context(), "123"))
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,17 @@
+package liveness
+
+import (
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+// New answers as long as the process serves requests. It checks no
+// dependencies, an outage of those must not get the service restarted.
+func New() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, apiresponse.Success("alive"))
+	}
+}
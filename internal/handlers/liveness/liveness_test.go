@@ -0,0 +1,23 @@
+package liveness_test
+
+import (
+	"file-service/m/internal/handlers/liveness"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLivenessHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	liveness.New().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	resp := w.Result()
+	body, _ := io.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "{\"status\":\"success\",\"message\":\"alive\"}\n", string(body))
+}
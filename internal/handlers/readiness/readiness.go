@@ -0,0 +1,51 @@
+package readiness
+
+import (
+	"context"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	apiresponse.ApiResponse
+	Checks map[string]string `json:"checks"`
+}
+
+type Checker interface {
+	Check(ctx context.Context) (bool, map[string]error)
+}
+
+func New(logger *slog.Logger, checker Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.readiness.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		ready, results := checker.Check(r.Context())
+
+		// the endpoint is public, the errors only go to the log
+		checks := make(map[string]string, len(results))
+		failures := map[string]string{}
+		for name, err := range results {
+			checks[name] = "ok"
+			if err != nil {
+				checks[name] = "failed"
+				failures[name] = err.Error()
+			}
+		}
+
+		if !ready {
+			log.Warn("not ready", slog.Any("failures", failures))
+			render.Status(r, http.StatusServiceUnavailable)
+			render.JSON(w, r, Response{ApiResponse: apiresponse.Error("not ready"), Checks: checks})
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{ApiResponse: apiresponse.Success("ready"), Checks: checks})
+	}
+}
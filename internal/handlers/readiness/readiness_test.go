@@ -0,0 +1,79 @@
+package readiness_test
+
+import (
+	"context"
+	"errors"
+	"file-service/m/internal/handlers/readiness"
+	"file-service/m/internal/health"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	dbErr := error(nil)
+
+	checker := health.New(time.Second)
+	checker.Add("database", func(ctx context.Context) error { return dbErr })
+	checker.Add("storage", func(ctx context.Context) error { return nil })
+
+	handler := readiness.New(log, checker)
+
+	t.Run("ready", func(t *testing.T) {
+		r, w := CreateRequestAndResponse()
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"ready\",\"checks\":{\"database\":\"ok\",\"storage\":\"ok\"}}\n", string(body))
+	})
+
+	t.Run("failing check", func(t *testing.T) {
+		dbErr = errors.New("connection refused")
+		defer func() { dbErr = nil }()
+
+		r, w := CreateRequestAndResponse()
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"not ready\",\"checks\":{\"database\":\"failed\",\"storage\":\"ok\"}}\n", string(body))
+	})
+
+	t.Run("shutting down", func(t *testing.T) {
+		checker.SetShuttingDown()
+
+		r, w := CreateRequestAndResponse()
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"not ready\",\"checks\":{\"shutdown\":\"failed\"}}\n", string(body))
+	})
+}
+
+func CreateRequestAndResponse() (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	r = r.WithContext(
+		requestctx.WithRequestId(r.Context(), "123"),
+	)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,51 @@
+package removegroupmember
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	apiresponse.ApiResponse
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	RemoveGroupMember(ctx context.Context, group string, username string) (int64, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.removegroupmember.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		group := chi.URLParam(r, "group")
+		username := chi.URLParam(r, "username")
+
+		_, err := db.RemoveGroupMember(r.Context(), group, username)
+		if errors.Is(err, database.ErrorNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("group member not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to remove group member", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to remove group member"))
+			return
+		}
+
+		log.Info("group member removed", slog.String("group", group), slog.String("username", username))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{apiresponse.Success("group member removed")})
+	}
+}
@@ -0,0 +1,75 @@
+package removegroupmember_test
+
+import (
+	"context"
+	"file-service/m/internal/database"
+	removegroupmember "file-service/m/internal/handlers/removeGroupMember"
+	"file-service/m/internal/handlers/removeGroupMember/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRemoveGroupMemberHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := removegroupmember.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("RemoveGroupMember", mock.Anything, "team", "bob").Return(int64(1), nil).Once()
+
+		r, w := CreateRequestAndResponse("team", "bob")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"group member removed\"}\n", string(body))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db.On("RemoveGroupMember", mock.Anything, "team", "nobody").
+			Return(int64(0), fmt.Errorf("postgres.RemoveGroupMember: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("team", "nobody")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("RemoveGroupMember", mock.Anything, "team", "bob").Return(int64(0), fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse("team", "bob")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(group string, username string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodDelete, "/admin/groups/group/members/user", nil)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("group", group)
+	rctx.URLParams.Add("username", username)
+
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,76 @@
+package resetpassword
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/auth"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	Password string `json:"password"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	SetUserPassword(ctx context.Context, username string, passwordHash string) (int64, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.resetpassword.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		username := chi.URLParam(r, "username")
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid request"))
+			return
+		}
+
+		hash, err := auth.HashPassword(req.Password)
+		if errors.Is(err, auth.ErrorInvalidPassword) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid password"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to hash password", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to reset password"))
+			return
+		}
+
+		_, err = db.SetUserPassword(r.Context(), username, hash)
+		if errors.Is(err, database.ErrorNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("user not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to reset password", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to reset password"))
+			return
+		}
+
+		log.Info("password reset", slog.String("username", username))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{apiresponse.Success("password reset")})
+	}
+}
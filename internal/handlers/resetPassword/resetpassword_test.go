@@ -0,0 +1,90 @@
+package resetpassword_test
+
+import (
+	"context"
+	"file-service/m/internal/auth"
+	"file-service/m/internal/database"
+	resetpassword "file-service/m/internal/handlers/resetPassword"
+	"file-service/m/internal/handlers/resetPassword/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestResetPasswordHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := resetpassword.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("SetUserPassword", mock.Anything, "bob", mock.MatchedBy(func(hash string) bool {
+			return auth.CheckPassword(hash, "new-password")
+		})).Return(int64(1), nil).Once()
+
+		r, w := CreateRequestAndResponse("bob", `{"password":"new-password"}`)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"password reset\"}\n", string(body))
+	})
+
+	t.Run("short password", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("bob", `{"password":"short"}`)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"invalid password\"}\n", string(body))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db.On("SetUserPassword", mock.Anything, "nobody", mock.Anything).
+			Return(int64(0), fmt.Errorf("postgres.SetUserPassword: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("nobody", `{"password":"new-password"}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("SetUserPassword", mock.Anything, "bob", mock.Anything).Return(int64(0), fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse("bob", `{"password":"new-password"}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(username string, body string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodPut, "/admin/users/"+username+"/password", strings.NewReader(body))
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("username", username)
+
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,98 @@
+package restore_test
+
+import (
+	"file-service/m/internal/database"
+	"file-service/m/internal/handlers/restore"
+	"file-service/m/internal/handlers/restore/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRestoreHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := restore.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), true).Return(&database.File{Id: 1, Owner: "alice", IsDeleted: true}, nil).Once()
+		db.On("RestoreFile", mock.Anything, int64(1)).Return(int64(1), nil).Once()
+
+		r, w := CreateRequestAndResponse("1")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"file restored\"}\n", string(body))
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), true).Return(&database.File{Id: 1, Owner: "alice", IsDeleted: true}, nil).Once()
+		db.On("RestoreFile", mock.Anything, int64(1)).Return(int64(0), fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse("1")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"failed to restore file\"}\n", string(body))
+	})
+
+	t.Run("access", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), true).Return(&database.File{Id: 1, Owner: "bob", IsDeleted: true}, nil).Once()
+		db.On("GetFilePermissions", mock.Anything, int64(1), "alice", []string(nil)).Return([]string{database.PermissionRead}, nil).Once()
+
+		r, w := CreateRequestAndResponse("1")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("not in trash", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(2), true).Return(nil, fmt.Errorf("postgres.GetFile: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("2")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"file not found\"}\n", string(body))
+	})
+
+	t.Run("invalid fileId", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("1asdf")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(fileId string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/%s/restore", fileId), nil)
+
+	ctx := requestctx.WithFileId(r.Context(), fileId)
+	ctx = requestctx.WithRequestId(ctx, "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Username: "alice", Role: database.RoleWriter})
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,69 @@
+package retrywebhookdelivery
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	apiresponse.ApiResponse
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	RetryWebhookDelivery(ctx context.Context, webhookId int64, id int64) (int64, error)
+}
+
+// New schedules a dead delivery for another round of attempts.
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.retrywebhookdelivery.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		webhookId, err := strconv.ParseInt(chi.URLParam(r, "webhookID"), 10, 64)
+		if err != nil {
+			log.Error("failed to parse webhook id", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid webhook id"))
+			return
+		}
+
+		deliveryId, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+		if err != nil {
+			log.Error("failed to parse delivery id", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid delivery id"))
+			return
+		}
+
+		_, err = db.RetryWebhookDelivery(r.Context(), webhookId, deliveryId)
+		if errors.Is(err, database.ErrorNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("dead delivery not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to retry webhook delivery", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to retry webhook delivery"))
+			return
+		}
+
+		log.Info("webhook delivery retried",
+			slog.Int64("webhook_id", webhookId),
+			slog.Int64("delivery_id", deliveryId),
+		)
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{apiresponse.Success("webhook delivery retried")})
+	}
+}
@@ -0,0 +1,91 @@
+package retrywebhookdelivery_test
+
+import (
+	"context"
+	"file-service/m/internal/database"
+	retrywebhookdelivery "file-service/m/internal/handlers/retryWebhookDelivery"
+	"file-service/m/internal/handlers/retryWebhookDelivery/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRetryWebhookDeliveryHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := retrywebhookdelivery.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("RetryWebhookDelivery", mock.Anything, int64(3), int64(5)).Return(int64(1), nil).Once()
+
+		r, w := CreateRequestAndResponse("3", "5")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"webhook delivery retried\"}\n", string(body))
+	})
+
+	t.Run("invalid webhook id", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("abc", "5")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+
+	t.Run("invalid delivery id", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("3", "abc")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+
+	t.Run("not dead", func(t *testing.T) {
+		db.On("RetryWebhookDelivery", mock.Anything, int64(3), int64(6)).
+			Return(int64(0), fmt.Errorf("postgres.RetryWebhookDelivery: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("3", "6")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("RetryWebhookDelivery", mock.Anything, int64(3), int64(5)).Return(int64(0), fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse("3", "5")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(webhookId string, deliveryId string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodPost, "/admin/webhooks/"+webhookId+"/deliveries/"+deliveryId+"/retry", nil)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("webhookID", webhookId)
+	rctx.URLParams.Add("deliveryID", deliveryId)
+
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,100 @@
+package revokeaccess
+
+import (
+	"context"
+	"errors"
+	"file-service/m/internal/access"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	apiresponse.ApiResponse
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	GetFile(ctx context.Context, id int64, isDeleted bool) (*database.File, error)
+	RevokeFileAccess(ctx context.Context, fileId int64, granteeType string, grantee string) (int64, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.revokeaccess.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		fileIdStr, ok := requestctx.FileId(r.Context())
+		if !ok || fileIdStr == "" {
+			log.Error("file id is empty")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("file id is empty"))
+			return
+		}
+
+		fileId, err := strconv.ParseInt(fileIdStr, 10, 64)
+		if err != nil {
+			log.Error("failed to parse file id", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid file id"))
+			return
+		}
+
+		granteeType := r.URL.Query().Get("grantee_type")
+		grantee := r.URL.Query().Get("grantee")
+		if (granteeType != database.GranteeUser && granteeType != database.GranteeGroup) || grantee == "" {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid grant"))
+			return
+		}
+
+		file, err := db.GetFile(r.Context(), fileId, false)
+		if err != nil && !errors.Is(err, database.ErrorNotFound) {
+			log.Error("failed to get file", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to revoke access"))
+			return
+		}
+
+		if err != nil || !access.IsOwner(user, file) {
+			log.Info("file not found or not owned")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
+
+		_, err = db.RevokeFileAccess(r.Context(), fileId, granteeType, grantee)
+		if errors.Is(err, database.ErrorNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("grant not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to revoke access", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to revoke access"))
+			return
+		}
+
+		log.Info("access revoked",
+			slog.String("grantee_type", granteeType),
+			slog.String("grantee", grantee),
+		)
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{apiresponse.Success("access revoked")})
+	}
+}
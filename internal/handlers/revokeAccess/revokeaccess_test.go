@@ -0,0 +1,95 @@
+package revokeaccess_test
+
+import (
+	"file-service/m/internal/database"
+	revokeaccess "file-service/m/internal/handlers/revokeAccess"
+	"file-service/m/internal/handlers/revokeAccess/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRevokeAccessHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := revokeaccess.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, Owner: "alice"}, nil).Once()
+		db.On("RevokeFileAccess", mock.Anything, int64(1), database.GranteeGroup, "team").Return(int64(1), nil).Once()
+
+		r, w := CreateRequestAndResponse("1", "grantee_type=group&grantee=team", "alice")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"access revoked\"}\n", string(body))
+	})
+
+	t.Run("not owner", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, Owner: "alice"}, nil).Once()
+
+		r, w := CreateRequestAndResponse("1", "grantee_type=user&grantee=bob", "bob")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, Owner: "alice"}, nil).Once()
+		db.On("RevokeFileAccess", mock.Anything, int64(1), database.GranteeUser, "bob").Return(int64(1), nil).Once()
+
+		r, w := CreateRequestAndResponse("1", "grantee_type=user&grantee=bob", "root")
+		r = r.WithContext(requestctx.WithUser(r.Context(), &database.User{Username: "root", Role: database.RoleAdmin}))
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+
+	t.Run("grant not found", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, Owner: "alice"}, nil).Once()
+		db.On("RevokeFileAccess", mock.Anything, int64(1), database.GranteeUser, "carol").
+			Return(int64(0), fmt.Errorf("postgres.RevokeFileAccess: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("1", "grantee_type=user&grantee=carol", "alice")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"grant not found\"}\n", string(body))
+	})
+
+	t.Run("invalid grant", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("1", "grantee_type=role&grantee=bob", "alice")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(fileId string, query string, username string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/%s/acl?%s", fileId, query), nil)
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = requestctx.WithFileId(ctx, fileId)
+	ctx = requestctx.WithUser(ctx, &database.User{Username: username})
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,90 @@
+package revokeshare_test
+
+import (
+	"context"
+	"file-service/m/internal/database"
+	revokeshare "file-service/m/internal/handlers/revokeShare"
+	"file-service/m/internal/handlers/revokeShare/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRevokeShareHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := revokeshare.New(log, db)
+	file := &database.File{Id: 1, Owner: "alice"}
+
+	t.Run("success", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+		db.On("DeleteShare", mock.Anything, int64(1), int64(7)).Return(int64(1), nil).Once()
+
+		r, w := CreateRequestAndResponse("alice", "7")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"share revoked\"}\n", string(body))
+	})
+
+	t.Run("unknown share", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+		db.On("DeleteShare", mock.Anything, int64(1), int64(8)).Return(int64(0), fmt.Errorf("postgres.DeleteShare: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("alice", "8")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"share not found\"}\n", string(body))
+	})
+
+	t.Run("not owner", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+
+		r, w := CreateRequestAndResponse("bob", "7")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("invalid share id", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("alice", "abc")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(username string, shareId string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodDelete, "/file/1/shares/"+shareId, nil)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("shareID", shareId)
+
+	ctx := requestctx.WithFileId(r.Context(), "1")
+	ctx = requestctx.WithRequestId(ctx, "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Username: username, Role: database.RoleWriter})
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
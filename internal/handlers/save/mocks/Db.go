@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	database "file-service/m/internal/database"
 
 	mock "github.com/stretchr/testify/mock"
@@ -13,27 +15,57 @@ type Db struct {
 	mock.Mock
 }
 
-// SaveFile provides a mock function with given fields: file
-func (_m *Db) SaveFile(file database.FileToSave) (int64, error) {
-	ret := _m.Called(file)
+// GetUsage provides a mock function with given fields: ctx, owner
+func (_m *Db) GetUsage(ctx context.Context, owner string) (*database.Usage, error) {
+	ret := _m.Called(ctx, owner)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUsage")
+	}
+
+	var r0 *database.Usage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*database.Usage, error)); ok {
+		return rf(ctx, owner)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *database.Usage); ok {
+		r0 = rf(ctx, owner)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*database.Usage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, owner)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveFile provides a mock function with given fields: ctx, file
+func (_m *Db) SaveFile(ctx context.Context, file database.FileToSave) (string, error) {
+	ret := _m.Called(ctx, file)
 
 	if len(ret) == 0 {
 		panic("no return value specified for SaveFile")
 	}
 
-	var r0 int64
+	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(database.FileToSave) (int64, error)); ok {
-		return rf(file)
+	if rf, ok := ret.Get(0).(func(context.Context, database.FileToSave) (string, error)); ok {
+		return rf(ctx, file)
 	}
-	if rf, ok := ret.Get(0).(func(database.FileToSave) int64); ok {
-		r0 = rf(file)
+	if rf, ok := ret.Get(0).(func(context.Context, database.FileToSave) string); ok {
+		r0 = rf(ctx, file)
 	} else {
-		r0 = ret.Get(0).(int64)
+		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(database.FileToSave) error); ok {
-		r1 = rf(file)
+	if rf, ok := ret.Get(1).(func(context.Context, database.FileToSave) error); ok {
+		r1 = rf(ctx, file)
 	} else {
 		r1 = ret.Error(1)
 	}
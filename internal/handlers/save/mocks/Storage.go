@@ -3,9 +3,13 @@
 package mocks
 
 import (
-	multipart "mime/multipart"
+	context "context"
+
+	io "io"
 
 	mock "github.com/stretchr/testify/mock"
+
+	storage "file-service/m/internal/storage"
 )
 
 // Storage is an autogenerated mock type for the Storage type
@@ -13,6 +17,24 @@ type Storage struct {
 	mock.Mock
 }
 
+// DeleteFile provides a mock function with given fields: ctx, name
+func (_m *Storage) DeleteFile(ctx context.Context, name string) error {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteFile")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetStoragePath provides a mock function with given fields:
 func (_m *Storage) GetStoragePath() string {
 	ret := _m.Called()
@@ -49,17 +71,17 @@ func (_m *Storage) GetStorageType() string {
 	return r0
 }
 
-// SaveFile provides a mock function with given fields: file, name
-func (_m *Storage) SaveFile(file multipart.File, name string) error {
-	ret := _m.Called(file, name)
+// SaveFile provides a mock function with given fields: ctx, file, name, meta
+func (_m *Storage) SaveFile(ctx context.Context, file io.Reader, name string, meta *storage.Meta) error {
+	ret := _m.Called(ctx, file, name, meta)
 
 	if len(ret) == 0 {
 		panic("no return value specified for SaveFile")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(multipart.File, string) error); ok {
-		r0 = rf(file, name)
+	if rf, ok := ret.Get(0).(func(context.Context, io.Reader, string, *storage.Meta) error); ok {
+		r0 = rf(ctx, file, name, meta)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -1,9 +1,18 @@
 package save
 
 import (
+	"context"
+	"errors"
 	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/audit"
+	"file-service/m/internal/customerkey"
 	"file-service/m/internal/database"
+	"file-service/m/internal/filename"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/storage"
+	"file-service/m/internal/tracing"
 	"fmt"
+	"io"
 	"log/slog"
 	"mime/multipart"
 	"net/http"
@@ -13,19 +22,25 @@ import (
 
 type Response struct {
 	apiresponse.ApiResponse
-	Id int64 `json:"id,omitempty"`
+	Id string `json:"id,omitempty"`
 }
 
+// multipartOverhead is allowed on top of the remaining quota for the
+// multipart framing around the file.
+const multipartOverhead = 64 << 10
+
 //go:generate mockery --name=Db
 type Db interface {
-	SaveFile(file database.FileToSave) (int64, error)
+	SaveFile(ctx context.Context, file database.FileToSave) (string, error)
+	GetUsage(ctx context.Context, owner string) (*database.Usage, error)
 }
 
 //go:generate mockery --name=Storage
 type Storage interface {
 	GetStoragePath() string
 	GetStorageType() string
-	SaveFile(file multipart.File, name string) error
+	SaveFile(ctx context.Context, file io.Reader, name string, meta *storage.Meta) error
+	DeleteFile(ctx context.Context, name string) error
 }
 
 //go:generate mockery --name=UuidGenerator
@@ -33,16 +48,78 @@ type UuidGenerator interface {
 	GenerateUUID() string
 }
 
-func New(logger *slog.Logger, db Db, storage Storage, uuidGen UuidGenerator) http.HandlerFunc {
+func New(logger *slog.Logger, db Db, fileStorage Storage, uuidGen UuidGenerator) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.save.New"
 
-		log := *logger.With(
-			slog.String("op", op),
-			slog.String("request_id", r.Context().Value("requestId").(string)),
-		)
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		owner := user.Username
+
+		customerKey, err := customerkey.FromRequest(r)
+		if err != nil {
+			log.Error("invalid customer key", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid encryption key"))
+			return
+		}
+
+		usage, err := db.GetUsage(r.Context(), owner)
+		if err != nil {
+			log.Error("failed to get usage", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to save file"))
+			return
+		}
+
+		if usage.Exhausted() {
+			log.Info("storage quota exhausted", slog.String("owner", owner))
+			render.Status(r, http.StatusInsufficientStorage)
+			render.JSON(w, r, apiresponse.Error("storage quota exceeded"))
+			return
+		}
+
+		// uploads through a signed URL carry the constraints it was issued
+		// with, the smaller of its size limit and the quota applies
+		constraints, _ := requestctx.UploadConstraints(r.Context())
+
+		limit, tooLarge := usage.RemainingBytes(), "file exceeds storage quota"
+		if constraints != nil && constraints.MaxSize > 0 && (limit < 0 || constraints.MaxSize < limit) {
+			limit, tooLarge = constraints.MaxSize, "file too large"
+		}
 
-		err := r.ParseMultipartForm(32 << 20)
+		if limit >= 0 {
+			if r.ContentLength > limit+multipartOverhead {
+				log.Info(tooLarge, slog.String("owner", owner))
+				render.Status(r, http.StatusRequestEntityTooLarge)
+				render.JSON(w, r, apiresponse.Error(tooLarge))
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit+multipartOverhead)
+		}
+
+		// parsing reads the whole upload, a span of its own tells slow
+		// clients apart from slow storage
+		_, span := tracing.Start(r.Context(), "save.ParseMultipartForm")
+		err = r.ParseMultipartForm(32 << 20)
+		span.End()
+
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			log.Info(tooLarge, slog.String("owner", owner))
+			render.Status(r, http.StatusRequestEntityTooLarge)
+			render.JSON(w, r, apiresponse.Error(tooLarge))
+			return
+		}
 		if err != nil {
 			log.Error("failed to parse multipart form", slog.Any("error", err))
 			render.Status(r, http.StatusBadRequest)
@@ -65,25 +142,96 @@ func New(logger *slog.Logger, db Db, storage Storage, uuidGen UuidGenerator) htt
 			slog.Int64("size", handler.Size),
 		)
 
-		newName := fmt.Sprintf("%v_%v", uuidGen.GenerateUUID(), handler.Filename)
+		if limit >= 0 && handler.Size > limit {
+			log.Info(tooLarge, slog.String("owner", owner))
+			render.Status(r, http.StatusRequestEntityTooLarge)
+			render.JSON(w, r, apiresponse.Error(tooLarge))
+			return
+		}
+
+		if constraints != nil {
+			// the header of the part is up to the client, the content is not
+			contentType, err := sniffContentType(file)
+			if err != nil {
+				log.Error("failed to read file", slog.Any("error", err))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, apiresponse.Error("invalid request"))
+				return
+			}
+
+			if !constraints.AllowsContentType(contentType) {
+				log.Info("content type not allowed",
+					slog.String("content_type", contentType),
+					slog.String("declared_content_type", handler.Header.Get("Content-Type")),
+				)
+				render.Status(r, http.StatusUnsupportedMediaType)
+				render.JSON(w, r, apiresponse.Error("content type not allowed"))
+				return
+			}
+		}
 
-		err = storage.SaveFile(file, newName)
+		originalName, err := filename.Normalize(handler.Filename)
 		if err != nil {
-			logger.Error("failed to save file", slog.Any("error", err))
+			log.Error("invalid file name", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid file name"))
+			return
+		}
+
+		newName := uuidGen.GenerateUUID()
+
+		meta := storage.Meta{
+			Size:        handler.Size,
+			CustomerKey: customerKey,
+		}
+		err = fileStorage.SaveFile(r.Context(), file, newName, &meta)
+		if err != nil {
+			log.Error("failed to save file", slog.Any("error", err))
 			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, apiresponse.Error("failed to save file"))
 			return
 		}
 
 		fileToSave := database.FileToSave{
-			OriginalName: handler.Filename,
+			Owner:        owner,
+			OriginalName: originalName,
 			Name:         newName,
-			Path:         fmt.Sprintf("%s/%s", storage.GetStoragePath(), newName),
-			StorageType:  storage.GetStorageType(),
+			Path:         fmt.Sprintf("%s/%s", fileStorage.GetStoragePath(), newName),
+			StorageType:  fileStorage.GetStorageType(),
 			Size:         handler.Size,
+			KeyId:        meta.KeyId,
+			WrappedKey:   meta.WrappedKey,
+			Encoding:     meta.Encoding,
 		}
 
-		id, err := db.SaveFile(fileToSave)
+		if customerKey != nil {
+			fileToSave.KeyFingerprint = customerkey.Fingerprint(customerKey)
+		}
+
+		if constraints != nil {
+			fileToSave.UploadNonce = constraints.Nonce
+			fileToSave.UploadExpires = constraints.Expires
+		}
+
+		id, err := db.SaveFile(r.Context(), fileToSave)
+		if err != nil {
+			// nothing refers to the blob, it would never be removed
+			if err := fileStorage.DeleteFile(context.WithoutCancel(r.Context()), newName); err != nil {
+				log.Error("failed to remove unsaved file", slog.Any("error", err), slog.String("name", newName))
+			}
+		}
+		if errors.Is(err, database.ErrorQuotaExceeded) {
+			log.Info("storage quota exceeded", slog.String("owner", owner))
+			render.Status(r, http.StatusInsufficientStorage)
+			render.JSON(w, r, apiresponse.Error("storage quota exceeded"))
+			return
+		}
+		// another upload with the same signed URL got there first
+		if errors.Is(err, database.ErrorUploadUrlUsed) {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, apiresponse.Error("url already used"))
+			return
+		}
 		if err != nil {
 			log.Error("failed to save file", slog.Any("error", err))
 			render.Status(r, http.StatusInternalServerError)
@@ -91,7 +239,10 @@ func New(logger *slog.Logger, db Db, storage Storage, uuidGen UuidGenerator) htt
 			return
 		}
 
-		log.Info("file saved", slog.Int64("id", id))
+		audit.SetFile(r.Context(), 0, id)
+		audit.SetBytes(r.Context(), handler.Size)
+
+		log.Info("file saved", slog.String("id", id))
 		render.Status(r, http.StatusCreated)
 		render.JSON(w, r, Response{
 			ApiResponse: apiresponse.Success("file saved"),
@@ -99,3 +250,19 @@ func New(logger *slog.Logger, db Db, storage Storage, uuidGen UuidGenerator) htt
 		})
 	}
 }
+
+// sniffContentType detects the content type of file from its first bytes
+// and rewinds it.
+func sniffContentType(file multipart.File) (string, error) {
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	return http.DetectContentType(buf[:n]), nil
+}
@@ -2,16 +2,23 @@ package save_test
 
 import (
 	"bytes"
-	"context"
+	"encoding/base64"
+	"file-service/m/internal/customerkey"
+	"file-service/m/internal/database"
 	"file-service/m/internal/handlers/save"
 	"file-service/m/internal/handlers/save/mocks"
 	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/signedurl"
+	fileStorage "file-service/m/internal/storage"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -27,10 +34,11 @@ func TestSaveHandler(t *testing.T) {
 	uuidGen.On("GenerateUUID").Return("123").Maybe()
 	storage.On("GetStoragePath").Return("test").Maybe()
 	storage.On("GetStorageType").Return("local").Maybe()
+	db.On("GetUsage", mock.Anything, mock.Anything).Return(&database.Usage{}, nil).Maybe()
 
 	t.Run("success", func(t *testing.T) {
-		storage.On("SaveFile", mock.Anything, mock.Anything).Return(nil).Once()
-		db.On("SaveFile", mock.Anything).Return(int64(1), nil).Once()
+		storage.On("SaveFile", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		db.On("SaveFile", mock.Anything, mock.Anything).Return("0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f", nil).Once()
 
 		r, w := CreateRequestAndResponse(t, []byte("test"), "file", "test")
 
@@ -38,10 +46,10 @@ func TestSaveHandler(t *testing.T) {
 
 		resp := w.Result()
 		body, _ := io.ReadAll(resp.Body)
-		bodyResp := string(body);
+		bodyResp := string(body)
 
 		assert.Equal(t, http.StatusCreated, resp.StatusCode)
-		assert.Equal(t, "{\"status\":\"success\",\"message\":\"file saved\",\"id\":1}\n", bodyResp)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"file saved\",\"id\":\"0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f\"}\n", bodyResp)
 	})
 
 	t.Run("invalid file key", func(t *testing.T) {
@@ -50,7 +58,7 @@ func TestSaveHandler(t *testing.T) {
 
 		resp := w.Result()
 		body, _ := io.ReadAll(resp.Body)
-		bodyResp := string(body);
+		bodyResp := string(body)
 
 		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 		assert.Equal(t, "{\"status\":\"error\",\"message\":\"invalid request\"}\n", bodyResp)
@@ -62,40 +70,362 @@ func TestSaveHandler(t *testing.T) {
 
 		resp := w.Result()
 		body, _ := io.ReadAll(resp.Body)
-		bodyResp := string(body);
+		bodyResp := string(body)
 
 		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 		assert.Equal(t, "{\"status\":\"error\",\"message\":\"invalid request\"}\n", bodyResp)
 	})
 
+	t.Run("hostile file name", func(t *testing.T) {
+		storage.On("SaveFile", mock.Anything, mock.Anything, "123", mock.Anything).Return(nil).Once()
+		db.On("SaveFile", mock.Anything, mock.MatchedBy(func(f database.FileToSave) bool {
+			return f.Name == "123" && f.OriginalName == "passwd" && f.Path == "test/123"
+		})).Return("0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f", nil).Once()
+
+		r, w := CreateRequestAndResponse(t, []byte("test"), "file", `..\..\etc/passwd`)
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("storage meta is saved", func(t *testing.T) {
+		storage.On("SaveFile", mock.Anything, mock.Anything, "123", mock.Anything).Run(func(args mock.Arguments) {
+			meta := args.Get(3).(*fileStorage.Meta)
+			assert.Equal(t, int64(4), meta.Size)
+			meta.KeyId = "key"
+			meta.WrappedKey = []byte("wrapped")
+			meta.Encoding = "gzip"
+		}).Return(nil).Once()
+		db.On("SaveFile", mock.Anything, mock.MatchedBy(func(f database.FileToSave) bool {
+			return f.KeyId == "key" && string(f.WrappedKey) == "wrapped" && f.Encoding == "gzip" && f.Size == 4
+		})).Return("0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f", nil).Once()
+
+		r, w := CreateRequestAndResponse(t, []byte("test"), "file", "test")
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("customer key", func(t *testing.T) {
+		key := bytes.Repeat([]byte("k"), 32)
+
+		storage.On("SaveFile", mock.Anything, mock.Anything, "123", mock.MatchedBy(func(meta *fileStorage.Meta) bool {
+			return bytes.Equal(meta.CustomerKey, key)
+		})).Return(nil).Once()
+		db.On("SaveFile", mock.Anything, mock.MatchedBy(func(f database.FileToSave) bool {
+			return f.KeyFingerprint == customerkey.Fingerprint(key)
+		})).Return("0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f", nil).Once()
+
+		r, w := CreateRequestAndResponse(t, []byte("test"), "file", "test")
+		r.Header.Set(customerkey.HeaderAlgorithm, customerkey.AlgorithmAES256)
+		r.Header.Set(customerkey.HeaderKey, base64.StdEncoding.EncodeToString(key))
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("invalid customer key", func(t *testing.T) {
+		r, w := CreateRequestAndResponse(t, []byte("test"), "file", "test")
+		r.Header.Set(customerkey.HeaderAlgorithm, customerkey.AlgorithmAES256)
+		r.Header.Set(customerkey.HeaderKey, "short")
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+		bodyResp := string(body)
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"invalid encryption key\"}\n", bodyResp)
+	})
+
+	t.Run("control characters in file name", func(t *testing.T) {
+		r, w := CreateRequestAndResponse(t, []byte("test"), "file", "evil\u202etxt.exe")
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+		bodyResp := string(body)
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"invalid file name\"}\n", bodyResp)
+	})
+
 	t.Run("storage error", func(t *testing.T) {
-		storage.On("SaveFile", mock.Anything, mock.Anything).Return(error).Once()
+		storage.On("SaveFile", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(error).Once()
 		r, w := CreateRequestAndResponse(t, []byte("test"), "file", "test")
 		handler.ServeHTTP(w, r)
 
 		resp := w.Result()
 		body, _ := io.ReadAll(resp.Body)
-		bodyResp := string(body);
+		bodyResp := string(body)
 
 		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 		assert.Equal(t, "{\"status\":\"error\",\"message\":\"failed to save file\"}\n", bodyResp)
 	})
 
 	t.Run("db error", func(t *testing.T) {
-		storage.On("SaveFile", mock.Anything, mock.Anything).Return(nil).Once()
-		db.On("SaveFile", mock.Anything).Return(int64(0), error).Once()
+		storage.On("SaveFile", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		db.On("SaveFile", mock.Anything, mock.Anything).Return("", error).Once()
+		storage.On("DeleteFile", mock.Anything, "123").Return(nil).Once()
 		r, w := CreateRequestAndResponse(t, []byte("test"), "file", "test")
 		handler.ServeHTTP(w, r)
 
 		resp := w.Result()
 		body, _ := io.ReadAll(resp.Body)
-		bodyResp := string(body);
+		bodyResp := string(body)
 
 		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 		assert.Equal(t, "{\"status\":\"error\",\"message\":\"failed to save file\"}\n", bodyResp)
 	})
 }
 
+func TestSaveHandlerQuota(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	storage := mocks.NewStorage(t)
+	uuidGen := mocks.NewUuidGenerator(t)
+	handler := save.New(log, db, storage, uuidGen)
+	uuidGen.On("GenerateUUID").Return("123").Maybe()
+	storage.On("GetStoragePath").Return("test").Maybe()
+	storage.On("GetStorageType").Return("local").Maybe()
+
+	quota := database.Quota{MaxBytes: 100, MaxFiles: 2}
+
+	t.Run("within quota", func(t *testing.T) {
+		db.On("GetUsage", mock.Anything, "alice").Return(&database.Usage{Quota: quota, UsedBytes: 90, UsedFiles: 1}, nil).Once()
+		storage.On("SaveFile", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		db.On("SaveFile", mock.Anything, mock.MatchedBy(func(f database.FileToSave) bool {
+			return f.Owner == "alice"
+		})).Return("0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f", nil).Once()
+
+		r, w := CreateRequestAndResponse(t, []byte("test"), "file", "test")
+		r = SetUser(r, "alice")
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+	})
+
+	tests := []struct {
+		name          string
+		usage         database.Usage
+		file          []byte
+		contentLength int64
+		status        int
+		message       string
+	}{
+		{
+			name:    "bytes exhausted",
+			usage:   database.Usage{Quota: quota, UsedBytes: 100},
+			file:    []byte("test"),
+			status:  http.StatusInsufficientStorage,
+			message: "storage quota exceeded",
+		},
+		{
+			name:    "files exhausted",
+			usage:   database.Usage{Quota: quota, UsedFiles: 2},
+			file:    []byte("test"),
+			status:  http.StatusInsufficientStorage,
+			message: "storage quota exceeded",
+		},
+		{
+			name:    "file larger than remaining quota",
+			usage:   database.Usage{Quota: quota, UsedBytes: 98},
+			file:    []byte("test"),
+			status:  http.StatusRequestEntityTooLarge,
+			message: "file exceeds storage quota",
+		},
+		{
+			name:          "content length larger than remaining quota",
+			usage:         database.Usage{Quota: quota, UsedBytes: 50},
+			file:          []byte("test"),
+			contentLength: 1 << 20,
+			status:        http.StatusRequestEntityTooLarge,
+			message:       "file exceeds storage quota",
+		},
+		{
+			name:          "streamed body larger than remaining quota",
+			usage:         database.Usage{Quota: quota, UsedBytes: 50},
+			file:          bytes.Repeat([]byte("a"), 128<<10),
+			contentLength: -1,
+			status:        http.StatusRequestEntityTooLarge,
+			message:       "file exceeds storage quota",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage := tt.usage
+			db.On("GetUsage", mock.Anything, "alice").Return(&usage, nil).Once()
+
+			r, w := CreateRequestAndResponse(t, tt.file, "file", "test")
+			r = SetUser(r, "alice")
+			if tt.contentLength != 0 {
+				r.ContentLength = tt.contentLength
+			}
+			handler.ServeHTTP(w, r)
+
+			resp := w.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Equal(t, fmt.Sprintf("{\"status\":\"error\",\"message\":\"%s\"}\n", tt.message), string(body))
+		})
+	}
+
+	t.Run("quota exceeded concurrently", func(t *testing.T) {
+		db.On("GetUsage", mock.Anything, "alice").Return(&database.Usage{Quota: quota}, nil).Once()
+		storage.On("SaveFile", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		db.On("SaveFile", mock.Anything, mock.Anything).Return("", fmt.Errorf("postgres.InsertFile: %w", database.ErrorQuotaExceeded)).Once()
+		storage.On("DeleteFile", mock.Anything, "123").Return(nil).Once()
+
+		r, w := CreateRequestAndResponse(t, []byte("test"), "file", "test")
+		r = SetUser(r, "alice")
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInsufficientStorage, w.Result().StatusCode)
+	})
+
+	t.Run("usage error", func(t *testing.T) {
+		db.On("GetUsage", mock.Anything, "alice").Return(nil, fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse(t, []byte("test"), "file", "test")
+		r = SetUser(r, "alice")
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func TestSaveHandlerUploadConstraints(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	storage := mocks.NewStorage(t)
+	uuidGen := mocks.NewUuidGenerator(t)
+	handler := save.New(log, db, storage, uuidGen)
+	uuidGen.On("GenerateUUID").Return("123").Maybe()
+	storage.On("GetStoragePath").Return("test").Maybe()
+	storage.On("GetStorageType").Return("local").Maybe()
+
+	expires := time.Now().Add(time.Minute)
+	constraints := &signedurl.UploadConstraints{Owner: "alice", MaxSize: 10, ContentTypes: []string{"image/*"}, Nonce: "n1", Expires: expires}
+	unlimited := &database.Usage{Quota: database.Quota{}}
+
+	png := []byte("\x89PNG\r\n\x1a\n")
+
+	t.Run("allowed", func(t *testing.T) {
+		var saved []byte
+		db.On("GetUsage", mock.Anything, "alice").Return(unlimited, nil).Once()
+		storage.On("SaveFile", mock.Anything, mock.Anything, "123", mock.Anything).Run(func(args mock.Arguments) {
+			saved, _ = io.ReadAll(args.Get(1).(io.Reader))
+		}).Return(nil).Once()
+		db.On("SaveFile", mock.Anything, mock.MatchedBy(func(f database.FileToSave) bool {
+			return f.Owner == "alice" && f.UploadNonce == "n1" && f.UploadExpires.Equal(expires)
+		})).Return("0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f", nil).Once()
+
+		r, w := CreateConstrainedRequest(png, "image/png", constraints)
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusCreated, w.Result().StatusCode)
+		assert.Equal(t, png, saved)
+	})
+
+	t.Run("url used meanwhile", func(t *testing.T) {
+		db.On("GetUsage", mock.Anything, "alice").Return(unlimited, nil).Once()
+		storage.On("SaveFile", mock.Anything, mock.Anything, "123", mock.Anything).Return(nil).Once()
+		db.On("SaveFile", mock.Anything, mock.Anything).Return("", fmt.Errorf("postgres.SaveFile: %w", database.ErrorUploadUrlUsed)).Once()
+		storage.On("DeleteFile", mock.Anything, "123").Return(nil).Once()
+
+		r, w := CreateConstrainedRequest(png, "image/png", constraints)
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"url already used\"}\n", string(body))
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		db.On("GetUsage", mock.Anything, "alice").Return(unlimited, nil).Once()
+
+		r, w := CreateConstrainedRequest([]byte("more than ten bytes"), "image/png", constraints)
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"file too large\"}\n", string(body))
+	})
+
+	t.Run("quota smaller than max size", func(t *testing.T) {
+		db.On("GetUsage", mock.Anything, "alice").Return(&database.Usage{Quota: database.Quota{MaxBytes: 100}, UsedBytes: 98}, nil).Once()
+
+		r, w := CreateConstrainedRequest([]byte("test"), "image/png", constraints)
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"file exceeds storage quota\"}\n", string(body))
+	})
+
+	t.Run("content type not allowed", func(t *testing.T) {
+		db.On("GetUsage", mock.Anything, "alice").Return(unlimited, nil).Once()
+
+		r, w := CreateConstrainedRequest([]byte("<html>"), "text/html", constraints)
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"content type not allowed\"}\n", string(body))
+	})
+
+	t.Run("mislabelled content type", func(t *testing.T) {
+		db.On("GetUsage", mock.Anything, "alice").Return(unlimited, nil).Once()
+
+		r, w := CreateConstrainedRequest([]byte("<html>"), "image/png", constraints)
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"content type not allowed\"}\n", string(body))
+	})
+}
+
+func CreateConstrainedRequest(file []byte, contentType string, constraints *signedurl.UploadConstraints) (*http.Request, *httptest.ResponseRecorder) {
+	var buf bytes.Buffer
+	multipartWriter := multipart.NewWriter(&buf)
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="test"`)
+	header.Set("Content-Type", contentType)
+	filePart, _ := multipartWriter.CreatePart(header)
+	filePart.Write(file)
+	multipartWriter.Close()
+
+	r := httptest.NewRequest("POST", "/upload", &buf)
+	r.Header.Set("Content-Type", multipartWriter.FormDataContentType())
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Username: constraints.Owner})
+	ctx = requestctx.WithUploadConstraints(ctx, constraints)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
+
 func CreateRequestAndResponse(t *testing.T, file []byte, fileKey string, fileName string) (*http.Request, *httptest.ResponseRecorder) {
 	var buf bytes.Buffer
 	multipartWriter := multipart.NewWriter(&buf)
@@ -106,10 +436,14 @@ func CreateRequestAndResponse(t *testing.T, file []byte, fileKey string, fileNam
 
 	r := httptest.NewRequest("POST", "/", &buf)
 	r.Header.Set("Content-Type", multipartWriter.FormDataContentType())
-	r = r.WithContext(
-		context.WithValue(r.Context(), "requestId", "123"),
-	)
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Username: "test"})
+	r = r.WithContext(ctx)
 	w := httptest.NewRecorder()
 
 	return r, w
 }
+
+func SetUser(r *http.Request, username string) *http.Request {
+	return r.WithContext(requestctx.WithUser(r.Context(), &database.User{Username: username}))
+}
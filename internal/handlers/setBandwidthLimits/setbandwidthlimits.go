@@ -0,0 +1,62 @@
+package setbandwidthlimits
+
+import (
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/bandwidth"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+// Request changes the limits that are set, in bytes per second. Zero
+// removes a limit.
+type Request struct {
+	Global    *int64 `json:"global"`
+	PerClient *int64 `json:"per_client"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+	Limits bandwidth.Limits `json:"limits"`
+}
+
+func New(logger *slog.Logger, throttle *bandwidth.Throttle) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.setbandwidthlimits.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid request"))
+			return
+		}
+
+		if (req.Global != nil && *req.Global < 0) || (req.PerClient != nil && *req.PerClient < 0) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid limit"))
+			return
+		}
+
+		limits := throttle.Limits()
+		if req.Global != nil {
+			limits.Global = *req.Global
+		}
+		if req.PerClient != nil {
+			limits.PerClient = *req.PerClient
+		}
+
+		throttle.SetLimits(limits)
+
+		log.Info("bandwidth limits updated", slog.Int64("global", limits.Global), slog.Int64("per_client", limits.PerClient))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			ApiResponse: apiresponse.Success("bandwidth limits updated"),
+			Limits:      limits,
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package setbandwidthlimits_test
+
+import (
+	"file-service/m/internal/bandwidth"
+	setbandwidthlimits "file-service/m/internal/handlers/setBandwidthLimits"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetBandwidthLimitsHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	throttle := bandwidth.New(bandwidth.Limits{Global: 1 << 20, PerClient: 1 << 10})
+	handler := setbandwidthlimits.New(log, throttle)
+
+	t.Run("partial update", func(t *testing.T) {
+		r, w := CreateRequestAndResponse(`{"per_client":4096}`)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"bandwidth limits updated\",\"limits\":{\"global\":1048576,\"per_client\":4096}}\n", string(body))
+		assert.Equal(t, bandwidth.Limits{Global: 1 << 20, PerClient: 4096}, throttle.Limits())
+	})
+
+	t.Run("remove limit", func(t *testing.T) {
+		r, w := CreateRequestAndResponse(`{"global":0}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Equal(t, bandwidth.Limits{PerClient: 4096}, throttle.Limits())
+	})
+
+	t.Run("negative limit", func(t *testing.T) {
+		r, w := CreateRequestAndResponse(`{"global":-1}`)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"invalid limit\"}\n", string(body))
+		assert.Equal(t, bandwidth.Limits{PerClient: 4096}, throttle.Limits())
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		r, w := CreateRequestAndResponse(`{`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(body string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodPut, "/admin/bandwidth", strings.NewReader(body))
+	r = r.WithContext(
+		requestctx.WithRequestId(r.Context(), "123"),
+	)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -1,7 +1,13 @@
 package setdelete
 
 import (
+	"context"
+	"errors"
+	"file-service/m/internal/access"
 	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/audit"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -15,19 +21,26 @@ type Response struct {
 
 //go:generate mockery --name=Db
 type Db interface {
-	SetFileIsDeleted(id int64) (int64, error)
+	GetFile(ctx context.Context, id int64, isDeleted bool) (*database.File, error)
+	GetFilePermissions(ctx context.Context, fileId int64, username string, groups []string) ([]string, error)
+	SetFileIsDeleted(ctx context.Context, id int64) (int64, error)
 }
 
 func New(logger *slog.Logger, db Db) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.get.New"
 
-		log := *logger.With(
-			slog.String("op", op),
-			slog.String("request_id", r.Context().Value("requestId").(string)),
-		)
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
 
-		fileIdStr, ok := r.Context().Value("fileID").(string)
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		fileIdStr, ok := requestctx.FileId(r.Context())
 		if !ok {
 			log.Error("file id is empty")
 			render.Status(r, http.StatusBadRequest)
@@ -50,7 +63,38 @@ func New(logger *slog.Logger, db Db) http.HandlerFunc {
 			return
 		}
 
-		AffectedRows, err := db.SetFileIsDeleted(fileId)
+		file, err := db.GetFile(r.Context(), fileId, false)
+		if errors.Is(err, database.ErrorNotFound) {
+			log.Info("file not found")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get file", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to delete file"))
+			return
+		}
+
+		audit.SetFile(r.Context(), file.Id, file.PublicId)
+
+		allowed, err := access.Allowed(r.Context(), db, user, file, database.PermissionWrite)
+		if err != nil {
+			log.Error("failed to check access", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to delete file"))
+			return
+		}
+
+		if !allowed {
+			log.Info("access denied")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("file not found"))
+			return
+		}
+
+		AffectedRows, err := db.SetFileIsDeleted(r.Context(), fileId)
 		if err != nil {
 			log.Error("failed to set file as deleted", slog.Any("error", err))
 			render.Status(r, http.StatusInternalServerError)
@@ -65,7 +109,7 @@ func New(logger *slog.Logger, db Db) http.HandlerFunc {
 			return
 		}
 
-		log.Info("file deleted", slog.Int64("file_id", fileId))
+		log.Info("file deleted")
 		render.Status(r, http.StatusOK)
 		render.JSON(w, r, Response{apiresponse.Success("file deleted")})
 	}
@@ -1,10 +1,11 @@
 package setdelete_test
 
 import (
-	"context"
-	"file-service/m/internal/handlers/setDelete/mocks"
+	"file-service/m/internal/database"
 	setdelete "file-service/m/internal/handlers/setDelete"
+	"file-service/m/internal/handlers/setDelete/mocks"
 	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
 	"fmt"
 	"io"
 	"net/http"
@@ -22,9 +23,10 @@ func TestSetDeleteHandler(t *testing.T) {
 	handler := setdelete.New(log, db)
 
 	t.Run("success", func(t *testing.T) {
-		db.On("SetFileIsDeleted", mock.Anything).Return(int64(1), nil).Once()
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, Owner: "alice"}, nil).Once()
+		db.On("SetFileIsDeleted", mock.Anything, mock.Anything).Return(int64(1), nil).Once()
 
-		r, w := CreateRequestAndResponse("fileID", "1")
+		r, w := CreateRequestAndResponse("1")
 
 		handler.ServeHTTP(w, r)
 
@@ -37,9 +39,10 @@ func TestSetDeleteHandler(t *testing.T) {
 	})
 
 	t.Run("db error", func(t *testing.T) {
-		db.On("SetFileIsDeleted", mock.Anything).Return(int64(0), errorResp).Once()
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, Owner: "alice"}, nil).Once()
+		db.On("SetFileIsDeleted", mock.Anything, mock.Anything).Return(int64(0), errorResp).Once()
 
-		r, w := CreateRequestAndResponse("fileID", "1")
+		r, w := CreateRequestAndResponse("1")
 
 		handler.ServeHTTP(w, r)
 
@@ -52,9 +55,10 @@ func TestSetDeleteHandler(t *testing.T) {
 	})
 
 	t.Run("0 affected rows", func(t *testing.T) {
-		db.On("SetFileIsDeleted", mock.Anything).Return(int64(0), nil).Once()
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1, Owner: "alice"}, nil).Once()
+		db.On("SetFileIsDeleted", mock.Anything, mock.Anything).Return(int64(0), nil).Once()
 
-		r, w := CreateRequestAndResponse("fileID", "1")
+		r, w := CreateRequestAndResponse("1")
 
 		handler.ServeHTTP(w, r)
 
@@ -66,8 +70,42 @@ func TestSetDeleteHandler(t *testing.T) {
 		assert.Equal(t, "{\"status\":\"error\",\"message\":\"failed to delete file\"}\n", bodyResp)
 	})
 
+	t.Run("access", func(t *testing.T) {
+		file := &database.File{Id: 1, Owner: "bob"}
+
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+		db.On("GetFilePermissions", mock.Anything, int64(1), "alice", []string(nil)).Return([]string{database.PermissionRead}, nil).Once()
+
+		r, w := CreateRequestAndResponse("1")
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"file not found\"}\n", string(body))
+
+		db.On("GetFile", mock.Anything, int64(1), false).Return(file, nil).Once()
+		db.On("GetFilePermissions", mock.Anything, int64(1), "alice", []string(nil)).Return([]string{database.PermissionWrite}, nil).Once()
+		db.On("SetFileIsDeleted", mock.Anything, int64(1)).Return(int64(1), nil).Once()
+
+		r, w = CreateRequestAndResponse("1")
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db.On("GetFile", mock.Anything, int64(2), false).Return(nil, fmt.Errorf("postgres.GetFile: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("2")
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
 	t.Run("invalid fileId", func(t *testing.T) {
-		r, w := CreateRequestAndResponse("fileID", "1asdf")
+		r, w := CreateRequestAndResponse("1asdf")
 
 		handler.ServeHTTP(w, r)
 
@@ -79,8 +117,8 @@ func TestSetDeleteHandler(t *testing.T) {
 		assert.Equal(t, "{\"status\":\"error\",\"message\":\"invalid file id\"}\n", bodyResp)
 	})
 
-	t.Run("invalid fileKey", func(t *testing.T) {
-		r, w := CreateRequestAndResponse("fileKey", "1")
+	t.Run("missing file id", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("")
 
 		handler.ServeHTTP(w, r)
 
@@ -93,13 +131,16 @@ func TestSetDeleteHandler(t *testing.T) {
 	})
 }
 
-func CreateRequestAndResponse(fileKey string, fileId string) (*http.Request, *httptest.ResponseRecorder) {
+func CreateRequestAndResponse(fileId string) (*http.Request, *httptest.ResponseRecorder) {
 	r := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/%s", fileId), nil)
+	if fileId != "" {
+		r = r.WithContext(requestctx.WithFileId(r.Context(), fileId))
+	}
 	r = r.WithContext(
-		context.WithValue(r.Context(), fileKey, fileId),
+		requestctx.WithRequestId(r.Context(), "123"),
 	)
 	r = r.WithContext(
-		context.WithValue(r.Context(), "requestId", "123"),
+		requestctx.WithUser(r.Context(), &database.User{Username: "alice"}),
 	)
 	w := httptest.NewRecorder()
 
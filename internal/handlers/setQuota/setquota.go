@@ -0,0 +1,72 @@
+package setquota
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// Request sets the limits of a user, zero means unlimited.
+type Request struct {
+	MaxBytes int64 `json:"max_bytes"`
+	MaxFiles int64 `json:"max_files"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	SetQuota(ctx context.Context, username string, quota database.Quota) (int64, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.setquota.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		username := chi.URLParam(r, "username")
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid request"))
+			return
+		}
+
+		if req.MaxBytes < 0 || req.MaxFiles < 0 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid quota"))
+			return
+		}
+
+		// limits below the current usage only stop further uploads
+		_, err := db.SetQuota(r.Context(), username, database.Quota{MaxBytes: req.MaxBytes, MaxFiles: req.MaxFiles})
+		if errors.Is(err, database.ErrorNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("user not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update quota", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to update quota"))
+			return
+		}
+
+		log.Info("quota changed", slog.String("username", username),
+			slog.Int64("max_bytes", req.MaxBytes), slog.Int64("max_files", req.MaxFiles))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{apiresponse.Success("quota updated")})
+	}
+}
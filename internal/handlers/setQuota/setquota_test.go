@@ -0,0 +1,106 @@
+package setquota_test
+
+import (
+	"context"
+	"file-service/m/internal/database"
+	setquota "file-service/m/internal/handlers/setQuota"
+	"file-service/m/internal/handlers/setQuota/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetQuotaHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := setquota.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("SetQuota", mock.Anything, "bob", database.Quota{MaxBytes: 1024, MaxFiles: 10}).Return(int64(1), nil).Once()
+
+		r, w := CreateRequestAndResponse("bob", `{"max_bytes":1024,"max_files":10}`)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"quota updated\"}\n", string(body))
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		db.On("SetQuota", mock.Anything, "bob", database.Quota{}).Return(int64(1), nil).Once()
+
+		r, w := CreateRequestAndResponse("bob", `{}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+
+	t.Run("negative limit", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("bob", `{"max_bytes":-1}`)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"invalid quota\"}\n", string(body))
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("bob", `{"max_bytes":"a lot"}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db.On("SetQuota", mock.Anything, "nobody", database.Quota{MaxFiles: 1}).
+			Return(int64(0), fmt.Errorf("postgres.SetQuota: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("nobody", `{"max_files":1}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("SetQuota", mock.Anything, "bob", database.Quota{MaxFiles: 1}).Return(int64(0), fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse("bob", `{"max_files":1}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(username string, body string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodPut, "/admin/users/"+username+"/quota", strings.NewReader(body))
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("username", username)
+
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Username: "admin", Role: database.RoleAdmin})
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,69 @@
+package setuserdisabled
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	Disabled *bool `json:"disabled"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	SetUserDisabled(ctx context.Context, username string, disabled bool) (int64, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.setuserdisabled.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		username := chi.URLParam(r, "username")
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil || req.Disabled == nil {
+			log.Error("failed to decode request", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid request"))
+			return
+		}
+
+		// an admin locking themselves out leaves nobody to undo it
+		if user, ok := requestctx.User(r.Context()); ok && user.Username == username && *req.Disabled {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("cannot disable own user"))
+			return
+		}
+
+		_, err := db.SetUserDisabled(r.Context(), username, *req.Disabled)
+		if errors.Is(err, database.ErrorNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("user not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update user", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to update user"))
+			return
+		}
+
+		log.Info("user updated", slog.String("username", username), slog.Bool("disabled", *req.Disabled))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{apiresponse.Success("user updated")})
+	}
+}
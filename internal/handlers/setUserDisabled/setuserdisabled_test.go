@@ -0,0 +1,106 @@
+package setuserdisabled_test
+
+import (
+	"context"
+	"file-service/m/internal/database"
+	setuserdisabled "file-service/m/internal/handlers/setUserDisabled"
+	"file-service/m/internal/handlers/setUserDisabled/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetUserDisabledHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := setuserdisabled.New(log, db)
+
+	t.Run("disable", func(t *testing.T) {
+		db.On("SetUserDisabled", mock.Anything, "bob", true).Return(int64(1), nil).Once()
+
+		r, w := CreateRequestAndResponse("bob", `{"disabled":true}`)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"user updated\"}\n", string(body))
+	})
+
+	t.Run("enable", func(t *testing.T) {
+		db.On("SetUserDisabled", mock.Anything, "bob", false).Return(int64(1), nil).Once()
+
+		r, w := CreateRequestAndResponse("bob", `{"disabled":false}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("bob", `{}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+
+	t.Run("own user", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("admin", `{"disabled":true}`)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"cannot disable own user\"}\n", string(body))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db.On("SetUserDisabled", mock.Anything, "nobody", true).
+			Return(int64(0), fmt.Errorf("postgres.SetUserDisabled: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("nobody", `{"disabled":true}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("SetUserDisabled", mock.Anything, "bob", true).Return(int64(0), fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse("bob", `{"disabled":true}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(username string, body string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodPatch, "/admin/users/"+username, strings.NewReader(body))
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("username", username)
+
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Username: "admin", Role: database.RoleAdmin})
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
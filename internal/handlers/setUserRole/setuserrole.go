@@ -0,0 +1,76 @@
+package setuserrole
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/policy"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	Role string `json:"role"`
+}
+
+type Response struct {
+	apiresponse.ApiResponse
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	SetUserRole(ctx context.Context, username string, role string) (int64, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.setuserrole.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		username := chi.URLParam(r, "username")
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.Any("error", err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid request"))
+			return
+		}
+
+		if !policy.ValidRole(req.Role) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("invalid role"))
+			return
+		}
+
+		// an admin demoting themselves may leave nobody to undo it
+		if user, ok := requestctx.User(r.Context()); ok && user.Username == username {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, apiresponse.Error("cannot change own role"))
+			return
+		}
+
+		_, err := db.SetUserRole(r.Context(), username, req.Role)
+		if errors.Is(err, database.ErrorNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, apiresponse.Error("user not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update user", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to update user"))
+			return
+		}
+
+		log.Info("user role changed", slog.String("username", username), slog.String("role", req.Role))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{apiresponse.Success("user updated")})
+	}
+}
@@ -0,0 +1,96 @@
+package setuserrole_test
+
+import (
+	"context"
+	"file-service/m/internal/database"
+	setuserrole "file-service/m/internal/handlers/setUserRole"
+	"file-service/m/internal/handlers/setUserRole/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetUserRoleHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	handler := setuserrole.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("SetUserRole", mock.Anything, "bob", database.RoleReader).Return(int64(1), nil).Once()
+
+		r, w := CreateRequestAndResponse("bob", `{"role":"reader"}`)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"user updated\"}\n", string(body))
+	})
+
+	t.Run("invalid role", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("bob", `{"role":"owner"}`)
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"invalid role\"}\n", string(body))
+	})
+
+	t.Run("own user", func(t *testing.T) {
+		r, w := CreateRequestAndResponse("admin", `{"role":"reader"}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db.On("SetUserRole", mock.Anything, "nobody", database.RoleWriter).
+			Return(int64(0), fmt.Errorf("postgres.SetUserRole: %w", database.ErrorNotFound)).Once()
+
+		r, w := CreateRequestAndResponse("nobody", `{"role":"writer"}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("SetUserRole", mock.Anything, "bob", database.RoleWriter).Return(int64(0), fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse("bob", `{"role":"writer"}`)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(username string, body string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodPut, "/admin/users/"+username+"/role", strings.NewReader(body))
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("username", username)
+
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Username: "admin", Role: database.RoleAdmin})
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,203 @@
+package streamevents
+
+import (
+	"context"
+	"encoding/json"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+// LastEventIdParam resumes a stream like the Last-Event-ID header, for
+// clients that cannot set headers on the first connection.
+const LastEventIdParam = "last_event_id"
+
+// batchSize bounds the events read from the log at once.
+const batchSize = 100
+
+type Event struct {
+	Id        int64     `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      File      `json:"data"`
+}
+
+type File struct {
+	FileId string `json:"file_id"`
+	Owner  string `json:"owner"`
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	GetLastFileEventId(ctx context.Context) (int64, error)
+	ListFileEvents(ctx context.Context, filter database.FileEventFilter) ([]database.FileEvent, error)
+}
+
+type Broker interface {
+	Subscribe() (<-chan struct{}, func())
+}
+
+// New streams file events as server-sent events, starting after the id in
+// the Last-Event-ID header so that reconnecting clients miss nothing, and
+// with the events from now on otherwise. Callers see the events of their
+// own files, admins those of all files unless they pick an owner with the
+// owner query parameter. Files are not organised in folders, so the owner
+// is the only filter. A comment is sent every heartbeat, after which the
+// log is read again in case a wake up was lost.
+func New(logger *slog.Logger, db Db, broker Broker, heartbeat time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.streamevents.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		owner := r.URL.Query().Get("owner")
+		if !user.IsAdmin() {
+			if owner != "" && owner != user.Username {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, apiresponse.Error("forbidden"))
+				return
+			}
+
+			owner = user.Username
+		}
+
+		lastEventId := r.Header.Get("Last-Event-ID")
+		if lastEventId == "" {
+			lastEventId = r.URL.Query().Get(LastEventIdParam)
+		}
+
+		var after int64
+		var err error
+		if lastEventId != "" {
+			after, err = strconv.ParseInt(lastEventId, 10, 64)
+			if err != nil || after < 0 {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, apiresponse.Error("invalid last event id"))
+				return
+			}
+		}
+
+		// subscribed before the log is read, so that no event falls between
+		// the two
+		wake, unsubscribe := broker.Subscribe()
+		defer unsubscribe()
+
+		if lastEventId == "" {
+			after, err = db.GetLastFileEventId(r.Context())
+			if err != nil {
+				log.Error("failed to get last file event id", slog.Any("error", err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, apiresponse.Error("failed to stream events"))
+				return
+			}
+		}
+
+		rc := http.NewResponseController(w)
+
+		// the write timeout of the server would end the stream
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			log.Warn("failed to clear write deadline", slog.Any("error", err))
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		if err := rc.Flush(); err != nil {
+			log.Error("streaming is not supported", slog.Any("error", err))
+			return
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			events, err := db.ListFileEvents(r.Context(), database.FileEventFilter{
+				AfterId: after,
+				Owner:   owner,
+				Limit:   batchSize,
+			})
+			if err != nil {
+				if r.Context().Err() == nil {
+					log.Error("failed to list file events", slog.Any("error", err))
+				}
+				return
+			}
+
+			for _, event := range events {
+				if err := writeEvent(w, &event); err != nil {
+					return
+				}
+
+				after = event.Id
+			}
+
+			if len(events) > 0 {
+				if err := rc.Flush(); err != nil {
+					return
+				}
+			}
+
+			if len(events) == batchSize {
+				continue
+			}
+
+			select {
+			case <-r.Context().Done():
+				return
+			case _, ok := <-wake:
+				if !ok {
+					// shutting down, clients reconnect to another replica
+					return
+				}
+			case <-ticker.C:
+				if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				if err := rc.Flush(); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func writeEvent(w io.Writer, event *database.FileEvent) error {
+	data, err := json.Marshal(Event{
+		Id:        event.Id,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		Data: File{
+			FileId: event.FilePublicId,
+			Owner:  event.Owner,
+			Name:   event.Name,
+			Size:   event.Size,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+
+	return err
+}
@@ -0,0 +1,188 @@
+package streamevents_test
+
+import (
+	"context"
+	"file-service/m/internal/database"
+	"file-service/m/internal/events"
+	streamevents "file-service/m/internal/handlers/streamEvents"
+	"file-service/m/internal/handlers/streamEvents/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const publicId = "0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f"
+
+func TestStreamEventsHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+
+	event := func(id int64, eventType string) database.FileEvent {
+		return database.FileEvent{
+			Id:           id,
+			Type:         eventType,
+			FileId:       7,
+			FilePublicId: publicId,
+			Owner:        "alice",
+			Name:         "a.txt",
+			Size:         4,
+			CreatedAt:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		}
+	}
+
+	t.Run("resume after last event id", func(t *testing.T) {
+		db := mocks.NewDb(t)
+		handler := streamevents.New(log, db, events.NewBroker(), time.Hour)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		db.On("ListFileEvents", mock.Anything, database.FileEventFilter{AfterId: 3, Owner: "alice", Limit: 100}).
+			Run(func(args mock.Arguments) { cancel() }).
+			Return([]database.FileEvent{event(4, database.FileEventCreated), event(5, database.FileEventTrashed)}, nil).Once()
+
+		r, w := CreateRequestAndResponse(ctx, "/events", false)
+		r.Header.Set("Last-Event-ID", "3")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Equal(t, "text/event-stream", w.Result().Header.Get("Content-Type"))
+		assert.Equal(t, "id: 4\nevent: file.created\ndata: {\"id\":4,\"type\":\"file.created\","+
+			"\"created_at\":\"2024-01-02T03:04:05Z\",\"data\":{\"file_id\":\""+publicId+"\",\"owner\":\"alice\","+
+			"\"name\":\"a.txt\",\"size\":4}}\n\n"+
+			"id: 5\nevent: file.trashed\ndata: {\"id\":5,\"type\":\"file.trashed\","+
+			"\"created_at\":\"2024-01-02T03:04:05Z\",\"data\":{\"file_id\":\""+publicId+"\",\"owner\":\"alice\","+
+			"\"name\":\"a.txt\",\"size\":4}}\n\n", w.Body.String())
+	})
+
+	t.Run("new events wake the stream", func(t *testing.T) {
+		db := mocks.NewDb(t)
+		broker := events.NewBroker()
+		handler := streamevents.New(log, db, broker, time.Hour)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		db.On("GetLastFileEventId", mock.Anything).Return(int64(8), nil).Once()
+		db.On("ListFileEvents", mock.Anything, database.FileEventFilter{AfterId: 8, Owner: "", Limit: 100}).
+			Run(func(args mock.Arguments) { broker.Publish() }).
+			Return([]database.FileEvent{}, nil).Once()
+		db.On("ListFileEvents", mock.Anything, database.FileEventFilter{AfterId: 8, Owner: "", Limit: 100}).
+			Run(func(args mock.Arguments) { cancel() }).
+			Return([]database.FileEvent{event(9, database.FileEventDeleted)}, nil).Once()
+
+		r, w := CreateRequestAndResponse(ctx, "/events", true)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Contains(t, w.Body.String(), "id: 9\nevent: file.deleted\n")
+	})
+
+	t.Run("heartbeat", func(t *testing.T) {
+		db := mocks.NewDb(t)
+		handler := streamevents.New(log, db, events.NewBroker(), time.Millisecond)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		db.On("ListFileEvents", mock.Anything, mock.Anything).Return([]database.FileEvent{}, nil).Once()
+		// the ticker may fire again before the cancellation is noticed
+		db.On("ListFileEvents", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { cancel() }).
+			Return([]database.FileEvent{}, nil)
+
+		r, w := CreateRequestAndResponse(ctx, "/events?last_event_id=1", false)
+
+		handler.ServeHTTP(w, r)
+
+		assert.True(t, strings.HasPrefix(w.Body.String(), ": heartbeat\n\n"))
+	})
+
+	t.Run("broker closed", func(t *testing.T) {
+		db := mocks.NewDb(t)
+		broker := events.NewBroker()
+		handler := streamevents.New(log, db, broker, time.Hour)
+
+		db.On("ListFileEvents", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { broker.Close() }).
+			Return([]database.FileEvent{}, nil).Once()
+
+		r, w := CreateRequestAndResponse(context.Background(), "/events?last_event_id=1", false)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+
+	t.Run("admin picks owner", func(t *testing.T) {
+		db := mocks.NewDb(t)
+		handler := streamevents.New(log, db, events.NewBroker(), time.Hour)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		db.On("ListFileEvents", mock.Anything, database.FileEventFilter{AfterId: 1, Owner: "bob", Limit: 100}).
+			Run(func(args mock.Arguments) { cancel() }).
+			Return([]database.FileEvent{}, nil).Once()
+
+		r, w := CreateRequestAndResponse(ctx, "/events?owner=bob&last_event_id=1", true)
+
+		handler.ServeHTTP(w, r)
+	})
+
+	t.Run("other owner", func(t *testing.T) {
+		handler := streamevents.New(log, mocks.NewDb(t), events.NewBroker(), time.Hour)
+
+		r, w := CreateRequestAndResponse(context.Background(), "/events?owner=bob", false)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("invalid last event id", func(t *testing.T) {
+		handler := streamevents.New(log, mocks.NewDb(t), events.NewBroker(), time.Hour)
+
+		r, w := CreateRequestAndResponse(context.Background(), "/events", false)
+		r.Header.Set("Last-Event-ID", "abc")
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db := mocks.NewDb(t)
+		handler := streamevents.New(log, db, events.NewBroker(), time.Hour)
+
+		db.On("GetLastFileEventId", mock.Anything).Return(int64(0), fmt.Errorf("error")).Once()
+
+		r, w := CreateRequestAndResponse(context.Background(), "/events", false)
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func CreateRequestAndResponse(ctx context.Context, target string, isAdmin bool) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	role := database.RoleReader
+	if isAdmin {
+		role = database.RoleAdmin
+	}
+	ctx = requestctx.WithRequestId(ctx, "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Id: 7, Username: "alice", Role: role})
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
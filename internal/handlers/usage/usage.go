@@ -0,0 +1,58 @@
+package usage
+
+import (
+	"context"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	apiresponse.ApiResponse
+	UsedBytes int64 `json:"used_bytes"`
+	UsedFiles int64 `json:"used_files"`
+	MaxBytes  int64 `json:"max_bytes"`
+	MaxFiles  int64 `json:"max_files"`
+}
+
+//go:generate mockery --name=Db
+type Db interface {
+	GetUsage(ctx context.Context, owner string) (*database.Usage, error)
+}
+
+func New(logger *slog.Logger, db Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.usage.New"
+
+		log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+		user, ok := requestctx.User(r.Context())
+		if !ok {
+			log.Error("user is not authenticated")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, apiresponse.Error("unauthorized"))
+			return
+		}
+
+		usage, err := db.GetUsage(r.Context(), user.Username)
+		if err != nil {
+			log.Error("failed to get usage", slog.Any("error", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, apiresponse.Error("failed to get usage"))
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			ApiResponse: apiresponse.Success("usage"),
+			UsedBytes:   usage.UsedBytes,
+			UsedFiles:   usage.UsedFiles,
+			MaxBytes:    usage.MaxBytes,
+			MaxFiles:    usage.MaxFiles,
+		})
+	}
+}
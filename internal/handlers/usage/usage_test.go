@@ -0,0 +1,68 @@
+package usage_test
+
+import (
+	"file-service/m/internal/database"
+	"file-service/m/internal/handlers/usage"
+	"file-service/m/internal/handlers/usage/mocks"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUsageHandler(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	errorResp := fmt.Errorf("error")
+	handler := usage.New(log, db)
+
+	t.Run("success", func(t *testing.T) {
+		db.On("GetUsage", mock.Anything, "alice").Return(&database.Usage{
+			Quota:     database.Quota{MaxBytes: 100, MaxFiles: 10},
+			UsedBytes: 40,
+			UsedFiles: 2,
+		}, nil).Once()
+
+		r, w := CreateRequestAndResponse("alice")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+		bodyResp := string(body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"success\",\"message\":\"usage\",\"used_bytes\":40,\"used_files\":2,\"max_bytes\":100,\"max_files\":10}\n", bodyResp)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("GetUsage", mock.Anything, "alice").Return(nil, errorResp).Once()
+
+		r, w := CreateRequestAndResponse("alice")
+
+		handler.ServeHTTP(w, r)
+
+		resp := w.Result()
+		body, _ := io.ReadAll(resp.Body)
+		bodyResp := string(body)
+
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, "{\"status\":\"error\",\"message\":\"failed to get usage\"}\n", bodyResp)
+	})
+}
+
+func CreateRequestAndResponse(owner string) (*http.Request, *httptest.ResponseRecorder) {
+	r := httptest.NewRequest(http.MethodGet, "/usage", nil)
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = requestctx.WithUser(ctx, &database.User{Username: owner})
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	return r, w
+}
@@ -0,0 +1,34 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var errorUnsupported = errors.New("disk space is not supported on this platform")
+
+// DiskSpace checks that the file system of path has at least minFreeBytes
+// and minFreePercent of its size available. Zero disables a threshold. On
+// platforms where the free space cannot be determined the check passes.
+func DiskSpace(path string, minFreeBytes int64, minFreePercent float64) Check {
+	return func(ctx context.Context) error {
+		free, total, err := diskSpace(path)
+		if errors.Is(err, errorUnsupported) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if minFreeBytes > 0 && free < uint64(minFreeBytes) {
+			return fmt.Errorf("%d bytes free, need %d", free, minFreeBytes)
+		}
+
+		if minFreePercent > 0 && total > 0 && float64(free)/float64(total)*100 < minFreePercent {
+			return fmt.Errorf("%.1f%% free, need %.1f%%", float64(free)/float64(total)*100, minFreePercent)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+
+package health
+
+func diskSpace(path string) (uint64, uint64, error) {
+	return 0, 0, errorUnsupported
+}
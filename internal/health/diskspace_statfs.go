@@ -0,0 +1,16 @@
+//go:build linux || darwin
+
+package health
+
+import "syscall"
+
+// diskSpace returns the bytes available to unprivileged users and the size
+// of the file system of path.
+func diskSpace(path string) (free uint64, total uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), uint64(stat.Blocks) * uint64(stat.Bsize), nil
+}
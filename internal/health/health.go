@@ -0,0 +1,107 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrorShuttingDown = errors.New("shutting down")
+
+// Check reports whether a dependency of the service is usable.
+type Check func(ctx context.Context) error
+
+// Checker runs the readiness checks. Once shutdown has begun it reports not
+// ready without running them, so that load balancers stop sending requests
+// while the open ones are drained.
+type Checker struct {
+	timeout      time.Duration
+	names        []string
+	checks       map[string]Check
+	shuttingDown atomic.Bool
+}
+
+func New(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  map[string]Check{},
+	}
+}
+
+// Add registers a check under name. It is not safe to call concurrently
+// with Check.
+func (c *Checker) Add(name string, check Check) {
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+		sort.Strings(c.names)
+	}
+
+	c.checks[name] = check
+}
+
+// SetShuttingDown makes every following Check report not ready.
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Check runs all checks concurrently within the timeout and returns
+// whether all passed, together with the result of each.
+func (c *Checker) Check(ctx context.Context) (bool, map[string]error) {
+	if c.shuttingDown.Load() {
+		return false, map[string]error{"shutdown": ErrorShuttingDown}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	results := make(map[string]error, len(c.names))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range c.names {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			err := check(ctx)
+
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+		}(name, c.checks[name])
+	}
+	wg.Wait()
+
+	ready := true
+	for _, err := range results {
+		if err != nil {
+			ready = false
+		}
+	}
+
+	return ready, results
+}
+
+// DirWritable checks that files can be created in dir by writing and
+// removing a probe file.
+func DirWritable(dir string) Check {
+	return func(ctx context.Context) error {
+		probe, err := os.CreateTemp(dir, ".probe-*")
+		if err != nil {
+			return err
+		}
+
+		defer os.Remove(probe.Name())
+
+		if _, err := probe.Write([]byte("ok")); err != nil {
+			probe.Close()
+			return err
+		}
+
+		return probe.Close()
+	}
+}
@@ -0,0 +1,72 @@
+package health_test
+
+import (
+	"context"
+	"file-service/m/internal/health"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker(t *testing.T) {
+	checker := health.New(50 * time.Millisecond)
+	checker.Add("ok", func(ctx context.Context) error { return nil })
+
+	ready, results := checker.Check(context.Background())
+	assert.True(t, ready)
+	assert.Equal(t, map[string]error{"ok": nil}, results)
+
+	checker.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ready, results = checker.Check(context.Background())
+	assert.False(t, ready)
+	assert.NoError(t, results["ok"])
+	assert.ErrorIs(t, results["slow"], context.DeadlineExceeded)
+
+	checker.SetShuttingDown()
+
+	ready, results = checker.Check(context.Background())
+	assert.False(t, ready)
+	assert.Equal(t, map[string]error{"shutdown": health.ErrorShuttingDown}, results)
+}
+
+func TestDirWritable(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, health.DirWritable(dir)(context.Background()))
+
+	// the probe file is removed again
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	assert.Error(t, health.DirWritable(filepath.Join(dir, "missing"))(context.Background()))
+
+	if os.Getuid() != 0 {
+		require.NoError(t, os.Chmod(dir, 0o500))
+		assert.Error(t, health.DirWritable(dir)(context.Background()))
+	}
+}
+
+func TestDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.NoError(t, health.DiskSpace(dir, 0, 0)(context.Background()))
+	assert.NoError(t, health.DiskSpace(dir, 1, 0)(context.Background()))
+
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("free space is not determined on " + runtime.GOOS)
+	}
+
+	assert.Error(t, health.DiskSpace(dir, 1<<62, 0)(context.Background()))
+	assert.Error(t, health.DiskSpace(dir, 0, 100.1)(context.Background()))
+	assert.Error(t, health.DiskSpace(filepath.Join(dir, "missing"), 1, 0)(context.Background()))
+}
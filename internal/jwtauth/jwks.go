@@ -0,0 +1,276 @@
+package jwtauth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// minRefreshInterval limits how often an unknown key id triggers a
+	// refresh, so that tokens with made up key ids cannot hammer the JWKS
+	// endpoint.
+	minRefreshInterval = time.Minute
+	maxJWKSSize        = 1 << 20
+)
+
+var ErrorUnknownKey = errors.New("unknown signing key")
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// keySet caches the public keys of a JWKS document by key id.
+type keySet struct {
+	load            func() ([]byte, error)
+	refreshInterval time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	// failedAt and err describe the last failed load, which holds off the
+	// next one for minRefreshInterval
+	failedAt time.Time
+	err      error
+	// refreshing is closed when the load in progress, if any, is done
+	refreshing chan struct{}
+}
+
+func newFileKeySet(path string, refreshInterval time.Duration) *keySet {
+	return &keySet{
+		load: func() ([]byte, error) {
+			return os.ReadFile(path)
+		},
+		refreshInterval: refreshInterval,
+	}
+}
+
+func newURLKeySet(url string, refreshInterval time.Duration) *keySet {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	return &keySet{
+		load: func() ([]byte, error) {
+			resp, err := client.Get(url)
+			if err != nil {
+				return nil, err
+			}
+
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+			}
+
+			return io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+		},
+		refreshInterval: refreshInterval,
+	}
+}
+
+// get returns the key with the given id. The set is reloaded when it is
+// older than the refresh interval, or when the id is unknown and the last
+// load was not too recent. Only one load runs at a time, without holding
+// the lock, and callers whose key is cached keep getting it meanwhile.
+func (s *keySet) get(kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[kid]
+
+	if s.due(ok) {
+		if s.refreshing == nil {
+			s.refresh()
+		} else if !ok {
+			done := s.refreshing
+			s.mu.Unlock()
+			<-done
+			s.mu.Lock()
+		}
+
+		key, ok = s.keys[kid]
+	}
+
+	if !ok {
+		// keep serving the keys we have while the source is unavailable
+		if s.keys == nil && s.err != nil {
+			return nil, s.err
+		}
+
+		return nil, fmt.Errorf("%w: %q", ErrorUnknownKey, kid)
+	}
+
+	return key, nil
+}
+
+// due reports whether the set should be reloaded. It must be called with
+// the lock held.
+func (s *keySet) due(known bool) bool {
+	if time.Since(s.failedAt) < minRefreshInterval {
+		return false
+	}
+
+	age := time.Since(s.fetchedAt)
+
+	return s.keys == nil || age >= s.refreshInterval || (!known && age >= minRefreshInterval)
+}
+
+// refresh reloads the set. It must be called with the lock held, which it
+// releases while loading.
+func (s *keySet) refresh() {
+	done := make(chan struct{})
+	s.refreshing = done
+
+	s.mu.Unlock()
+	keys, err := s.fetch()
+	s.mu.Lock()
+
+	s.refreshing = nil
+	close(done)
+
+	if err != nil {
+		s.failedAt = time.Now()
+		s.err = err
+		return
+	}
+
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.err = nil
+}
+
+func (s *keySet) fetch() (map[string]crypto.PublicKey, error) {
+	data, err := s.load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load jwks: %w", err)
+	}
+
+	return parseJWKS(data)
+}
+
+// parseJWKS returns the RSA and EC signing keys of a JWKS document. Keys of
+// other types or for encryption are skipped.
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		var err error
+
+		switch k.Kty {
+		case "RSA":
+			key, err = parseRSAKey(k)
+		case "EC":
+			key, err = parseECKey(k)
+		default:
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse jwk %q: %w", k.Kid, err)
+		}
+
+		keys[k.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func parseRSAKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("invalid rsa exponent")
+	}
+
+	key := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}
+
+	if key.N.BitLen() < 2048 {
+		return nil, errors.New("rsa key too small")
+	}
+
+	return key, nil
+}
+
+func parseECKey(k jwk) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	var validate ecdh.Curve
+
+	switch k.Crv {
+	case "P-256":
+		curve, validate = elliptic.P256(), ecdh.P256()
+	case "P-384":
+		curve, validate = elliptic.P384(), ecdh.P384()
+	case "P-521":
+		curve, validate = elliptic.P521(), ecdh.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+	if len(x) != size || len(y) != size {
+		return nil, errors.New("invalid ec point")
+	}
+
+	// rejects points that are not on the curve
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := validate.NewPublicKey(point); err != nil {
+		return nil, err
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}, nil
+}
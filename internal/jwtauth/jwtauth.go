@@ -0,0 +1,131 @@
+package jwtauth
+
+import (
+	"errors"
+	"file-service/m/internal/config"
+	"file-service/m/internal/database"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const leeway = 30 * time.Second
+
+var ErrorInvalidToken = errors.New("invalid token")
+
+// Claims is what the service takes from a verified token.
+type Claims struct {
+	Username string
+	Roles    []string
+	Role     string
+}
+
+// Verifier checks RS256 and ES256 tokens against the keys of a JWKS.
+type Verifier struct {
+	keys       *keySet
+	parser     *jwt.Parser
+	userClaim  string
+	rolesClaim string
+	roleNames  map[string]string
+}
+
+func New(cfg config.JWTConfig) (*Verifier, error) {
+	var keys *keySet
+
+	switch {
+	case cfg.JWKSFile != "":
+		keys = newFileKeySet(cfg.JWKSFile, cfg.JWKSRefreshInterval)
+	case cfg.JWKSURL != "":
+		keys = newURLKeySet(cfg.JWKSURL, cfg.JWKSRefreshInterval)
+	default:
+		return nil, errors.New("jwks file or url required")
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+	}
+
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &Verifier{
+		keys:       keys,
+		parser:     jwt.NewParser(options...),
+		userClaim:  cfg.UserClaim,
+		rolesClaim: cfg.RolesClaim,
+		roleNames: map[string]string{
+			database.RoleAdmin:  cfg.AdminRole,
+			database.RoleWriter: cfg.WriterRole,
+			database.RoleReader: cfg.ReaderRole,
+		},
+	}, nil
+}
+
+// Verify checks the signature, expiry, issuer and audience of token and
+// maps its claims.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		return v.keys.get(kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrorInvalidToken, err)
+	}
+
+	username, _ := claims[v.userClaim].(string)
+	if username == "" {
+		return nil, fmt.Errorf("%w: missing %q claim", ErrorInvalidToken, v.userClaim)
+	}
+
+	roles := rolesFromClaim(claims[v.rolesClaim])
+
+	return &Claims{
+		Username: username,
+		Roles:    roles,
+		Role:     v.role(roles),
+	}, nil
+}
+
+// role returns the highest service role the token roles map to.
+func (v *Verifier) role(roles []string) string {
+	for _, role := range []string{database.RoleAdmin, database.RoleWriter} {
+		if name := v.roleNames[role]; name != "" && slices.Contains(roles, name) {
+			return role
+		}
+	}
+
+	return database.RoleReader
+}
+
+// rolesFromClaim accepts a list of strings or a space separated string, as
+// identity providers use both.
+func rolesFromClaim(claim any) []string {
+	switch value := claim.(type) {
+	case string:
+		return strings.Fields(value)
+	case []any:
+		roles := make([]string, 0, len(value))
+		for _, role := range value {
+			if role, ok := role.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+
+		return roles
+	}
+
+	return nil
+}
@@ -0,0 +1,355 @@
+package jwtauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"file-service/m/internal/config"
+	"file-service/m/internal/database"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	return testKeys{rsa: rsaKey, ec: ecKey}
+}
+
+func (k testKeys) jwks(rsaKid string, ecKid string) []byte {
+	b64 := base64.RawURLEncoding.EncodeToString
+	doc := map[string]any{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": rsaKid,
+				"use": "sig",
+				"n":   b64(k.rsa.N.Bytes()),
+				"e":   b64(big.NewInt(int64(k.rsa.E)).Bytes()),
+			},
+			{
+				"kty": "EC",
+				"kid": ecKid,
+				"crv": "P-256",
+				"x":   b64(k.ec.X.FillBytes(make([]byte, 32))),
+				"y":   b64(k.ec.Y.FillBytes(make([]byte, 32))),
+			},
+		},
+	}
+
+	data, _ := json.Marshal(doc)
+
+	return data
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "alice",
+		"iss":   "https://idp.example.com",
+		"aud":   "file-service",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"user", "admin"},
+	}
+}
+
+func newConfig(url string) config.JWTConfig {
+	return config.JWTConfig{
+		JWKSURL:             url,
+		JWKSRefreshInterval: time.Hour,
+		Issuer:              "https://idp.example.com",
+		Audience:            "file-service",
+		UserClaim:           "sub",
+		RolesClaim:          "roles",
+		AdminRole:           "admin",
+		WriterRole:          "writer",
+		ReaderRole:          "reader",
+	}
+}
+
+func TestVerify(t *testing.T) {
+	keys := newTestKeys(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(keys.jwks("rsa-1", "ec-1"))
+	}))
+	defer server.Close()
+
+	verifier, err := New(newConfig(server.URL))
+	require.NoError(t, err)
+
+	t.Run("rs256", func(t *testing.T) {
+		claims, err := verifier.Verify(sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", validClaims()))
+		require.NoError(t, err)
+
+		assert.Equal(t, "alice", claims.Username)
+		assert.Equal(t, []string{"user", "admin"}, claims.Roles)
+		assert.Equal(t, database.RoleAdmin, claims.Role)
+	})
+
+	t.Run("es256", func(t *testing.T) {
+		c := validClaims()
+		c["roles"] = "user writer"
+
+		claims, err := verifier.Verify(sign(t, jwt.SigningMethodES256, keys.ec, "ec-1", c))
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"user", "writer"}, claims.Roles)
+		assert.Equal(t, database.RoleWriter, claims.Role)
+	})
+
+	t.Run("no known role", func(t *testing.T) {
+		c := validClaims()
+		delete(c, "roles")
+
+		claims, err := verifier.Verify(sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", c))
+		require.NoError(t, err)
+
+		assert.Empty(t, claims.Roles)
+		assert.Equal(t, database.RoleReader, claims.Role)
+	})
+
+	otherKeys := newTestKeys(t)
+
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{"expired", func() string {
+			c := validClaims()
+			c["exp"] = time.Now().Add(-time.Hour).Unix()
+			return sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", c)
+		}},
+		{"missing expiry", func() string {
+			c := validClaims()
+			delete(c, "exp")
+			return sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", c)
+		}},
+		{"wrong issuer", func() string {
+			c := validClaims()
+			c["iss"] = "https://evil.example.com"
+			return sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", c)
+		}},
+		{"wrong audience", func() string {
+			c := validClaims()
+			c["aud"] = "other-service"
+			return sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", c)
+		}},
+		{"missing subject", func() string {
+			c := validClaims()
+			delete(c, "sub")
+			return sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", c)
+		}},
+		{"wrong signature", func() string {
+			return sign(t, jwt.SigningMethodRS256, otherKeys.rsa, "rsa-1", validClaims())
+		}},
+		{"key type mismatch", func() string {
+			return sign(t, jwt.SigningMethodES256, keys.ec, "rsa-1", validClaims())
+		}},
+		{"unknown kid", func() string {
+			return sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-2", validClaims())
+		}},
+		{"hs256", func() string {
+			return sign(t, jwt.SigningMethodHS256, []byte("secret"), "rsa-1", validClaims())
+		}},
+		{"none", func() string {
+			return sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "rsa-1", validClaims())
+		}},
+		{"garbage", func() string {
+			return "not.a.token"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(tt.token())
+			assert.ErrorIs(t, err, ErrorInvalidToken)
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	keys := newTestKeys(t)
+	rotated := newTestKeys(t)
+
+	var current atomic.Pointer[[]byte]
+	initial := keys.jwks("rsa-1", "ec-1")
+	current.Store(&initial)
+
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(*current.Load())
+	}))
+	defer server.Close()
+
+	verifier, err := New(newConfig(server.URL))
+	require.NoError(t, err)
+
+	_, err = verifier.Verify(sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", validClaims()))
+	require.NoError(t, err)
+
+	next := rotated.jwks("rsa-2", "ec-2")
+	current.Store(&next)
+
+	token := sign(t, jwt.SigningMethodRS256, rotated.rsa, "rsa-2", validClaims())
+
+	// a fresh key set is not reloaded for unknown key ids
+	_, err = verifier.Verify(token)
+	assert.ErrorIs(t, err, ErrorInvalidToken)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	verifier.keys.fetchedAt = time.Now().Add(-minRefreshInterval)
+
+	_, err = verifier.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestKeySetFailedRefresh(t *testing.T) {
+	keys := newTestKeys(t)
+
+	var fetches atomic.Int32
+	var failing atomic.Bool
+	set := &keySet{
+		load: func() ([]byte, error) {
+			fetches.Add(1)
+			if failing.Load() {
+				return nil, errors.New("unavailable")
+			}
+			return keys.jwks("rsa-1", "ec-1"), nil
+		},
+		refreshInterval: time.Hour,
+	}
+
+	failing.Store(true)
+
+	_, err := set.get("rsa-1")
+	assert.Error(t, err)
+
+	// failed attempts hold off the next one as well
+	_, err = set.get("rsa-1")
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	failing.Store(false)
+	set.failedAt = time.Now().Add(-minRefreshInterval)
+
+	_, err = set.get("rsa-1")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	// a failing source keeps the cached keys in use
+	failing.Store(true)
+	set.fetchedAt = time.Now().Add(-time.Hour)
+
+	_, err = set.get("rsa-1")
+	require.NoError(t, err)
+	_, err = set.get("rsa-1")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), fetches.Load())
+}
+
+func TestKeySetConcurrentRefresh(t *testing.T) {
+	keys := newTestKeys(t)
+
+	var fetches atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	set := &keySet{
+		load: func() ([]byte, error) {
+			if fetches.Add(1) == 2 {
+				close(started)
+				<-release
+			}
+			return keys.jwks("rsa-1", "ec-1"), nil
+		},
+		refreshInterval: time.Hour,
+	}
+
+	_, err := set.get("rsa-1")
+	require.NoError(t, err)
+
+	set.fetchedAt = time.Now().Add(-time.Hour)
+
+	done := make(chan error)
+	go func() {
+		_, err := set.get("rsa-1")
+		done <- err
+	}()
+
+	<-started
+
+	// the slow load neither blocks nor is repeated for cached keys
+	_, err = set.get("ec-1")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	close(release)
+	require.NoError(t, <-done)
+}
+
+func TestJWKSFile(t *testing.T) {
+	keys := newTestKeys(t)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, keys.jwks("rsa-1", "ec-1"), 0o600))
+
+	cfg := newConfig("")
+	cfg.JWKSFile = path
+
+	verifier, err := New(cfg)
+	require.NoError(t, err)
+
+	claims, err := verifier.Verify(sign(t, jwt.SigningMethodES256, keys.ec, "ec-1", validClaims()))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Username)
+}
+
+func TestParseJWKS(t *testing.T) {
+	_, err := parseJWKS([]byte(`{"keys":[{"kty":"EC","kid":"bad","crv":"P-256","x":"AAAA","y":"AAAA"}]}`))
+	assert.Error(t, err)
+
+	zero := base64.RawURLEncoding.EncodeToString(make([]byte, 32))
+	_, err = parseJWKS([]byte(`{"keys":[{"kty":"EC","kid":"bad","crv":"P-256","x":"` + zero + `","y":"` + zero + `"}]}`))
+	assert.Error(t, err)
+
+	keys, err := parseJWKS([]byte(`{"keys":[{"kty":"oct","kid":"sym","k":"c2VjcmV0"},{"kty":"RSA","kid":"enc","use":"enc"}]}`))
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	_, err = New(config.JWTConfig{})
+	assert.Error(t, err)
+}
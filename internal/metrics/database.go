@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"file-service/m/internal/database"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type Db interface {
+	PoolStats() sql.DBStats
+	GetFileStats(ctx context.Context) (*database.FileStats, error)
+}
+
+var (
+	poolOpenDesc = prometheus.NewDesc(
+		namespace+"_db_open_connections", "Established database connections, in use and idle.", nil, nil)
+	poolInUseDesc = prometheus.NewDesc(
+		namespace+"_db_in_use_connections", "Database connections currently in use.", nil, nil)
+	poolIdleDesc = prometheus.NewDesc(
+		namespace+"_db_idle_connections", "Idle database connections.", nil, nil)
+	poolMaxOpenDesc = prometheus.NewDesc(
+		namespace+"_db_max_open_connections", "Maximum number of open database connections.", nil, nil)
+	poolWaitCountDesc = prometheus.NewDesc(
+		namespace+"_db_wait_count_total", "Connections waited for.", nil, nil)
+	poolWaitDurationDesc = prometheus.NewDesc(
+		namespace+"_db_wait_duration_seconds_total", "Time spent waiting for connections.", nil, nil)
+
+	filesDesc = prometheus.NewDesc(
+		namespace+"_files", "Stored files, by whether they are in the trash.", []string{"state"}, nil)
+	fileBytesDesc = prometheus.NewDesc(
+		namespace+"_file_bytes", "Bytes of stored files, by whether they are in the trash.", []string{"state"}, nil)
+)
+
+// DatabaseCollector reports the connection pool and the file totals. The
+// totals are queried on every scrape.
+type DatabaseCollector struct {
+	db Db
+}
+
+func NewDatabaseCollector(db Db) *DatabaseCollector {
+	return &DatabaseCollector{db: db}
+}
+
+func (c *DatabaseCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolOpenDesc
+	ch <- poolInUseDesc
+	ch <- poolIdleDesc
+	ch <- poolMaxOpenDesc
+	ch <- poolWaitCountDesc
+	ch <- poolWaitDurationDesc
+	ch <- filesDesc
+	ch <- fileBytesDesc
+}
+
+func (c *DatabaseCollector) Collect(ch chan<- prometheus.Metric) {
+	pool := c.db.PoolStats()
+
+	ch <- prometheus.MustNewConstMetric(poolOpenDesc, prometheus.GaugeValue, float64(pool.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(poolInUseDesc, prometheus.GaugeValue, float64(pool.InUse))
+	ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(pool.Idle))
+	ch <- prometheus.MustNewConstMetric(poolMaxOpenDesc, prometheus.GaugeValue, float64(pool.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(poolWaitCountDesc, prometheus.CounterValue, float64(pool.WaitCount))
+	ch <- prometheus.MustNewConstMetric(poolWaitDurationDesc, prometheus.CounterValue, pool.WaitDuration.Seconds())
+
+	stats, err := c.db.GetFileStats(context.Background())
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(filesDesc, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(filesDesc, prometheus.GaugeValue, float64(stats.Files), "active")
+	ch <- prometheus.MustNewConstMetric(filesDesc, prometheus.GaugeValue, float64(stats.TrashedFiles), "trash")
+	ch <- prometheus.MustNewConstMetric(fileBytesDesc, prometheus.GaugeValue, float64(stats.Bytes), "active")
+	ch <- prometheus.MustNewConstMetric(fileBytesDesc, prometheus.GaugeValue, float64(stats.TrashedBytes), "trash")
+}
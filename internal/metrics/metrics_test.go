@@ -0,0 +1,76 @@
+package metrics_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"file-service/m/internal/database"
+	"file-service/m/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDb struct {
+	stats *database.FileStats
+	err   error
+}
+
+func (f *fakeDb) PoolStats() sql.DBStats {
+	return sql.DBStats{OpenConnections: 3, InUse: 1, Idle: 2, MaxOpenConnections: 10}
+}
+
+func (f *fakeDb) GetFileStats(_ context.Context) (*database.FileStats, error) {
+	return f.stats, f.err
+}
+
+func TestObserveRequest(t *testing.T) {
+	m := metrics.New()
+
+	m.ObserveRequest("/file/{fileID}/", http.MethodGet, http.StatusOK, 10*time.Millisecond)
+	m.ObserveRequest("/file/{fileID}/", http.MethodGet, http.StatusOK, 20*time.Millisecond)
+	m.ObserveRequest("/file/{fileID}/", http.MethodGet, http.StatusNotFound, time.Millisecond)
+
+	out := scrape(t, m)
+
+	assert.Contains(t, out, `file_service_http_requests_total{method="GET",route="/file/{fileID}/",status="200"} 2`)
+	assert.Contains(t, out, `file_service_http_requests_total{method="GET",route="/file/{fileID}/",status="404"} 1`)
+	assert.Contains(t, out, `file_service_http_request_duration_seconds_count{method="GET",route="/file/{fileID}/",status="200"} 2`)
+}
+
+func TestDatabaseCollector(t *testing.T) {
+	m := metrics.New()
+	db := &fakeDb{stats: &database.FileStats{Files: 4, Bytes: 400, TrashedFiles: 1, TrashedBytes: 50}}
+	require.NoError(t, m.Register(metrics.NewDatabaseCollector(db)))
+
+	out := scrape(t, m)
+
+	assert.Contains(t, out, "file_service_db_open_connections 3")
+	assert.Contains(t, out, "file_service_db_in_use_connections 1")
+	assert.Contains(t, out, "file_service_db_max_open_connections 10")
+	assert.Contains(t, out, `file_service_files{state="active"} 4`)
+	assert.Contains(t, out, `file_service_files{state="trash"} 1`)
+	assert.Contains(t, out, `file_service_file_bytes{state="active"} 400`)
+	assert.Contains(t, out, `file_service_file_bytes{state="trash"} 50`)
+
+	// a failing query leaves out the totals but not the rest
+	db.err = errors.New("connection refused")
+
+	out = scrape(t, m)
+
+	assert.Contains(t, out, "file_service_db_open_connections 3")
+	assert.NotContains(t, out, "file_service_files{")
+}
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	return w.Body.String()
+}
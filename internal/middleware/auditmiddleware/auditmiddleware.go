@@ -0,0 +1,83 @@
+package auditmiddleware
+
+import (
+	"context"
+	"file-service/m/internal/audit"
+	"file-service/m/internal/clientip"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+//go:generate mockery --name=Db
+type Db interface {
+	CreateAuditEvent(ctx context.Context, event database.AuditEvent) error
+}
+
+// New records an audit event of action for every request, whatever its
+// result. It has to run before the middlewares that may reject the request,
+// so that denials, unknown files and invalid signatures are recorded too.
+// The middlewares and handlers inside it add what only they know with
+// audit.SetActor, audit.SetFile and audit.SetBytes.
+func New(logger *slog.Logger, db Db, action string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "middleware.auditmiddleware.New"
+
+			ctx, details := audit.NewContext(r.Context())
+
+			if _, file, ok := requestctx.Share(ctx); ok {
+				details.FileId, details.FilePublicId = file.Id, file.PublicId
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			if details.Actor == "" {
+				details.Actor = actor(ctx)
+			}
+
+			event := database.AuditEvent{
+				Actor:        details.Actor,
+				Action:       action,
+				FileId:       details.FileId,
+				FilePublicId: details.FilePublicId,
+				IP:           clientip.Get(r),
+				UserAgent:    r.UserAgent(),
+				RequestId:    requestctx.RequestId(ctx),
+				Result:       audit.Result(status),
+				Status:       status,
+				Bytes:        details.Bytes,
+			}
+
+			// clients that went away still have to show up in the log
+			if err := db.CreateAuditEvent(context.WithoutCancel(ctx), event); err != nil {
+				log := requestctx.Logger(ctx, logger).With(slog.String("op", op))
+				log.Error("failed to record audit event", slog.String("action", action), slog.Any("error", err))
+			}
+		})
+	}
+}
+
+// actor names the caller, share links by the share they were made with.
+func actor(ctx context.Context) string {
+	if user, ok := requestctx.User(ctx); ok {
+		return user.Username
+	}
+
+	if share, _, ok := requestctx.Share(ctx); ok {
+		return "share:" + strconv.FormatInt(share.Id, 10)
+	}
+
+	return audit.ActorAnonymous
+}
@@ -0,0 +1,130 @@
+package auditmiddleware_test
+
+import (
+	"context"
+	"errors"
+	"file-service/m/internal/audit"
+	"file-service/m/internal/database"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/middleware/auditmiddleware"
+	"file-service/m/internal/middleware/auditmiddleware/mocks"
+	"file-service/m/internal/requestctx"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const publicId = "0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f"
+
+func TestAudit(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+
+	t.Run("download", func(t *testing.T) {
+		handler := auditmiddleware.New(log, db, audit.ActionDownload)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			audit.SetFile(r.Context(), 7, publicId)
+			audit.SetBytes(r.Context(), 4)
+			w.Write([]byte("data"))
+		}))
+
+		db.On("CreateAuditEvent", mock.Anything, database.AuditEvent{
+			Actor:        "alice",
+			Action:       audit.ActionDownload,
+			FileId:       7,
+			FilePublicId: publicId,
+			IP:           "192.0.2.1",
+			UserAgent:    "test",
+			RequestId:    "123",
+			Result:       audit.ResultSuccess,
+			Status:       http.StatusOK,
+			Bytes:        4,
+		}).Return(nil).Once()
+
+		r := newRequest()
+		r = r.WithContext(requestctx.WithUser(r.Context(), &database.User{Username: "alice"}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	})
+
+	t.Run("denied before the handler", func(t *testing.T) {
+		handler := auditmiddleware.New(log, db, audit.ActionDelete)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// as resolved by the file id middleware
+			audit.SetFile(r.Context(), 7, publicId)
+			w.WriteHeader(http.StatusForbidden)
+		}))
+
+		db.On("CreateAuditEvent", mock.Anything, mock.MatchedBy(func(event database.AuditEvent) bool {
+			return event.Actor == "mallory" && event.FileId == 7 && event.FilePublicId == publicId &&
+				event.Result == audit.ResultDenied && event.Status == http.StatusForbidden
+		})).Return(nil).Once()
+
+		r := newRequest()
+		ctx := requestctx.WithUser(r.Context(), &database.User{Username: "mallory"})
+
+		handler.ServeHTTP(httptest.NewRecorder(), r.WithContext(ctx))
+	})
+
+	t.Run("caller authenticated inside", func(t *testing.T) {
+		handler := auditmiddleware.New(log, db, audit.ActionUpload)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// as authenticated by a signed upload URL
+			audit.SetActor(r.Context(), "alice")
+		}))
+
+		db.On("CreateAuditEvent", mock.Anything, mock.MatchedBy(func(event database.AuditEvent) bool {
+			return event.Actor == "alice" && event.Result == audit.ResultSuccess
+		})).Return(nil).Once()
+
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest())
+	})
+
+	t.Run("share download", func(t *testing.T) {
+		handler := auditmiddleware.New(log, db, audit.ActionDownload)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		db.On("CreateAuditEvent", mock.Anything, mock.MatchedBy(func(event database.AuditEvent) bool {
+			return event.Actor == "share:3" && event.FileId == 7 && event.FilePublicId == publicId
+		})).Return(nil).Once()
+
+		r := newRequest()
+		ctx := requestctx.WithShare(r.Context(), &database.Share{Id: 3}, &database.File{Id: 7, PublicId: publicId})
+
+		handler.ServeHTTP(httptest.NewRecorder(), r.WithContext(ctx))
+	})
+
+	t.Run("recorded after the client went away", func(t *testing.T) {
+		handler := auditmiddleware.New(log, db, audit.ActionUpload)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		db.On("CreateAuditEvent", mock.MatchedBy(func(ctx context.Context) bool {
+			return ctx.Err() == nil
+		}), mock.MatchedBy(func(event database.AuditEvent) bool {
+			return event.Actor == audit.ActorAnonymous
+		})).Return(nil).Once()
+
+		r := newRequest()
+		ctx, cancel := context.WithCancel(r.Context())
+		cancel()
+
+		handler.ServeHTTP(httptest.NewRecorder(), r.WithContext(ctx))
+	})
+
+	t.Run("failure to record does not fail the request", func(t *testing.T) {
+		handler := auditmiddleware.New(log, db, audit.ActionTrash)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		db.On("CreateAuditEvent", mock.Anything, mock.Anything).Return(errors.New("error")).Once()
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, newRequest())
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+}
+
+func newRequest() *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("User-Agent", "test")
+
+	return r.WithContext(requestctx.WithRequestId(r.Context(), "123"))
+}
@@ -0,0 +1,257 @@
+package authmiddleware
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/apikey"
+	"file-service/m/internal/auth"
+	"file-service/m/internal/database"
+	"file-service/m/internal/jwtauth"
+	"file-service/m/internal/policy"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+const realm = "file-service"
+
+var errorUnauthorized = errors.New("unauthorized")
+
+//go:generate mockery --name=Db
+type Db interface {
+	GetUserByUsername(ctx context.Context, username string) (*database.User, error)
+	CreateUser(ctx context.Context, user database.User) (int64, error)
+	GetApiKey(ctx context.Context, prefix string) (*database.ApiKey, *database.User, error)
+	TouchApiKey(ctx context.Context, id int64) error
+}
+
+//go:generate mockery --name=TokenVerifier
+type TokenVerifier interface {
+	Verify(token string) (*jwtauth.Claims, error)
+}
+
+// New authenticates the caller with an API key sent as a bearer token, and
+// otherwise with HTTP basic auth against the users table or, when verifier
+// is set, with a JWT bearer token. It stores the *database.User in the
+// request context, see requestctx.User, and for API keys the granted scopes,
+// see requestctx.Scopes. The user is added to the request logger.
+func New(logger *slog.Logger, db Db, verifier TokenVerifier) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "middleware.authmiddleware.New"
+
+			log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+			ctx := r.Context()
+
+			token, isBearer := bearerToken(r)
+
+			if isBearer && apikey.IsKey(token) {
+				key, user, err := authenticateKey(r.Context(), db, token)
+				if err != nil && !errors.Is(err, errorUnauthorized) {
+					log.Error("failed to get api key", slog.Any("error", err))
+					internalError(w, r)
+					return
+				}
+				if err != nil {
+					log.Info("api key authentication failed")
+					unauthorized(w, r)
+					return
+				}
+
+				if err := db.TouchApiKey(r.Context(), key.Id); err != nil {
+					log.Warn("failed to record api key use", slog.Any("error", err))
+				}
+
+				ctx = requestctx.WithUser(ctx, user)
+				ctx = requestctx.WithScopes(ctx, key.Scopes)
+				ctx = requestctx.WithLogAttrs(ctx, logger, slog.String("username", user.Username), slog.Int64("key_id", key.Id))
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			if verifier != nil {
+				if !isBearer {
+					unauthorizedBearer(w, r)
+					return
+				}
+
+				user, err := authenticateToken(r.Context(), db, verifier, token)
+				if err != nil && !errors.Is(err, errorUnauthorized) {
+					log.Error("failed to get token user", slog.Any("error", err))
+					internalError(w, r)
+					return
+				}
+				if err != nil {
+					log.Info("token authentication failed")
+					unauthorizedBearer(w, r)
+					return
+				}
+
+				ctx = requestctx.WithUser(ctx, user)
+				ctx = requestctx.WithLogAttrs(ctx, logger, slog.String("username", user.Username))
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			username, password, ok := r.BasicAuth()
+			if !ok {
+				unauthorized(w, r)
+				return
+			}
+
+			user, err := db.GetUserByUsername(r.Context(), username)
+			if err != nil && !errors.Is(err, database.ErrorNotFound) {
+				log.Error("failed to get user", slog.Any("error", err))
+				internalError(w, r)
+				return
+			}
+
+			hash := ""
+			if user != nil {
+				hash = user.PasswordHash
+			}
+
+			if !auth.CheckPassword(hash, password) || user.IsDisabled {
+				log.Info("authentication failed", slog.String("username", username))
+				unauthorized(w, r)
+				return
+			}
+
+			ctx = requestctx.WithUser(ctx, user)
+			ctx = requestctx.WithLogAttrs(ctx, logger, slog.String("username", user.Username))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireScope rejects API keys that were not granted scope. Callers
+// authenticated with a password are not limited by scopes. It must run
+// after New.
+func RequireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !hasScope(r, scope) {
+				forbidden(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Authorize rejects callers whose role may not perform action according to
+// the policy. It must run after New.
+func Authorize(p *policy.Policy, action policy.Action) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := requestctx.User(r.Context())
+			if !ok || !p.Allows(user.Role, action) {
+				forbidden(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func authenticateKey(ctx context.Context, db Db, token string) (*database.ApiKey, *database.User, error) {
+	prefix, err := apikey.Prefix(token)
+	if err != nil {
+		return nil, nil, errorUnauthorized
+	}
+
+	key, user, err := db.GetApiKey(ctx, prefix)
+	if errors.Is(err, database.ErrorNotFound) {
+		return nil, nil, errorUnauthorized
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !apikey.Matches(token, key.Hash) || key.Expired(time.Now()) || user.IsDisabled {
+		return nil, nil, errorUnauthorized
+	}
+
+	return key, user, nil
+}
+
+// authenticateToken verifies a JWT and returns the user it was issued for.
+// Users are created on their first request so that files, quotas and API
+// keys can refer to them, and they can be disabled like any other user. The
+// role always follows the roles of the token.
+func authenticateToken(ctx context.Context, db Db, verifier TokenVerifier, token string) (*database.User, error) {
+	claims, err := verifier.Verify(token)
+	if err != nil {
+		return nil, errorUnauthorized
+	}
+
+	user, err := db.GetUserByUsername(ctx, claims.Username)
+	if errors.Is(err, database.ErrorNotFound) {
+		user = &database.User{Username: claims.Username, Role: claims.Role}
+		user.Id, err = db.CreateUser(ctx, *user)
+		if errors.Is(err, database.ErrorAlreadyExists) {
+			// created by a concurrent request
+			user, err = db.GetUserByUsername(ctx, claims.Username)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsDisabled {
+		return nil, errorUnauthorized
+	}
+
+	user.Role = claims.Role
+
+	return user, nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
+
+func hasScope(r *http.Request, scope string) bool {
+	scopes, ok := requestctx.Scopes(r.Context())
+	if !ok {
+		return true
+	}
+
+	return slices.Contains(scopes, scope)
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`"`)
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, apiresponse.Error("unauthorized"))
+}
+
+func unauthorizedBearer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="`+realm+`"`)
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, apiresponse.Error("unauthorized"))
+}
+
+func forbidden(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusForbidden)
+	render.JSON(w, r, apiresponse.Error("forbidden"))
+}
+
+func internalError(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusInternalServerError)
+	render.JSON(w, r, apiresponse.Error("internal error"))
+}
@@ -0,0 +1,326 @@
+package authmiddleware_test
+
+import (
+	"file-service/m/internal/apikey"
+	"file-service/m/internal/auth"
+	"file-service/m/internal/config"
+	"file-service/m/internal/database"
+	"file-service/m/internal/jwtauth"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/middleware/authmiddleware"
+	"file-service/m/internal/middleware/authmiddleware/mocks"
+	"file-service/m/internal/policy"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+
+	hash, err := auth.HashPassword("password123")
+	require.NoError(t, err)
+
+	var got *database.User
+	handler := authmiddleware.New(log, db, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = requestctx.User(r.Context())
+	}))
+
+	t.Run("success", func(t *testing.T) {
+		got = nil
+		db.On("GetUserByUsername", mock.Anything, "alice").Return(&database.User{Id: 1, Username: "alice", PasswordHash: hash}, nil).Once()
+
+		w := serve(handler, "alice", "password123")
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.NotNil(t, got)
+		assert.Equal(t, "alice", got.Username)
+	})
+
+	t.Run("no credentials", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r = r.WithContext(requestctx.WithRequestId(r.Context(), "123"))
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+		assert.Equal(t, `Basic realm="file-service"`, w.Result().Header.Get("WWW-Authenticate"))
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		db.On("GetUserByUsername", mock.Anything, "alice").Return(&database.User{Username: "alice", PasswordHash: hash}, nil).Once()
+
+		w := serve(handler, "alice", "password124")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		db.On("GetUserByUsername", mock.Anything, "mallory").
+			Return(nil, fmt.Errorf("postgres.GetUserByUsername: %w", database.ErrorNotFound)).Once()
+
+		w := serve(handler, "mallory", "password123")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("disabled user", func(t *testing.T) {
+		db.On("GetUserByUsername", mock.Anything, "alice").
+			Return(&database.User{Username: "alice", PasswordHash: hash, IsDisabled: true}, nil).Once()
+
+		w := serve(handler, "alice", "password123")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("GetUserByUsername", mock.Anything, "alice").Return(nil, fmt.Errorf("error")).Once()
+
+		w := serve(handler, "alice", "password123")
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func TestAuthMiddlewareApiKey(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+
+	token, prefix, hash, err := apikey.Generate()
+	require.NoError(t, err)
+
+	var got *database.User
+	var scopes []string
+	handler := authmiddleware.New(log, db, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = requestctx.User(r.Context())
+		scopes, _ = requestctx.Scopes(r.Context())
+	}))
+
+	user := &database.User{Id: 1, Username: "ci"}
+	key := &database.ApiKey{Id: 5, UserId: 1, Prefix: prefix, Hash: hash, Scopes: []string{apikey.ScopeFileRead}}
+
+	t.Run("success", func(t *testing.T) {
+		db.On("GetApiKey", mock.Anything, prefix).Return(key, user, nil).Once()
+		db.On("TouchApiKey", mock.Anything, int64(5)).Return(nil).Once()
+
+		w := serveBearer(handler, token)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.NotNil(t, got)
+		assert.Equal(t, "ci", got.Username)
+		assert.Equal(t, []string{apikey.ScopeFileRead}, scopes)
+	})
+
+	t.Run("malformed key", func(t *testing.T) {
+		w := serveBearer(handler, "not-a-key")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		db.On("GetApiKey", mock.Anything, prefix).Return(key, user, nil).Once()
+
+		forged := token[:len(token)-1] + "0"
+		if forged == token {
+			forged = token[:len(token)-1] + "1"
+		}
+		w := serveBearer(handler, forged)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		db.On("GetApiKey", mock.Anything, prefix).Return(nil, nil, fmt.Errorf("postgres.GetApiKey: %w", database.ErrorNotFound)).Once()
+
+		w := serveBearer(handler, token)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("expired key", func(t *testing.T) {
+		expired := *key
+		expiresAt := time.Now().Add(-time.Minute)
+		expired.ExpiresAt = &expiresAt
+		db.On("GetApiKey", mock.Anything, prefix).Return(&expired, user, nil).Once()
+
+		w := serveBearer(handler, token)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("disabled user", func(t *testing.T) {
+		db.On("GetApiKey", mock.Anything, prefix).Return(key, &database.User{Id: 1, Username: "ci", IsDisabled: true}, nil).Once()
+
+		w := serveBearer(handler, token)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("GetApiKey", mock.Anything, prefix).Return(nil, nil, fmt.Errorf("error")).Once()
+
+		w := serveBearer(handler, token)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func TestAuthMiddlewareJWT(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	verifier := mocks.NewTokenVerifier(t)
+
+	var got *database.User
+	handler := authmiddleware.New(log, db, verifier)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = requestctx.User(r.Context())
+	}))
+
+	t.Run("known user", func(t *testing.T) {
+		got = nil
+		verifier.On("Verify", "token").Return(&jwtauth.Claims{Username: "alice", Role: database.RoleAdmin}, nil).Once()
+		db.On("GetUserByUsername", mock.Anything, "alice").Return(&database.User{Id: 3, Username: "alice"}, nil).Once()
+
+		w := serveBearer(handler, "token")
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.NotNil(t, got)
+		assert.Equal(t, int64(3), got.Id)
+		assert.Equal(t, database.RoleAdmin, got.Role)
+	})
+
+	t.Run("first request", func(t *testing.T) {
+		got = nil
+		verifier.On("Verify", "token").Return(&jwtauth.Claims{Username: "bob", Role: database.RoleReader}, nil).Once()
+		db.On("GetUserByUsername", mock.Anything, "bob").
+			Return(nil, fmt.Errorf("postgres.GetUserByUsername: %w", database.ErrorNotFound)).Once()
+		db.On("CreateUser", mock.Anything, database.User{Username: "bob", Role: database.RoleReader}).Return(int64(4), nil).Once()
+
+		w := serveBearer(handler, "token")
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.NotNil(t, got)
+		assert.Equal(t, int64(4), got.Id)
+		assert.Equal(t, "bob", got.Username)
+		assert.Equal(t, database.RoleReader, got.Role)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		verifier.On("Verify", "token").Return(nil, jwtauth.ErrorInvalidToken).Once()
+
+		w := serveBearer(handler, "token")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+		assert.Equal(t, `Bearer realm="file-service"`, w.Result().Header.Get("WWW-Authenticate"))
+	})
+
+	t.Run("disabled user", func(t *testing.T) {
+		verifier.On("Verify", "token").Return(&jwtauth.Claims{Username: "alice"}, nil).Once()
+		db.On("GetUserByUsername", mock.Anything, "alice").Return(&database.User{Id: 3, Username: "alice", IsDisabled: true}, nil).Once()
+
+		w := serveBearer(handler, "token")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("basic auth rejected", func(t *testing.T) {
+		w := serve(handler, "alice", "password123")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		verifier.On("Verify", "token").Return(&jwtauth.Claims{Username: "alice"}, nil).Once()
+		db.On("GetUserByUsername", mock.Anything, "alice").Return(nil, fmt.Errorf("error")).Once()
+
+		w := serveBearer(handler, "token")
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func TestRequireScope(t *testing.T) {
+	handler := authmiddleware.RequireScope(apikey.ScopeFileWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tt := range []struct {
+		name   string
+		scopes []string
+		status int
+	}{
+		{"password", nil, http.StatusOK},
+		{"granted", []string{apikey.ScopeFileRead, apikey.ScopeFileWrite}, http.StatusOK},
+		{"missing", []string{apikey.ScopeFileRead}, http.StatusForbidden},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.scopes != nil {
+				r = r.WithContext(requestctx.WithScopes(r.Context(), tt.scopes))
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.status, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	p, err := policy.New(config.PolicyConfig{})
+	require.NoError(t, err)
+
+	handler := authmiddleware.Authorize(p, policy.ActionDelete)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tt := range []struct {
+		name   string
+		user   *database.User
+		status int
+	}{
+		{"admin", &database.User{Username: "root", Role: database.RoleAdmin}, http.StatusOK},
+		{"writer", &database.User{Username: "alice", Role: database.RoleWriter}, http.StatusForbidden},
+		{"reader", &database.User{Username: "bob", Role: database.RoleReader}, http.StatusForbidden},
+		{"anonymous", nil, http.StatusForbidden},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodDelete, "/", nil)
+			if tt.user != nil {
+				r = r.WithContext(requestctx.WithUser(r.Context(), tt.user))
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.status, w.Result().StatusCode)
+		})
+	}
+}
+
+func serve(handler http.Handler, username string, password string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth(username, password)
+	r = r.WithContext(requestctx.WithRequestId(r.Context(), "123"))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	return w
+}
+
+func serveBearer(handler http.Handler, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	r = r.WithContext(requestctx.WithRequestId(r.Context(), "123"))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	return w
+}
@@ -0,0 +1,21 @@
+package clientipmiddleware
+
+import (
+	"file-service/m/internal/clientip"
+	"file-service/m/internal/requestctx"
+	"net/http"
+	"net/netip"
+)
+
+// New resolves the address of the client once for the middlewares and
+// handlers after it, believing the forwarding headers of the trusted
+// proxies only.
+func New(trusted []netip.Prefix) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := requestctx.WithClientIP(r.Context(), clientip.Resolve(r, trusted))
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
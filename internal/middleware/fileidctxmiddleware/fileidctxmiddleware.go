@@ -2,22 +2,74 @@ package fileidctxmiddleware
 
 import (
 	"context"
+	"errors"
 	"file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/audit"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
+	"github.com/google/uuid"
 )
 
-func FileIdCtx(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fileId := chi.URLParam(r, "fileID")
-		if fileId == "" {
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, apiresponse.Error("file id is empty"))
-			return
-		}
-		ctx := context.WithValue(r.Context(), "fileID", fileId)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+//go:generate mockery --name=Db
+type Db interface {
+	GetFileId(ctx context.Context, publicId string) (int64, error)
+}
+
+// FileIdCtx resolves the public id in the fileID URL parameter and stores the
+// internal id of the file in the request context, see requestctx.FileId, and
+// adds it to the request logger and the audit details. Sequential
+// ids are passed through unchanged while acceptLegacyIds is set.
+func FileIdCtx(logger *slog.Logger, db Db, acceptLegacyIds bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "middleware.fileidctxmiddleware.FileIdCtx"
+
+			log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+			fileId := chi.URLParam(r, "fileID")
+			if fileId == "" {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, apiresponse.Error("file id is empty"))
+				return
+			}
+
+			if publicId, err := uuid.Parse(fileId); err == nil {
+				// unknown ids show up in the audit log too
+				audit.SetFile(r.Context(), 0, publicId.String())
+
+				id, err := db.GetFileId(r.Context(), publicId.String())
+				if errors.Is(err, database.ErrorNotFound) {
+					render.Status(r, http.StatusNotFound)
+					render.JSON(w, r, apiresponse.Error("file not found"))
+					return
+				}
+				if err != nil {
+					log.Error("failed to resolve file id", slog.Any("error", err))
+					render.Status(r, http.StatusInternalServerError)
+					render.JSON(w, r, apiresponse.Error("internal error"))
+					return
+				}
+
+				audit.SetFile(r.Context(), id, publicId.String())
+				fileId = strconv.FormatInt(id, 10)
+			} else if id, err := strconv.ParseInt(fileId, 10, 64); err != nil || !acceptLegacyIds {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, apiresponse.Error("invalid file id"))
+				return
+			} else {
+				log.Warn("legacy file id used", slog.String("file_id", fileId))
+				audit.SetFile(r.Context(), id, "")
+			}
+
+			ctx := requestctx.WithFileId(r.Context(), fileId)
+			ctx = requestctx.WithLogAttrs(ctx, logger, slog.String("file_id", fileId))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
@@ -0,0 +1,100 @@
+package fileidctxmiddleware_test
+
+import (
+	"context"
+	"file-service/m/internal/audit"
+	"file-service/m/internal/database"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/middleware/fileidctxmiddleware"
+	"file-service/m/internal/middleware/fileidctxmiddleware/mocks"
+	"file-service/m/internal/requestctx"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const publicId = "0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f"
+
+func TestFileIdCtx(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+
+	var got string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = requestctx.FileId(r.Context())
+	})
+	handler := fileidctxmiddleware.FileIdCtx(log, db, true)(next)
+	strict := fileidctxmiddleware.FileIdCtx(log, db, false)(next)
+
+	t.Run("public id", func(t *testing.T) {
+		got = ""
+		db.On("GetFileId", mock.Anything, publicId).Return(int64(42), nil).Once()
+
+		w, details := serve(handler, publicId)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Equal(t, "42", got)
+		assert.Equal(t, &audit.Details{FileId: 42, FilePublicId: publicId}, details)
+	})
+
+	t.Run("unknown public id", func(t *testing.T) {
+		db.On("GetFileId", mock.Anything, publicId).Return(int64(0), fmt.Errorf("postgres.GetFileId: %w", database.ErrorNotFound)).Once()
+
+		w, details := serve(handler, publicId)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+		// the id that was asked for is audited
+		assert.Equal(t, &audit.Details{FilePublicId: publicId}, details)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("GetFileId", mock.Anything, publicId).Return(int64(0), fmt.Errorf("error")).Once()
+
+		w, _ := serve(handler, publicId)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+
+	t.Run("legacy id", func(t *testing.T) {
+		got = ""
+
+		w, details := serve(handler, "7")
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Equal(t, "7", got)
+		assert.Equal(t, &audit.Details{FileId: 7}, details)
+	})
+
+	t.Run("legacy id rejected", func(t *testing.T) {
+		w, _ := serve(strict, "7")
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		w, _ := serve(handler, "abc")
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+}
+
+func serve(handler http.Handler, fileId string) (*httptest.ResponseRecorder, *audit.Details) {
+	r := httptest.NewRequest(http.MethodGet, "/file/"+fileId, nil)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("fileID", fileId)
+
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	ctx, details := audit.NewContext(ctx)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+
+	return w, details
+}
@@ -1,26 +1,35 @@
 package loggerMiddleware
 
 import (
+	"file-service/m/internal/requestctx"
 	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// New logs every request once it completes and stores the logger of the
+// request, with its request and trace id, in the request context for the
+// middlewares and handlers after it.
 func New(log *slog.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		log = log.With("component", "middleware/logger")
-
-		log.Info("logger middleware created")
+		log.With("component", "middleware/logger").Info("logger middleware created")
 
 		fn := func(w http.ResponseWriter, r *http.Request) {
-			entry := log.With(
+			requestLog := requestctx.Logger(r.Context(), log)
+
+			if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.IsValid() {
+				requestLog = requestLog.With(slog.String("trace_id", spanContext.TraceID().String()))
+			}
+
+			entry := requestLog.With(
+				slog.String("component", "middleware/logger"),
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.Path),
 				slog.String("remote_address", r.RemoteAddr),
 				slog.String("user_agent", r.UserAgent()),
-				slog.String("request_id", r.Context().Value("requestId").(string)),
 			)
 
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
@@ -35,7 +44,7 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 				)
 			}()
 
-			next.ServeHTTP(ww, r)
+			next.ServeHTTP(ww, r.WithContext(requestctx.WithLogger(r.Context(), requestLog)))
 		}
 
 		return http.HandlerFunc(fn)
@@ -0,0 +1,39 @@
+package metricsmiddleware
+
+import (
+	"file-service/m/internal/metrics"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// unmatchedRoute labels requests that matched no route, so that scanning
+// random paths does not create a series per path.
+const unmatchedRoute = "unmatched"
+
+// New records the count and latency of requests by route pattern rather
+// than path, which keeps file ids out of the labels.
+func New(m *metrics.Metrics) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+
+			next.ServeHTTP(ww, r)
+
+			route := unmatchedRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			m.ObserveRequest(route, r.Method, status, time.Since(start))
+		})
+	}
+}
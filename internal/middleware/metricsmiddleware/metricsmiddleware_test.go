@@ -0,0 +1,41 @@
+package metricsmiddleware_test
+
+import (
+	"file-service/m/internal/metrics"
+	"file-service/m/internal/middleware/metricsmiddleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	m := metrics.New()
+
+	router := chi.NewRouter()
+	router.Use(metricsmiddleware.New(m))
+	router.Get("/file/{fileID}", func(w http.ResponseWriter, r *http.Request) {})
+	router.Delete("/file/{fileID}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/file/1", nil),
+		httptest.NewRequest(http.MethodGet, "/file/2", nil),
+		httptest.NewRequest(http.MethodDelete, "/file/3", nil),
+		httptest.NewRequest(http.MethodGet, "/nothing/here", nil),
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	out := w.Body.String()
+
+	// labelled by route pattern, not by path
+	assert.Contains(t, out, `file_service_http_requests_total{method="GET",route="/file/{fileID}",status="200"} 2`)
+	assert.Contains(t, out, `file_service_http_requests_total{method="DELETE",route="/file/{fileID}",status="404"} 1`)
+	assert.Contains(t, out, `file_service_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+}
@@ -0,0 +1,158 @@
+package ratelimitmiddleware
+
+import (
+	"file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/bandwidth"
+	"file-service/m/internal/clientip"
+	"file-service/m/internal/ratelimit"
+	"file-service/m/internal/requestctx"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+// ByIP limits requests per client IP. A nil limiter disables it.
+func ByIP(limiter *ratelimit.Limiter) func(next http.Handler) http.Handler {
+	return limit(limiter, ipKey)
+}
+
+// ByClient limits requests per authenticated user, and per IP for requests
+// without one such as signed URLs and share links. A nil limiter disables
+// it.
+func ByClient(limiter *ratelimit.Limiter) func(next http.Handler) http.Handler {
+	return limit(limiter, clientKey)
+}
+
+// ConcurrentUploads caps the uploads a client runs at the same time. A nil
+// limit disables it.
+func ConcurrentUploads(concurrency *ratelimit.Concurrency) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if concurrency == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := clientKey(r)
+			if !concurrency.Acquire(key) {
+				tooManyRequests(w, r, time.Second)
+				return
+			}
+
+			defer concurrency.Release(key)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Bandwidth throttles the request body and the response of a client to
+// the byte rates of throttle. A nil throttle disables it. A throttled
+// transfer can take longer than the read and write timeouts of the server,
+// so the connection deadlines are moved to timeout past every chunk the
+// rates let through instead, which still cuts off stalled clients.
+func Bandwidth(throttle *bandwidth.Throttle, timeout time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if throttle == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			stream := throttle.Open(r.Context(), clientKey(r))
+			defer stream.Close()
+
+			rc := http.NewResponseController(w)
+
+			if r.Body != nil {
+				body := deadlineReader{Reader: r.Body, rc: rc, timeout: timeout}
+				r.Body = throttledBody{Reader: stream.Reader(body), Closer: r.Body}
+			}
+
+			writer := deadlineWriter{Writer: w, rc: rc, timeout: timeout}
+			next.ServeHTTP(throttledResponseWriter{ResponseWriter: w, writer: stream.Writer(writer)}, r)
+		})
+	}
+}
+
+type throttledBody struct {
+	io.Reader
+	io.Closer
+}
+
+type throttledResponseWriter struct {
+	http.ResponseWriter
+	writer io.Writer
+}
+
+func (w throttledResponseWriter) Write(p []byte) (int, error) {
+	return w.writer.Write(p)
+}
+
+// deadlineReader and deadlineWriter move the connection deadline before
+// every read and write. Writers that do not support deadlines, such as
+// recorders in tests, are passed through.
+type deadlineReader struct {
+	io.Reader
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func (r deadlineReader) Read(p []byte) (int, error) {
+	if r.timeout > 0 {
+		_ = r.rc.SetReadDeadline(time.Now().Add(r.timeout))
+	}
+
+	return r.Reader.Read(p)
+}
+
+type deadlineWriter struct {
+	io.Writer
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func (w deadlineWriter) Write(p []byte) (int, error) {
+	if w.timeout > 0 {
+		_ = w.rc.SetWriteDeadline(time.Now().Add(w.timeout))
+	}
+
+	return w.Writer.Write(p)
+}
+
+func limit(limiter *ratelimit.Limiter, key func(r *http.Request) string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, retryAfter := limiter.Allow(key(r)); !ok {
+				tooManyRequests(w, r, retryAfter)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func clientKey(r *http.Request) string {
+	if user, ok := requestctx.User(r.Context()); ok {
+		return "user:" + user.Username
+	}
+
+	return ipKey(r)
+}
+
+func ipKey(r *http.Request) string {
+	return "ip:" + clientip.Get(r)
+}
+
+func tooManyRequests(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	render.Status(r, http.StatusTooManyRequests)
+	render.JSON(w, r, apiresponse.Error("too many requests"))
+}
@@ -0,0 +1,185 @@
+package ratelimitmiddleware_test
+
+import (
+	"bytes"
+	"file-service/m/internal/bandwidth"
+	"file-service/m/internal/database"
+	"file-service/m/internal/middleware/clientipmiddleware"
+	"file-service/m/internal/middleware/ratelimitmiddleware"
+	"file-service/m/internal/ratelimit"
+	"file-service/m/internal/requestctx"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+func TestByIP(t *testing.T) {
+	handler := ratelimitmiddleware.ByIP(ratelimit.New(0.5, 1))(ok)
+
+	w := serve(handler, "192.0.2.1:1000", nil)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	w = serve(handler, "192.0.2.1:2000", nil)
+	resp := w.Result()
+	body, _ := io.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+	assert.Equal(t, "{\"status\":\"error\",\"message\":\"too many requests\"}\n", string(body))
+
+	w = serve(handler, "192.0.2.2:1000", nil)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
+func TestByIPBehindProxy(t *testing.T) {
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	handler := clientipmiddleware.New(proxies)(ratelimitmiddleware.ByIP(ratelimit.New(0.5, 1))(ok))
+
+	forwarded := func(remoteAddr string, forwardedFor string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w.Result().StatusCode
+	}
+
+	// clients behind the proxy have buckets of their own
+	assert.Equal(t, http.StatusOK, forwarded("10.0.0.1:1000", "192.0.2.1"))
+	assert.Equal(t, http.StatusOK, forwarded("10.0.0.1:1000", "192.0.2.2"))
+	assert.Equal(t, http.StatusTooManyRequests, forwarded("10.0.0.2:1000", "192.0.2.1"))
+
+	// others cannot pick a fresh bucket with the header
+	assert.Equal(t, http.StatusOK, forwarded("198.51.100.1:1000", "192.0.2.3"))
+	assert.Equal(t, http.StatusTooManyRequests, forwarded("198.51.100.1:1000", "192.0.2.4"))
+}
+
+func TestByClient(t *testing.T) {
+	handler := ratelimitmiddleware.ByClient(ratelimit.New(1, 1))(ok)
+	alice := &database.User{Username: "alice"}
+
+	w := serve(handler, "192.0.2.1:1000", alice)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	// the same user from another address shares the bucket
+	w = serve(handler, "192.0.2.2:1000", alice)
+	assert.Equal(t, http.StatusTooManyRequests, w.Result().StatusCode)
+
+	// anonymous requests from the first address have a bucket of their own
+	w = serve(handler, "192.0.2.1:1000", nil)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
+func TestDisabled(t *testing.T) {
+	handler := ratelimitmiddleware.ByClient(nil)(ok)
+
+	for range 10 {
+		w := serve(handler, "192.0.2.1:1000", nil)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	}
+}
+
+func TestConcurrentUploads(t *testing.T) {
+	concurrency := ratelimit.NewConcurrency(1)
+	alice := &database.User{Username: "alice"}
+
+	var inner *httptest.ResponseRecorder
+	var handler http.Handler
+	handler = ratelimitmiddleware.ConcurrentUploads(concurrency)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a second upload while the first is still running
+		inner = serve(handler, "192.0.2.1:1000", alice)
+	}))
+
+	w := serve(handler, "192.0.2.1:1000", alice)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, http.StatusTooManyRequests, inner.Result().StatusCode)
+	assert.Equal(t, "1", inner.Result().Header.Get("Retry-After"))
+
+	// the slot is free again once the upload finished
+	assert.True(t, concurrency.Acquire("user:alice"))
+}
+
+func serve(handler http.Handler, remoteAddr string, user *database.User) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = remoteAddr
+	if user != nil {
+		r = r.WithContext(requestctx.WithUser(r.Context(), user))
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestBandwidth(t *testing.T) {
+	throttle := bandwidth.New(bandwidth.Limits{PerClient: 64 << 10})
+	handler := ratelimitmiddleware.Bandwidth(throttle, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		w.Write(data)
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(make([]byte, 48<<10)))
+	w := httptest.NewRecorder()
+	start := time.Now()
+
+	handler.ServeHTTP(w, r)
+
+	// the body and the response take from the same client limit
+	assert.Equal(t, 48<<10, w.Body.Len())
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+
+	throttle.SetLimits(bandwidth.Limits{})
+
+	r = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(make([]byte, 1<<20)))
+	w = httptest.NewRecorder()
+	start = time.Now()
+
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, 1<<20, w.Body.Len())
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestBandwidthOutlastsServerTimeouts(t *testing.T) {
+	throttle := bandwidth.New(bandwidth.Limits{PerClient: 64 << 10})
+	timeout := 200 * time.Millisecond
+
+	server := httptest.NewUnstartedServer(ratelimitmiddleware.Bandwidth(throttle, timeout)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write(data)
+	})))
+	server.Config.ReadTimeout = timeout
+	server.Config.WriteTimeout = timeout
+	server.Start()
+	defer server.Close()
+
+	start := time.Now()
+
+	// the upload and the download together take about twice the timeout
+	resp, err := http.Post(server.URL, "application/octet-stream", bytes.NewReader(make([]byte, 48<<10)))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 48<<10, len(data))
+	assert.Greater(t, time.Since(start), 2*timeout)
+}
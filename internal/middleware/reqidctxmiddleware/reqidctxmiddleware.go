@@ -1,7 +1,7 @@
 package reqidctxmiddleware
 
 import (
-	"context"
+	"file-service/m/internal/requestctx"
 	"net/http"
 
 	"github.com/go-chi/chi/middleware"
@@ -10,7 +10,7 @@ import (
 func RequestIdCtx(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		requestId := middleware.GetReqID(r.Context())
-		ctx := context.WithValue(r.Context(), "requestId", requestId)
+		ctx := requestctx.WithRequestId(r.Context(), requestId)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
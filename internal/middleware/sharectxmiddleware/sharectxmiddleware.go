@@ -0,0 +1,103 @@
+package sharectxmiddleware
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/auth"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/sharetoken"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// PasswordHeader carries the password of protected shares. Browsers can send
+// it as the "password" field of a form instead.
+const PasswordHeader = "X-Share-Password"
+
+//go:generate mockery --name=Db
+type Db interface {
+	GetShare(ctx context.Context, tokenHash string) (*database.Share, error)
+	GetFile(ctx context.Context, id int64, isDeleted bool) (*database.File, error)
+}
+
+// ShareCtx resolves the share of the token URL parameter, checks that it is
+// still usable and that the caller knows its password, and stores it and its
+// file in the request context, see requestctx.Share.
+func ShareCtx(logger *slog.Logger, db Db) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "middleware.sharectxmiddleware.ShareCtx"
+
+			log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+			share, err := db.GetShare(r.Context(), sharetoken.Hash(chi.URLParam(r, "token")))
+			if errors.Is(err, database.ErrorNotFound) {
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, apiresponse.Error("share not found"))
+				return
+			}
+			if err != nil {
+				log.Error("failed to get share", slog.Any("error", err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, apiresponse.Error("internal error"))
+				return
+			}
+
+			if share.Expired(time.Now()) {
+				render.Status(r, http.StatusGone)
+				render.JSON(w, r, apiresponse.Error("share expired"))
+				return
+			}
+
+			if share.Exhausted() {
+				render.Status(r, http.StatusGone)
+				render.JSON(w, r, apiresponse.Error("download limit reached"))
+				return
+			}
+
+			if share.PasswordHash != "" {
+				password := r.Header.Get(PasswordHeader)
+				if password == "" && r.Method == http.MethodPost {
+					password = r.PostFormValue("password")
+				}
+
+				if password == "" {
+					render.Status(r, http.StatusUnauthorized)
+					render.JSON(w, r, apiresponse.Error("password required"))
+					return
+				}
+
+				if !auth.CheckPassword(share.PasswordHash, password) {
+					log.Info("wrong share password", slog.Int64("share_id", share.Id))
+					render.Status(r, http.StatusUnauthorized)
+					render.JSON(w, r, apiresponse.Error("invalid password"))
+					return
+				}
+			}
+
+			file, err := db.GetFile(r.Context(), share.FileId, false)
+			if errors.Is(err, database.ErrorNotFound) {
+				// the file is in the trash
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, apiresponse.Error("share not found"))
+				return
+			}
+			if err != nil {
+				log.Error("failed to get file", slog.Any("error", err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, apiresponse.Error("internal error"))
+				return
+			}
+
+			ctx := requestctx.WithShare(r.Context(), share, file)
+			ctx = requestctx.WithLogAttrs(ctx, logger, slog.Int64("share_id", share.Id), slog.Int64("file_id", file.Id))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
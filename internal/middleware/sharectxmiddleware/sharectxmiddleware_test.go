@@ -0,0 +1,150 @@
+package sharectxmiddleware_test
+
+import (
+	"context"
+	"file-service/m/internal/auth"
+	"file-service/m/internal/database"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/middleware/sharectxmiddleware"
+	"file-service/m/internal/middleware/sharectxmiddleware/mocks"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/sharetoken"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareCtx(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+
+	var share *database.Share
+	var file *database.File
+	handler := sharectxmiddleware.ShareCtx(log, db)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		share, file, _ = requestctx.Share(r.Context())
+	}))
+
+	hash := sharetoken.Hash("token")
+	passwordHash, err := auth.HashPassword("password123")
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		share, file = nil, nil
+		db.On("GetShare", mock.Anything, hash).Return(&database.Share{Id: 7, FileId: 1}, nil).Once()
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1}, nil).Once()
+
+		w := serve(handler, httptest.NewRequest(http.MethodGet, "/s/token", nil))
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		require.NotNil(t, share)
+		assert.Equal(t, int64(7), share.Id)
+		require.NotNil(t, file)
+		assert.Equal(t, int64(1), file.Id)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		db.On("GetShare", mock.Anything, hash).Return(nil, fmt.Errorf("postgres.GetShare: %w", database.ErrorNotFound)).Once()
+
+		w := serve(handler, httptest.NewRequest(http.MethodGet, "/s/token", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		expiresAt := time.Now().Add(-time.Minute)
+		db.On("GetShare", mock.Anything, hash).Return(&database.Share{Id: 7, FileId: 1, ExpiresAt: &expiresAt}, nil).Once()
+
+		w := serve(handler, httptest.NewRequest(http.MethodGet, "/s/token", nil))
+
+		assertError(t, w, http.StatusGone, "share expired")
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		maxDownloads := int64(2)
+		db.On("GetShare", mock.Anything, hash).Return(&database.Share{Id: 7, FileId: 1, MaxDownloads: &maxDownloads, DownloadCount: 2}, nil).Once()
+
+		w := serve(handler, httptest.NewRequest(http.MethodGet, "/s/token", nil))
+
+		assertError(t, w, http.StatusGone, "download limit reached")
+	})
+
+	t.Run("password required", func(t *testing.T) {
+		db.On("GetShare", mock.Anything, hash).Return(&database.Share{Id: 7, FileId: 1, PasswordHash: passwordHash}, nil).Once()
+
+		w := serve(handler, httptest.NewRequest(http.MethodGet, "/s/token", nil))
+
+		assertError(t, w, http.StatusUnauthorized, "password required")
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		db.On("GetShare", mock.Anything, hash).Return(&database.Share{Id: 7, FileId: 1, PasswordHash: passwordHash}, nil).Once()
+
+		r := httptest.NewRequest(http.MethodGet, "/s/token", nil)
+		r.Header.Set(sharectxmiddleware.PasswordHeader, "wrong-password")
+		w := serve(handler, r)
+
+		assertError(t, w, http.StatusUnauthorized, "invalid password")
+	})
+
+	t.Run("password header", func(t *testing.T) {
+		db.On("GetShare", mock.Anything, hash).Return(&database.Share{Id: 7, FileId: 1, PasswordHash: passwordHash}, nil).Once()
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1}, nil).Once()
+
+		r := httptest.NewRequest(http.MethodGet, "/s/token", nil)
+		r.Header.Set(sharectxmiddleware.PasswordHeader, "password123")
+		w := serve(handler, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+
+	t.Run("password form", func(t *testing.T) {
+		db.On("GetShare", mock.Anything, hash).Return(&database.Share{Id: 7, FileId: 1, PasswordHash: passwordHash}, nil).Once()
+		db.On("GetFile", mock.Anything, int64(1), false).Return(&database.File{Id: 1}, nil).Once()
+
+		r := httptest.NewRequest(http.MethodPost, "/s/token/download", strings.NewReader(url.Values{"password": {"password123"}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := serve(handler, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+
+	t.Run("trashed file", func(t *testing.T) {
+		db.On("GetShare", mock.Anything, hash).Return(&database.Share{Id: 7, FileId: 1}, nil).Once()
+		db.On("GetFile", mock.Anything, int64(1), false).Return(nil, fmt.Errorf("postgres.GetFile: %w", database.ErrorNotFound)).Once()
+
+		w := serve(handler, httptest.NewRequest(http.MethodGet, "/s/token", nil))
+
+		assertError(t, w, http.StatusNotFound, "share not found")
+	})
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("token", "token")
+
+	ctx := requestctx.WithRequestId(r.Context(), "123")
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+
+	return w
+}
+
+func assertError(t *testing.T, w *httptest.ResponseRecorder, status int, message string) {
+	resp := w.Result()
+	body, _ := io.ReadAll(resp.Body)
+
+	assert.Equal(t, status, resp.StatusCode)
+	assert.Equal(t, fmt.Sprintf("{\"status\":\"error\",\"message\":\"%s\"}\n", message), string(body))
+}
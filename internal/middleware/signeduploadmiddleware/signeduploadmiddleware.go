@@ -0,0 +1,99 @@
+package signeduploadmiddleware
+
+import (
+	"context"
+	"errors"
+	apiresponse "file-service/m/internal/api/apiResponse"
+	"file-service/m/internal/audit"
+	"file-service/m/internal/clientip"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/signedurl"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+//go:generate mockery --name=Db
+type Db interface {
+	GetUserByUsername(ctx context.Context, username string) (*database.User, error)
+	IsUploadUrlUsed(ctx context.Context, nonce string) (bool, error)
+}
+
+// New authenticates uploads with a signed upload URL instead of credentials.
+// It stores the owner the URL was issued for and the constraints of the URL,
+// which the save handler enforces, in the request context. URLs that a file
+// was already saved with are rejected before the upload is read; the save
+// handler uses up the URL atomically with the file.
+func New(logger *slog.Logger, db Db, signer *signedurl.Signer) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "middleware.signeduploadmiddleware.New"
+
+			log := requestctx.Logger(r.Context(), logger).With(slog.String("op", op))
+
+			query := r.URL.Query()
+
+			err := signer.Verify(signedurl.UploadPath, query, clientip.Get(r), time.Now())
+			if errors.Is(err, signedurl.ErrorExpired) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, apiresponse.Error("url expired"))
+				return
+			}
+			if err != nil {
+				log.Info("invalid signed url", slog.Any("error", err))
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, apiresponse.Error("invalid signature"))
+				return
+			}
+
+			constraints, err := signedurl.ParseUploadConstraints(query)
+			if err != nil {
+				log.Error("signed url without valid constraints", slog.Any("error", err))
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, apiresponse.Error("invalid signature"))
+				return
+			}
+
+			used, err := db.IsUploadUrlUsed(r.Context(), constraints.Nonce)
+			if err != nil {
+				log.Error("failed to check upload url", slog.Any("error", err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, apiresponse.Error("internal error"))
+				return
+			}
+
+			if used {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, apiresponse.Error("url already used"))
+				return
+			}
+
+			user, err := db.GetUserByUsername(r.Context(), constraints.Owner)
+			if err != nil && !errors.Is(err, database.ErrorNotFound) {
+				log.Error("failed to get user", slog.Any("error", err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, apiresponse.Error("internal error"))
+				return
+			}
+
+			// the owner may have been removed or disabled since the URL was
+			// issued
+			if errors.Is(err, database.ErrorNotFound) || user.IsDisabled {
+				log.Info("signed upload for unavailable owner", slog.String("owner", constraints.Owner))
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, apiresponse.Error("forbidden"))
+				return
+			}
+
+			audit.SetActor(r.Context(), user.Username)
+
+			ctx := requestctx.WithUser(r.Context(), user)
+			ctx = requestctx.WithUploadConstraints(ctx, constraints)
+			ctx = requestctx.WithLogAttrs(ctx, logger, slog.String("username", user.Username))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
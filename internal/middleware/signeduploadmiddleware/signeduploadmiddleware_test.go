@@ -0,0 +1,142 @@
+package signeduploadmiddleware_test
+
+import (
+	"bytes"
+	"file-service/m/internal/audit"
+	"file-service/m/internal/database"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/middleware/signeduploadmiddleware"
+	"file-service/m/internal/middleware/signeduploadmiddleware/mocks"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/signedurl"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedUploadMiddleware(t *testing.T) {
+	log := mockLogger.NewLogger()
+	db := mocks.NewDb(t)
+	signer, err := signedurl.New(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+
+	var user *database.User
+	var constraints *signedurl.UploadConstraints
+	handler := signeduploadmiddleware.New(log, db, signer)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ = requestctx.User(r.Context())
+		constraints, _ = requestctx.UploadConstraints(r.Context())
+	}))
+
+	expires := time.Now().Add(time.Minute).Truncate(time.Second)
+	issued := &signedurl.UploadConstraints{Owner: "alice", MaxSize: 1024, ContentTypes: []string{"image/*"}, Nonce: "n1", Expires: expires}
+	valid := signer.Sign(signedurl.UploadPath, issued.Query(), expires)
+
+	t.Run("success", func(t *testing.T) {
+		user, constraints = nil, nil
+		db.On("IsUploadUrlUsed", mock.Anything, "n1").Return(false, nil).Once()
+		db.On("GetUserByUsername", mock.Anything, "alice").Return(&database.User{Id: 1, Username: "alice"}, nil).Once()
+
+		r := httptest.NewRequest(http.MethodPost, signedurl.UploadPath+"?"+valid.Encode(), nil)
+		ctx, details := audit.NewContext(requestctx.WithRequestId(r.Context(), "123"))
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, r.WithContext(ctx))
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		// the audit middleware runs first and learns the owner from here
+		assert.Equal(t, "alice", details.Actor)
+		require.NotNil(t, user)
+		assert.Equal(t, "alice", user.Username)
+		assert.Equal(t, issued, constraints)
+	})
+
+	t.Run("tampered constraints", func(t *testing.T) {
+		tampered, _ := url.ParseQuery(valid.Encode())
+		tampered.Set("max_size", "1073741824")
+
+		w := serve(handler, tampered)
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		w := serve(handler, signer.Sign(signedurl.UploadPath, issued.Query(), time.Now().Add(-time.Minute)))
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("download signature", func(t *testing.T) {
+		w := serve(handler, signer.Sign(signedurl.DownloadPath("x"), issued.Query(), time.Now().Add(time.Minute)))
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("used", func(t *testing.T) {
+		db.On("IsUploadUrlUsed", mock.Anything, "n1").Return(true, nil).Once()
+
+		w := serve(handler, valid)
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("without nonce", func(t *testing.T) {
+		query := issued.Query()
+		query.Del(signedurl.ParamNonce)
+
+		w := serve(handler, signer.Sign(signedurl.UploadPath, query, expires))
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("disabled owner", func(t *testing.T) {
+		db.On("IsUploadUrlUsed", mock.Anything, "n1").Return(false, nil).Once()
+		db.On("GetUserByUsername", mock.Anything, "alice").Return(&database.User{Id: 1, Username: "alice", IsDisabled: true}, nil).Once()
+
+		w := serve(handler, valid)
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("removed owner", func(t *testing.T) {
+		db.On("IsUploadUrlUsed", mock.Anything, "n1").Return(false, nil).Once()
+		db.On("GetUserByUsername", mock.Anything, "alice").Return(nil, fmt.Errorf("postgres.GetUserByUsername: %w", database.ErrorNotFound)).Once()
+
+		w := serve(handler, valid)
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db.On("IsUploadUrlUsed", mock.Anything, "n1").Return(false, nil).Once()
+		db.On("GetUserByUsername", mock.Anything, "alice").Return(nil, fmt.Errorf("error")).Once()
+
+		w := serve(handler, valid)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+
+	t.Run("db error checking the nonce", func(t *testing.T) {
+		db.On("IsUploadUrlUsed", mock.Anything, "n1").Return(false, fmt.Errorf("error")).Once()
+
+		w := serve(handler, valid)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
+
+func serve(handler http.Handler, query url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, signedurl.UploadPath+"?"+query.Encode(), nil)
+	r = r.WithContext(requestctx.WithRequestId(r.Context(), "123"))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	return w
+}
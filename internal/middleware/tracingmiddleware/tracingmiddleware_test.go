@@ -0,0 +1,85 @@
+package tracingmiddleware_test
+
+import (
+	"file-service/m/internal/middleware/tracingmiddleware"
+	"file-service/m/internal/requestctx"
+	"file-service/m/internal/tracing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	traceId  = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentId = "00f067aa0ba902b7"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), "test", 1))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(requestctx.WithRequestId(r.Context(), "123")))
+		})
+	})
+	router.Use(tracingmiddleware.New)
+	router.Get("/file/{fileID}", func(w http.ResponseWriter, r *http.Request) {
+		// stands in for a database or storage call of the handler
+		_, span := tracing.Start(r.Context(), "postgres.GetFile")
+		span.End()
+
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	t.Run("continues the trace of the caller", func(t *testing.T) {
+		exporter.Reset()
+
+		r := httptest.NewRequest(http.MethodGet, "/file/1", nil)
+		r.Header.Set("traceparent", "00-"+traceId+"-"+parentId+"-01")
+
+		router.ServeHTTP(httptest.NewRecorder(), r)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 2)
+
+		child, server := spans[0], spans[1]
+
+		assert.Equal(t, "GET /file/{fileID}", server.Name)
+		assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+		assert.Equal(t, traceId, server.SpanContext.TraceID().String())
+		assert.Equal(t, parentId, server.Parent.SpanID().String())
+		assert.Contains(t, server.Attributes, attribute.String("request_id", "123"))
+		assert.Contains(t, server.Attributes, attribute.Int("http.response.status_code", http.StatusInternalServerError))
+		assert.Equal(t, codes.Error, server.Status.Code)
+
+		assert.Equal(t, "postgres.GetFile", child.Name)
+		assert.Equal(t, server.SpanContext.SpanID(), child.Parent.SpanID())
+	})
+
+	t.Run("starts a new trace", func(t *testing.T) {
+		exporter.Reset()
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/file/1", nil))
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 2)
+
+		assert.True(t, spans[1].SpanContext.TraceID().IsValid())
+		assert.NotEqual(t, traceId, spans[1].SpanContext.TraceID().String())
+		assert.False(t, spans[1].Parent.IsValid())
+	})
+}
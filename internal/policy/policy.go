@@ -0,0 +1,88 @@
+package policy
+
+import (
+	"file-service/m/internal/config"
+	"file-service/m/internal/database"
+	"fmt"
+)
+
+type Action string
+
+const (
+	ActionRead        Action = "read"
+	ActionUpload      Action = "upload"
+	ActionTrash       Action = "trash"
+	ActionShare       Action = "share"
+	ActionDelete      Action = "delete"
+	ActionRestore     Action = "restore"
+	ActionManageKeys  Action = "manage_keys"
+	ActionManageUsers Action = "manage_users"
+)
+
+// ranks orders the roles, every role may do what the roles below it may.
+var ranks = map[string]int{
+	database.RoleReader: 1,
+	database.RoleWriter: 2,
+	database.RoleAdmin:  3,
+}
+
+var defaultRoles = map[Action]string{
+	ActionRead:        database.RoleReader,
+	ActionUpload:      database.RoleWriter,
+	ActionTrash:       database.RoleWriter,
+	ActionShare:       database.RoleWriter,
+	ActionDelete:      database.RoleAdmin,
+	ActionRestore:     database.RoleAdmin,
+	ActionManageKeys:  database.RoleWriter,
+	ActionManageUsers: database.RoleAdmin,
+}
+
+// Policy decides which role an action requires. Whether a caller may act on
+// a particular file is decided by ownership and grants on top of that.
+type Policy struct {
+	roles map[Action]string
+}
+
+// New returns the default policy with the configured overrides applied.
+func New(cfg config.PolicyConfig) (*Policy, error) {
+	roles := make(map[Action]string, len(defaultRoles))
+	for action, role := range defaultRoles {
+		roles[action] = role
+	}
+
+	overrides := map[Action]string{
+		ActionDelete:  cfg.DeleteRole,
+		ActionRestore: cfg.RestoreRole,
+	}
+
+	for action, role := range overrides {
+		if role == "" {
+			continue
+		}
+
+		if !ValidRole(role) {
+			return nil, fmt.Errorf("unknown role %q for %s", role, action)
+		}
+
+		roles[action] = role
+	}
+
+	return &Policy{roles: roles}, nil
+}
+
+// Allows reports whether role may perform action. Unknown roles and actions
+// are denied.
+func (p *Policy) Allows(role string, action Action) bool {
+	required, ok := p.roles[action]
+	if !ok {
+		return false
+	}
+
+	return ranks[role] > 0 && ranks[role] >= ranks[required]
+}
+
+func ValidRole(role string) bool {
+	_, ok := ranks[role]
+
+	return ok
+}
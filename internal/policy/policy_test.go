@@ -0,0 +1,55 @@
+package policy_test
+
+import (
+	"file-service/m/internal/config"
+	"file-service/m/internal/database"
+	"file-service/m/internal/policy"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	p, err := policy.New(config.PolicyConfig{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		action  policy.Action
+		allowed []string
+	}{
+		{policy.ActionRead, []string{database.RoleReader, database.RoleWriter, database.RoleAdmin}},
+		{policy.ActionUpload, []string{database.RoleWriter, database.RoleAdmin}},
+		{policy.ActionTrash, []string{database.RoleWriter, database.RoleAdmin}},
+		{policy.ActionShare, []string{database.RoleWriter, database.RoleAdmin}},
+		{policy.ActionDelete, []string{database.RoleAdmin}},
+		{policy.ActionRestore, []string{database.RoleAdmin}},
+		{policy.ActionManageKeys, []string{database.RoleWriter, database.RoleAdmin}},
+		{policy.ActionManageUsers, []string{database.RoleAdmin}},
+	}
+
+	for _, tt := range tests {
+		for _, role := range []string{database.RoleReader, database.RoleWriter, database.RoleAdmin, "", "owner"} {
+			allowed := false
+			for _, r := range tt.allowed {
+				allowed = allowed || r == role
+			}
+
+			assert.Equal(t, allowed, p.Allows(role, tt.action), "%s %s", role, tt.action)
+		}
+	}
+
+	assert.False(t, p.Allows(database.RoleAdmin, policy.Action("unknown")))
+}
+
+func TestPolicyOverrides(t *testing.T) {
+	p, err := policy.New(config.PolicyConfig{DeleteRole: database.RoleWriter})
+	require.NoError(t, err)
+
+	assert.True(t, p.Allows(database.RoleWriter, policy.ActionDelete))
+	assert.False(t, p.Allows(database.RoleReader, policy.ActionDelete))
+	assert.False(t, p.Allows(database.RoleWriter, policy.ActionRestore))
+
+	_, err = policy.New(config.PolicyConfig{RestoreRole: "owner"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// minIdle is the shortest time a bucket is kept after its last use.
+const minIdle = time.Minute
+
+// Limiter keeps a token bucket per key, e.g. per user or per IP. Buckets
+// that were idle long enough to be full again are dropped, which keeps the
+// memory bounded by the number of recently active keys.
+type Limiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	idle      time.Duration
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// New returns a limiter that allows perSecond requests per key on average
+// and bursts of up to burst requests.
+func New(perSecond float64, burst int) *Limiter {
+	idle := minIdle
+	if refill := time.Duration(float64(burst) / perSecond * float64(time.Second)); refill > idle {
+		idle = refill
+	}
+
+	return &Limiter{
+		limit:   rate.Limit(perSecond),
+		burst:   burst,
+		idle:    idle,
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of key. When the bucket is empty it
+// reports how long the caller has to wait for the next token.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+
+	b.lastSeen = now
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, l.idle
+	}
+
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+
+	return true, 0
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.idle {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= l.idle {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.lastSweep = now
+}
+
+// Concurrency caps the number of operations in flight per key.
+type Concurrency struct {
+	mu     sync.Mutex
+	max    int
+	active map[string]int
+}
+
+// NewConcurrency returns a limit of max concurrent operations per key.
+func NewConcurrency(max int) *Concurrency {
+	return &Concurrency{max: max, active: map[string]int{}}
+}
+
+// Acquire reserves a slot for key and reports whether one was free. Every
+// successful Acquire must be followed by a Release.
+func (c *Concurrency) Acquire(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.active[key] >= c.max {
+		return false
+	}
+
+	c.active[key]++
+
+	return true
+}
+
+func (c *Concurrency) Release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.active[key] <= 1 {
+		delete(c.active, key)
+		return
+	}
+
+	c.active[key]--
+}
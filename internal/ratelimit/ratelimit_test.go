@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := New(1, 2)
+	l.now = func() time.Time { return now }
+
+	t.Run("burst", func(t *testing.T) {
+		ok, _ := l.Allow("alice")
+		assert.True(t, ok)
+		ok, _ = l.Allow("alice")
+		assert.True(t, ok)
+
+		ok, retryAfter := l.Allow("alice")
+		assert.False(t, ok)
+		assert.Equal(t, time.Second, retryAfter)
+	})
+
+	t.Run("keys are independent", func(t *testing.T) {
+		ok, _ := l.Allow("bob")
+		assert.True(t, ok)
+	})
+
+	t.Run("refill", func(t *testing.T) {
+		now = now.Add(500 * time.Millisecond)
+
+		ok, retryAfter := l.Allow("alice")
+		assert.False(t, ok)
+		assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+		now = now.Add(500 * time.Millisecond)
+
+		ok, _ = l.Allow("alice")
+		assert.True(t, ok)
+	})
+
+	t.Run("idle buckets are dropped", func(t *testing.T) {
+		now = now.Add(time.Hour)
+
+		ok, _ := l.Allow("carol")
+		assert.True(t, ok)
+		assert.Len(t, l.buckets, 1)
+	})
+}
+
+func TestConcurrency(t *testing.T) {
+	c := NewConcurrency(2)
+
+	assert.True(t, c.Acquire("alice"))
+	assert.True(t, c.Acquire("alice"))
+	assert.False(t, c.Acquire("alice"))
+	assert.True(t, c.Acquire("bob"))
+
+	c.Release("alice")
+	assert.True(t, c.Acquire("alice"))
+
+	c.Release("alice")
+	c.Release("alice")
+	c.Release("bob")
+	assert.Empty(t, c.active)
+}
@@ -0,0 +1,134 @@
+// Package requestctx holds the values the middlewares attach to a request,
+// under keys of its own type so that they cannot collide with keys of other
+// packages. The accessors report missing values instead of panicking, which
+// keeps handlers usable behind routers that do not install every
+// middleware.
+package requestctx
+
+import (
+	"context"
+	"file-service/m/internal/database"
+	"file-service/m/internal/signedurl"
+	"log/slog"
+)
+
+type key int
+
+const (
+	requestIdKey key = iota
+	loggerKey
+	userKey
+	scopesKey
+	fileIdKey
+	shareKey
+	uploadConstraintsKey
+	clientIPKey
+)
+
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey, requestId)
+}
+
+// RequestId returns the id of the request or "" when it has none.
+func RequestId(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey).(string)
+	return requestId
+}
+
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// Logger returns the logger of the request, which carries the request id
+// and, once they are known, the user and the file. Requests without one,
+// e.g. in handlers mounted without the logger middleware, get fallback with
+// the request id.
+func Logger(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+
+	if requestId := RequestId(ctx); requestId != "" {
+		return fallback.With(slog.String("request_id", requestId))
+	}
+
+	return fallback
+}
+
+// WithLogAttrs adds attrs to the logger of the request, see Logger for
+// fallback.
+func WithLogAttrs(ctx context.Context, fallback *slog.Logger, attrs ...any) context.Context {
+	return WithLogger(ctx, Logger(ctx, fallback).With(attrs...))
+}
+
+func WithUser(ctx context.Context, user *database.User) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+// User returns the authenticated caller.
+func User(ctx context.Context) (*database.User, bool) {
+	user, ok := ctx.Value(userKey).(*database.User)
+	return user, ok && user != nil
+}
+
+func WithScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, scopesKey, scopes)
+}
+
+// Scopes returns the scopes granted to the API key of the caller. Callers
+// that did not authenticate with an API key have none.
+func Scopes(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(scopesKey).([]string)
+	return scopes, ok
+}
+
+func WithFileId(ctx context.Context, fileId string) context.Context {
+	return context.WithValue(ctx, fileIdKey, fileId)
+}
+
+// FileId returns the internal id of the file addressed by the URL.
+func FileId(ctx context.Context) (string, bool) {
+	fileId, ok := ctx.Value(fileIdKey).(string)
+	return fileId, ok
+}
+
+type shareValue struct {
+	share *database.Share
+	file  *database.File
+}
+
+func WithShare(ctx context.Context, share *database.Share, file *database.File) context.Context {
+	return context.WithValue(ctx, shareKey, shareValue{share: share, file: file})
+}
+
+// Share returns the share addressed by the URL and its file.
+func Share(ctx context.Context) (*database.Share, *database.File, bool) {
+	value, ok := ctx.Value(shareKey).(shareValue)
+	if !ok || value.share == nil || value.file == nil {
+		return nil, nil, false
+	}
+
+	return value.share, value.file, true
+}
+
+func WithUploadConstraints(ctx context.Context, constraints *signedurl.UploadConstraints) context.Context {
+	return context.WithValue(ctx, uploadConstraintsKey, constraints)
+}
+
+// UploadConstraints returns the constraints of the signed upload URL the
+// request was made with.
+func UploadConstraints(ctx context.Context) (*signedurl.UploadConstraints, bool) {
+	constraints, ok := ctx.Value(uploadConstraintsKey).(*signedurl.UploadConstraints)
+	return constraints, ok && constraints != nil
+}
+
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// ClientIP returns the address of the client, which differs from the peer
+// of the connection behind trusted proxies.
+func ClientIP(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(clientIPKey).(string)
+	return ip, ok
+}
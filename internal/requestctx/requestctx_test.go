@@ -0,0 +1,71 @@
+package requestctx_test
+
+import (
+	"bytes"
+	"context"
+	"file-service/m/internal/database"
+	"file-service/m/internal/requestctx"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingValues(t *testing.T) {
+	ctx := context.Background()
+
+	assert.Equal(t, "", requestctx.RequestId(ctx))
+
+	_, ok := requestctx.User(ctx)
+	assert.False(t, ok)
+
+	_, ok = requestctx.Scopes(ctx)
+	assert.False(t, ok)
+
+	_, ok = requestctx.FileId(ctx)
+	assert.False(t, ok)
+
+	_, _, ok = requestctx.Share(ctx)
+	assert.False(t, ok)
+
+	_, ok = requestctx.UploadConstraints(ctx)
+	assert.False(t, ok)
+}
+
+func TestKeysDoNotCollide(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user", &database.User{Username: "mallory"})
+
+	_, ok := requestctx.User(ctx)
+	assert.False(t, ok)
+
+	ctx = requestctx.WithUser(ctx, &database.User{Username: "alice"})
+
+	user, ok := requestctx.User(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "alice", user.Username)
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	fallback := slog.New(slog.NewTextHandler(&buf, nil))
+
+	t.Run("fallback with request id", func(t *testing.T) {
+		buf.Reset()
+		ctx := requestctx.WithRequestId(context.Background(), "123")
+
+		requestctx.Logger(ctx, fallback).Info("message")
+
+		assert.Contains(t, buf.String(), "request_id=123")
+	})
+
+	t.Run("attrs are added to the request logger", func(t *testing.T) {
+		buf.Reset()
+		ctx := requestctx.WithRequestId(context.Background(), "123")
+		ctx = requestctx.WithLogAttrs(ctx, fallback, slog.String("username", "alice"))
+		ctx = requestctx.WithLogAttrs(ctx, fallback, slog.String("file_id", "7"))
+
+		requestctx.Logger(ctx, slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))).Info("message")
+
+		assert.Contains(t, buf.String(), "request_id=123 username=alice file_id=7")
+	})
+}
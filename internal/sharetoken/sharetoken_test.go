@@ -0,0 +1,23 @@
+package sharetoken_test
+
+import (
+	"file-service/m/internal/sharetoken"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	token, hash, err := sharetoken.Generate()
+	require.NoError(t, err)
+
+	assert.Regexp(t, regexp.MustCompile(`^[A-Za-z0-9_-]{32}$`), token)
+	assert.Equal(t, sharetoken.Hash(token), hash)
+	assert.NotEqual(t, token, hash)
+
+	other, _, err := sharetoken.Generate()
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
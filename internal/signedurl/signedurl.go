@@ -0,0 +1,119 @@
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	ParamExpires     = "expires"
+	ParamIP          = "ip"
+	ParamDisposition = "disposition"
+	ParamSignature   = "signature"
+
+	minKeySize = 32
+)
+
+var (
+	ErrorInvalidKey       = errors.New("signing key must be at least 32 bytes")
+	ErrorInvalidSignature = errors.New("invalid signature")
+	ErrorExpired          = errors.New("signed url expired")
+	ErrorIPMismatch       = errors.New("signed url is bound to another ip")
+)
+
+// Signer signs and verifies URLs with HMAC-SHA256. The signature covers the
+// path and every query parameter, so none of them can be changed or added
+// without invalidating it.
+type Signer struct {
+	key []byte
+}
+
+func New(key []byte) (*Signer, error) {
+	if len(key) < minKeySize {
+		return nil, ErrorInvalidKey
+	}
+
+	return &Signer{key: key}, nil
+}
+
+// DownloadPath is the path signed download URLs of a file are served at.
+func DownloadPath(publicId string) string {
+	return "/download/" + publicId
+}
+
+// ParseKey decodes a base64 encoded signing key.
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) < minKeySize {
+		return nil, ErrorInvalidKey
+	}
+
+	return key, nil
+}
+
+// Sign returns a copy of query with the expiry and the signature for path
+// added. query may carry an "ip" parameter to bind the URL to a client.
+func (s *Signer) Sign(path string, query url.Values, expires time.Time) url.Values {
+	signed := url.Values{}
+	for key, values := range query {
+		signed[key] = append([]string(nil), values...)
+	}
+
+	signed.Del(ParamSignature)
+	signed.Set(ParamExpires, strconv.FormatInt(expires.Unix(), 10))
+	signed.Set(ParamSignature, s.signature(path, signed))
+
+	return signed
+}
+
+// Verify checks the signature of path and query, that the URL has not
+// expired at now and, for bound URLs, that it is used from remoteIP.
+func (s *Signer) Verify(path string, query url.Values, remoteIP string, now time.Time) error {
+	signature, err := base64.RawURLEncoding.DecodeString(query.Get(ParamSignature))
+	if err != nil || len(query[ParamSignature]) != 1 {
+		return ErrorInvalidSignature
+	}
+
+	unsigned := url.Values{}
+	for key, values := range query {
+		if key != ParamSignature {
+			unsigned[key] = values
+		}
+	}
+
+	expected, _ := base64.RawURLEncoding.DecodeString(s.signature(path, unsigned))
+	if !hmac.Equal(signature, expected) {
+		return ErrorInvalidSignature
+	}
+
+	expires, err := strconv.ParseInt(query.Get(ParamExpires), 10, 64)
+	if err != nil {
+		return ErrorInvalidSignature
+	}
+
+	if now.After(time.Unix(expires, 0)) {
+		return ErrorExpired
+	}
+
+	if ip := query.Get(ParamIP); ip != "" && ip != remoteIP {
+		return ErrorIPMismatch
+	}
+
+	return nil
+}
+
+func (s *Signer) signature(path string, query url.Values) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(path))
+	mac.Write([]byte{'?'})
+	// Encode sorts by key, which makes the message independent of the order
+	// the parameters arrive in.
+	mac.Write([]byte(query.Encode()))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,146 @@
+package signedurl_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"file-service/m/internal/signedurl"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSigner(t *testing.T) *signedurl.Signer {
+	signer, err := signedurl.New(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+
+	return signer
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer := newSigner(t)
+	now := time.Unix(1700000000, 0)
+	expires := now.Add(time.Minute)
+
+	query := signer.Sign("/download/abc", url.Values{"disposition": {"attachment"}}, expires)
+
+	t.Run("valid", func(t *testing.T) {
+		assert.NoError(t, signer.Verify("/download/abc", query, "10.0.0.1", now))
+		assert.NoError(t, signer.Verify("/download/abc", query, "10.0.0.1", expires))
+	})
+
+	t.Run("round trip through the query string", func(t *testing.T) {
+		parsed, err := url.ParseQuery(query.Encode())
+		require.NoError(t, err)
+
+		assert.NoError(t, signer.Verify("/download/abc", parsed, "10.0.0.1", now))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		assert.ErrorIs(t, signer.Verify("/download/abc", query, "10.0.0.1", expires.Add(time.Second)), signedurl.ErrorExpired)
+	})
+
+	t.Run("other path", func(t *testing.T) {
+		assert.ErrorIs(t, signer.Verify("/download/abd", query, "10.0.0.1", now), signedurl.ErrorInvalidSignature)
+	})
+
+	t.Run("tampered parameter", func(t *testing.T) {
+		tampered, _ := url.ParseQuery(query.Encode())
+		tampered.Set("disposition", "inline")
+
+		assert.ErrorIs(t, signer.Verify("/download/abc", tampered, "10.0.0.1", now), signedurl.ErrorInvalidSignature)
+	})
+
+	t.Run("added parameter", func(t *testing.T) {
+		tampered, _ := url.ParseQuery(query.Encode())
+		tampered.Set("ip", "10.0.0.1")
+
+		assert.ErrorIs(t, signer.Verify("/download/abc", tampered, "10.0.0.1", now), signedurl.ErrorInvalidSignature)
+	})
+
+	t.Run("extended expiry", func(t *testing.T) {
+		tampered, _ := url.ParseQuery(query.Encode())
+		tampered.Set("expires", "1800000000")
+
+		assert.ErrorIs(t, signer.Verify("/download/abc", tampered, "10.0.0.1", now), signedurl.ErrorInvalidSignature)
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		other, err := signedurl.New(bytes.Repeat([]byte{2}, 32))
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, other.Verify("/download/abc", query, "10.0.0.1", now), signedurl.ErrorInvalidSignature)
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		assert.ErrorIs(t, signer.Verify("/download/abc", url.Values{"expires": {"1800000000"}}, "10.0.0.1", now), signedurl.ErrorInvalidSignature)
+	})
+}
+
+func TestVerifyIP(t *testing.T) {
+	signer := newSigner(t)
+	now := time.Unix(1700000000, 0)
+
+	query := signer.Sign("/download/abc", url.Values{"ip": {"10.0.0.1"}}, now.Add(time.Minute))
+
+	assert.NoError(t, signer.Verify("/download/abc", query, "10.0.0.1", now))
+	assert.ErrorIs(t, signer.Verify("/download/abc", query, "10.0.0.2", now), signedurl.ErrorIPMismatch)
+}
+
+func TestKeys(t *testing.T) {
+	_, err := signedurl.New([]byte("short"))
+	assert.ErrorIs(t, err, signedurl.ErrorInvalidKey)
+
+	key, err := signedurl.ParseKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+	require.NoError(t, err)
+	assert.Len(t, key, 32)
+
+	_, err = signedurl.ParseKey("c2hvcnQ=")
+	assert.ErrorIs(t, err, signedurl.ErrorInvalidKey)
+
+	_, err = signedurl.ParseKey("not base64!")
+	assert.ErrorIs(t, err, signedurl.ErrorInvalidKey)
+}
+
+func TestUploadConstraints(t *testing.T) {
+	c := &signedurl.UploadConstraints{Owner: "alice", MaxSize: 1024, ContentTypes: []string{"image/*", "application/pdf"}, Nonce: "n1"}
+
+	parsed, err := signedurl.ParseUploadConstraints(c.Query())
+	require.NoError(t, err)
+	assert.Equal(t, c, parsed)
+
+	// the expiry comes with the signature
+	signer, err := signedurl.New(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+	expires := time.Unix(1700000000, 0)
+
+	parsed, err = signedurl.ParseUploadConstraints(signer.Sign(signedurl.UploadPath, c.Query(), expires))
+	require.NoError(t, err)
+	assert.Equal(t, expires, parsed.Expires)
+
+	assert.True(t, parsed.AllowsContentType("image/png"))
+	assert.True(t, parsed.AllowsContentType("Application/PDF; name=a.pdf"))
+	assert.False(t, parsed.AllowsContentType("text/html"))
+	assert.False(t, parsed.AllowsContentType(""))
+
+	unrestricted := &signedurl.UploadConstraints{Owner: "alice"}
+	assert.True(t, unrestricted.AllowsContentType("text/html"))
+
+	_, err = signedurl.ParseUploadConstraints(url.Values{"nonce": {"n1"}, "max_size": {"1"}})
+	assert.ErrorIs(t, err, signedurl.ErrorInvalidConstraints)
+
+	// URLs without a nonce could be used any number of times
+	_, err = signedurl.ParseUploadConstraints(url.Values{"owner": {"alice"}})
+	assert.ErrorIs(t, err, signedurl.ErrorInvalidConstraints)
+
+	_, err = signedurl.ParseUploadConstraints(url.Values{"owner": {"alice"}, "nonce": {"n1"}, "max_size": {"-1"}})
+	assert.ErrorIs(t, err, signedurl.ErrorInvalidConstraints)
+
+	assert.True(t, signedurl.ValidContentType("image/png"))
+	assert.True(t, signedurl.ValidContentType("image/*"))
+	assert.False(t, signedurl.ValidContentType("*/*"))
+	assert.False(t, signedurl.ValidContentType("image"))
+	assert.False(t, signedurl.ValidContentType("text/plain; charset=utf-8"))
+}
@@ -0,0 +1,124 @@
+package signedurl
+
+import (
+	"errors"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// UploadPath is the path signed upload URLs are accepted at.
+	UploadPath = "/upload"
+
+	ParamOwner       = "owner"
+	ParamMaxSize     = "max_size"
+	ParamContentType = "content_type"
+	ParamNonce       = "nonce"
+)
+
+var ErrorInvalidConstraints = errors.New("invalid upload constraints")
+
+// UploadConstraints are embedded in signed upload URLs and enforced when the
+// file is saved. A zero MaxSize and empty ContentTypes do not restrict the
+// upload beyond the quota of Owner. Nonce makes the URL single-use, the first
+// file saved with it uses it up. Expires is read from the signed URL, Sign
+// adds it.
+//
+// Files are not organised in folders, so an upload URL cannot be limited to
+// one.
+type UploadConstraints struct {
+	Owner        string
+	MaxSize      int64
+	ContentTypes []string
+	Nonce        string
+	Expires      time.Time
+}
+
+// Query encodes the constraints as query parameters to be signed.
+func (c *UploadConstraints) Query() url.Values {
+	query := url.Values{}
+	query.Set(ParamOwner, c.Owner)
+	query.Set(ParamNonce, c.Nonce)
+
+	if c.MaxSize > 0 {
+		query.Set(ParamMaxSize, strconv.FormatInt(c.MaxSize, 10))
+	}
+
+	for _, contentType := range c.ContentTypes {
+		query.Add(ParamContentType, contentType)
+	}
+
+	return query
+}
+
+// ParseUploadConstraints decodes the constraints of a verified upload URL.
+func ParseUploadConstraints(query url.Values) (*UploadConstraints, error) {
+	c := &UploadConstraints{
+		Owner:        query.Get(ParamOwner),
+		ContentTypes: query[ParamContentType],
+		Nonce:        query.Get(ParamNonce),
+	}
+
+	if c.Owner == "" || c.Nonce == "" {
+		return nil, ErrorInvalidConstraints
+	}
+
+	if value := query.Get(ParamExpires); value != "" {
+		expires, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, ErrorInvalidConstraints
+		}
+
+		c.Expires = time.Unix(expires, 0)
+	}
+
+	if value := query.Get(ParamMaxSize); value != "" {
+		maxSize, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || maxSize <= 0 {
+			return nil, ErrorInvalidConstraints
+		}
+
+		c.MaxSize = maxSize
+	}
+
+	return c, nil
+}
+
+// ValidContentType reports whether pattern is a media type such as
+// "image/png" or a wildcard such as "image/*".
+func ValidContentType(pattern string) bool {
+	mediaType, params, err := mime.ParseMediaType(pattern)
+	if err != nil || len(params) != 0 {
+		return false
+	}
+
+	major, minor, ok := strings.Cut(mediaType, "/")
+
+	return ok && major != "" && major != "*" && minor != ""
+}
+
+// AllowsContentType reports whether a file of contentType may be uploaded.
+func (c *UploadConstraints) AllowsContentType(contentType string) bool {
+	if len(c.ContentTypes) == 0 {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	major, _, _ := strings.Cut(mediaType, "/")
+
+	for _, pattern := range c.ContentTypes {
+		pattern = strings.ToLower(pattern)
+		if pattern == mediaType || pattern == major+"/*" {
+			return true
+		}
+	}
+
+	return false
+}
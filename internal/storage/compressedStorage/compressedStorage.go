@@ -0,0 +1,124 @@
+package compressedstorage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"file-service/m/internal/storage"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	EncodingGzip = "gzip"
+
+	sniffLen = 512
+)
+
+// compressibleTypes are matched as prefixes against the sniffed MIME type.
+var compressibleTypes = []string{
+	"text/",
+	"application/json",
+	"application/xml",
+	"application/javascript",
+	"image/svg+xml",
+}
+
+// Storage gzips files whose sniffed content type compresses well before
+// they reach the backend and records the encoding in storage.Meta. Files
+// smaller than minSize are stored as they are.
+type Storage struct {
+	storage.Backend
+	minSize int64
+}
+
+func New(backend storage.Backend, minSize int64) *Storage {
+	return &Storage{
+		Backend: backend,
+		minSize: minSize,
+	}
+}
+
+func (s *Storage) SaveFile(ctx context.Context, file io.Reader, name string, meta *storage.Meta) error {
+	buffered := bufio.NewReaderSize(file, sniffLen)
+	head, err := buffered.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	if meta.Size < s.minSize || !compressible(http.DetectContentType(head)) {
+		return s.Backend.SaveFile(ctx, buffered, name, meta)
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		gz := gzip.NewWriter(pw)
+		_, err := io.Copy(gz, buffered)
+		if err == nil {
+			err = gz.Close()
+		}
+
+		pw.CloseWithError(err)
+	}()
+
+	err = s.Backend.SaveFile(ctx, pr, name, meta)
+	// unblocks the compressor if the backend gave up early
+	pr.Close()
+	<-done
+
+	if err != nil {
+		return err
+	}
+
+	meta.Encoding = EncodingGzip
+
+	return nil
+}
+
+func (s *Storage) GetFile(ctx context.Context, name string, meta storage.Meta) ([]byte, error) {
+	const op = "compressedstorage.GetFile"
+
+	data, err := s.Backend.GetFile(ctx, name, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if meta.Encoding == "" || meta.KeepEncoding {
+		return data, nil
+	}
+
+	if meta.Encoding != EncodingGzip {
+		return nil, fmt.Errorf("%s: unsupported encoding %q", op, meta.Encoding)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer gz.Close()
+
+	plain, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return plain, nil
+}
+
+func compressible(contentType string) bool {
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,91 @@
+package compressedstorage_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"file-service/m/internal/storage"
+	compressedstorage "file-service/m/internal/storage/compressedStorage"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryBackend struct {
+	files map[string][]byte
+}
+
+func (m *memoryBackend) GetStoragePath() string {
+	return "memory"
+}
+
+func (m *memoryBackend) GetStorageType() string {
+	return "memory"
+}
+
+func (m *memoryBackend) SaveFile(_ context.Context, file io.Reader, name string, _ *storage.Meta) error {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	m.files[name] = data
+
+	return nil
+}
+
+func (m *memoryBackend) GetFile(_ context.Context, name string, _ storage.Meta) ([]byte, error) {
+	return m.files[name], nil
+}
+
+func (m *memoryBackend) DeleteFile(_ context.Context, name string) error {
+	delete(m.files, name)
+
+	return nil
+}
+
+func TestStorage(t *testing.T) {
+	backend := &memoryBackend{files: map[string][]byte{}}
+	s := compressedstorage.New(backend, 64)
+
+	csv := []byte(strings.Repeat("id,name,created_at\n1,report,2024-01-01\n", 200))
+	binary := make([]byte, 4096)
+	_, err := rand.Read(binary)
+	require.NoError(t, err)
+
+	t.Run("text is compressed", func(t *testing.T) {
+		meta := storage.Meta{Size: int64(len(csv))}
+		require.NoError(t, s.SaveFile(context.Background(), bytes.NewReader(csv), "csv", &meta))
+
+		assert.Equal(t, compressedstorage.EncodingGzip, meta.Encoding)
+		assert.Less(t, len(backend.files["csv"]), len(csv)/10)
+
+		got, err := s.GetFile(context.Background(), "csv", meta)
+		require.NoError(t, err)
+		assert.Equal(t, csv, got)
+
+		meta.KeepEncoding = true
+		raw, err := s.GetFile(context.Background(), "csv", meta)
+		require.NoError(t, err)
+		assert.Equal(t, backend.files["csv"], raw)
+	})
+
+	t.Run("binary is stored as is", func(t *testing.T) {
+		meta := storage.Meta{Size: int64(len(binary))}
+		require.NoError(t, s.SaveFile(context.Background(), bytes.NewReader(binary), "binary", &meta))
+
+		assert.Equal(t, "", meta.Encoding)
+		assert.Equal(t, binary, backend.files["binary"])
+	})
+
+	t.Run("small files are stored as is", func(t *testing.T) {
+		meta := storage.Meta{Size: 5}
+		require.NoError(t, s.SaveFile(context.Background(), bytes.NewReader([]byte("small")), "small", &meta))
+
+		assert.Equal(t, "", meta.Encoding)
+		assert.Equal(t, []byte("small"), backend.files["small"])
+	})
+}
@@ -0,0 +1,129 @@
+package encryptedstorage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"file-service/m/internal/storage"
+	"fmt"
+	"io"
+)
+
+// CustomerKeyId marks files whose data key is wrapped with a key supplied by
+// the client instead of a master key.
+const CustomerKeyId = "customer"
+
+var ErrorCustomerKeyRequired = errors.New("customer encryption key required")
+
+// Storage encrypts file contents before they reach the backend. Every file
+// gets its own random data key which is wrapped with the client supplied key
+// when there is one, or the active master key otherwise, and returned through
+// storage.Meta to be kept in the files row. Without a keyring only files with
+// a customer key are encrypted.
+type Storage struct {
+	storage.Backend
+	keyring   *Keyring
+	chunkSize int
+}
+
+func New(backend storage.Backend, keyring *Keyring) *Storage {
+	return &Storage{
+		Backend:   backend,
+		keyring:   keyring,
+		chunkSize: defaultChunkSize,
+	}
+}
+
+func (s *Storage) SaveFile(ctx context.Context, file io.Reader, name string, meta *storage.Meta) error {
+	const op = "encryptedstorage.SaveFile"
+
+	if meta.CustomerKey == nil && s.keyring == nil {
+		return s.Backend.SaveFile(ctx, file, name, meta)
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	keyId, wrappedKey, err := s.wrap(dataKey, meta.CustomerKey)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	encrypted, err := newEncryptReader(file, dataKey, s.chunkSize)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.Backend.SaveFile(ctx, encrypted, name, meta); err != nil {
+		return err
+	}
+
+	meta.KeyId = keyId
+	meta.WrappedKey = wrappedKey
+
+	return nil
+}
+
+func (s *Storage) GetFile(ctx context.Context, name string, meta storage.Meta) ([]byte, error) {
+	const op = "encryptedstorage.GetFile"
+
+	data, err := s.Backend.GetFile(ctx, name, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files written before encryption was enabled have no key and are
+	// returned as they are.
+	if meta.KeyId == "" {
+		return data, nil
+	}
+
+	dataKey, err := s.unwrap(meta)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	reader, err := newDecryptReader(bytes.NewReader(data), int64(len(data)), dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	plain := make([]byte, reader.Size())
+	if _, err := reader.ReadAt(plain, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return plain, nil
+}
+
+func (s *Storage) wrap(dataKey []byte, customerKey []byte) (string, []byte, error) {
+	if customerKey != nil {
+		wrapped, err := wrapKey(customerKey, dataKey, []byte(CustomerKeyId))
+		if err != nil {
+			return "", nil, err
+		}
+
+		return CustomerKeyId, wrapped, nil
+	}
+
+	return s.keyring.Wrap(dataKey)
+}
+
+func (s *Storage) unwrap(meta storage.Meta) ([]byte, error) {
+	if meta.KeyId == CustomerKeyId {
+		if meta.CustomerKey == nil {
+			return nil, ErrorCustomerKeyRequired
+		}
+
+		return unwrapKey(meta.CustomerKey, meta.WrappedKey, []byte(CustomerKeyId))
+	}
+
+	if s.keyring == nil {
+		return nil, fmt.Errorf("key %q: %w", meta.KeyId, ErrorUnknownKey)
+	}
+
+	return s.keyring.Unwrap(meta.KeyId, meta.WrappedKey)
+}
@@ -0,0 +1,246 @@
+package encryptedstorage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"file-service/m/internal/config"
+	"file-service/m/internal/storage"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryBackend struct {
+	files map[string][]byte
+}
+
+func (m *memoryBackend) GetStoragePath() string {
+	return "memory"
+}
+
+func (m *memoryBackend) GetStorageType() string {
+	return "memory"
+}
+
+func (m *memoryBackend) SaveFile(_ context.Context, file io.Reader, name string, _ *storage.Meta) error {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	m.files[name] = data
+
+	return nil
+}
+
+func (m *memoryBackend) GetFile(_ context.Context, name string, _ storage.Meta) ([]byte, error) {
+	return m.files[name], nil
+}
+
+func (m *memoryBackend) DeleteFile(_ context.Context, name string) error {
+	delete(m.files, name)
+
+	return nil
+}
+
+func newKey(t *testing.T) []byte {
+	key := make([]byte, keySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	return key
+}
+
+func configWithKeys(keys map[string][]byte, activeId string) config.EncryptionConfig {
+	var pairs []string
+	for id, key := range keys {
+		pairs = append(pairs, id+":"+base64.StdEncoding.EncodeToString(key))
+	}
+
+	return config.EncryptionConfig{
+		MasterKeys:  strings.Join(pairs, ","),
+		ActiveKeyId: activeId,
+	}
+}
+
+func newStorage(t *testing.T, chunkSize int) (*Storage, *memoryBackend, *Keyring) {
+	keyring, err := NewKeyring(map[string][]byte{"k1": newKey(t)}, "k1")
+	require.NoError(t, err)
+
+	backend := &memoryBackend{files: map[string][]byte{}}
+	s := New(backend, keyring)
+	s.chunkSize = chunkSize
+
+	return s, backend, keyring
+}
+
+func TestRoundTrip(t *testing.T) {
+	s, backend, _ := newStorage(t, 16)
+
+	for _, size := range []int{0, 1, 15, 16, 17, 32, 100} {
+		plain := make([]byte, size)
+		_, err := rand.Read(plain)
+		require.NoError(t, err)
+
+		var meta storage.Meta
+		require.NoError(t, s.SaveFile(context.Background(), bytes.NewReader(plain), "file", &meta))
+		assert.Equal(t, "k1", meta.KeyId)
+		assert.NotEmpty(t, meta.WrappedKey)
+
+		if size >= 8 {
+			assert.False(t, bytes.Contains(backend.files["file"], plain), "plaintext stored for size %d", size)
+		}
+
+		got, err := s.GetFile(context.Background(), "file", meta)
+		require.NoError(t, err)
+		assert.Equal(t, plain, append([]byte{}, got...), "size %d", size)
+	}
+}
+
+func TestReadAt(t *testing.T) {
+	s, backend, keyring := newStorage(t, 16)
+
+	plain := []byte("the quick brown fox jumps over the lazy dog")
+	var meta storage.Meta
+	require.NoError(t, s.SaveFile(context.Background(), bytes.NewReader(plain), "file", &meta))
+
+	dataKey, err := keyring.Unwrap(meta.KeyId, meta.WrappedKey)
+	require.NoError(t, err)
+
+	sealed := backend.files["file"]
+	reader, err := newDecryptReader(bytes.NewReader(sealed), int64(len(sealed)), dataKey)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(plain)), reader.Size())
+
+	buf := make([]byte, 10)
+	n, err := reader.ReadAt(buf, 12)
+	require.NoError(t, err)
+	assert.Equal(t, plain[12:22], buf[:n])
+
+	n, err = reader.ReadAt(buf, int64(len(plain))-4)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, plain[len(plain)-4:], buf[:n])
+}
+
+func TestTampering(t *testing.T) {
+	s, backend, _ := newStorage(t, 16)
+
+	plain := bytes.Repeat([]byte("a"), 40)
+	var meta storage.Meta
+	require.NoError(t, s.SaveFile(context.Background(), bytes.NewReader(plain), "file", &meta))
+	sealed := append([]byte{}, backend.files["file"]...)
+
+	t.Run("flipped bit", func(t *testing.T) {
+		backend.files["file"] = append([]byte{}, sealed...)
+		backend.files["file"][headerSize+3] ^= 1
+
+		_, err := s.GetFile(context.Background(), "file", meta)
+		assert.ErrorIs(t, err, ErrorCorrupted)
+	})
+
+	t.Run("truncated at chunk boundary", func(t *testing.T) {
+		backend.files["file"] = append([]byte{}, sealed[:headerSize+2*(16+16)]...)
+
+		_, err := s.GetFile(context.Background(), "file", meta)
+		assert.ErrorIs(t, err, ErrorCorrupted)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		other, _, _ := newStorage(t, 16)
+		var otherMeta storage.Meta
+		require.NoError(t, other.SaveFile(context.Background(), bytes.NewReader(plain), "other", &otherMeta))
+
+		backend.files["file"] = sealed
+		_, err := s.GetFile(context.Background(), "file", storage.Meta{KeyId: "k1", WrappedKey: otherMeta.WrappedKey})
+		assert.Error(t, err)
+	})
+}
+
+func TestUnencryptedFile(t *testing.T) {
+	s, backend, _ := newStorage(t, 16)
+	backend.files["legacy"] = []byte("plain")
+
+	got, err := s.GetFile(context.Background(), "legacy", storage.Meta{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("plain"), got)
+}
+
+func TestRewrap(t *testing.T) {
+	oldKey, newKey := newKey(t), newKey(t)
+
+	oldKeyring, err := NewKeyring(map[string][]byte{"old": oldKey}, "old")
+	require.NoError(t, err)
+	backend := &memoryBackend{files: map[string][]byte{}}
+
+	var meta storage.Meta
+	require.NoError(t, New(backend, oldKeyring).SaveFile(context.Background(), bytes.NewReader([]byte("secret")), "file", &meta))
+	sealed := append([]byte{}, backend.files["file"]...)
+
+	rotated, err := NewKeyringFromConfig(configWithKeys(map[string][]byte{"old": oldKey, "new": newKey}, "new"))
+	require.NoError(t, err)
+
+	keyId, wrapped, err := rotated.Rewrap(meta.KeyId, meta.WrappedKey)
+	require.NoError(t, err)
+	assert.Equal(t, "new", keyId)
+
+	newKeyring, err := NewKeyring(map[string][]byte{"new": newKey}, "new")
+	require.NoError(t, err)
+
+	got, err := New(backend, newKeyring).GetFile(context.Background(), "file", storage.Meta{KeyId: keyId, WrappedKey: wrapped})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), got)
+	assert.Equal(t, sealed, backend.files["file"])
+}
+
+func TestParseKeys(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(make([]byte, keySize))
+
+	keys, err := ParseKeys("a:" + key + ", b:" + key)
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	_, err = ParseKeys("a:" + key + ",a:" + key)
+	assert.ErrorIs(t, err, ErrorInvalidKey)
+
+	_, err = ParseKeys("nokey")
+	assert.ErrorIs(t, err, ErrorInvalidKey)
+
+	_, err = NewKeyring(map[string][]byte{"a": make([]byte, 16)}, "a")
+	assert.ErrorIs(t, err, ErrorInvalidKey)
+
+	_, err = NewKeyring(map[string][]byte{"a": make([]byte, keySize)}, "b")
+	assert.ErrorIs(t, err, ErrorUnknownKey)
+}
+
+func TestCustomerKey(t *testing.T) {
+	backend := &memoryBackend{files: map[string][]byte{}}
+	s := New(backend, nil)
+	customerKey := newKey(t)
+
+	var plain storage.Meta
+	require.NoError(t, s.SaveFile(context.Background(), bytes.NewReader([]byte("plain")), "plain", &plain))
+	assert.Equal(t, "", plain.KeyId)
+	assert.Equal(t, []byte("plain"), backend.files["plain"])
+
+	meta := storage.Meta{CustomerKey: customerKey}
+	require.NoError(t, s.SaveFile(context.Background(), bytes.NewReader([]byte("secret")), "file", &meta))
+	assert.Equal(t, CustomerKeyId, meta.KeyId)
+
+	got, err := s.GetFile(context.Background(), "file", meta)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), got)
+
+	_, err = s.GetFile(context.Background(), "file", storage.Meta{KeyId: meta.KeyId, WrappedKey: meta.WrappedKey})
+	assert.ErrorIs(t, err, ErrorCustomerKeyRequired)
+
+	_, err = s.GetFile(context.Background(), "file", storage.Meta{KeyId: meta.KeyId, WrappedKey: meta.WrappedKey, CustomerKey: newKey(t)})
+	assert.Error(t, err)
+
+	_, err = NewKeyring(map[string][]byte{CustomerKeyId: newKey(t)}, CustomerKeyId)
+	assert.ErrorIs(t, err, ErrorInvalidKey)
+}
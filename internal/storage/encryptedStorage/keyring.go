@@ -0,0 +1,168 @@
+package encryptedstorage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"file-service/m/internal/config"
+	"fmt"
+	"strings"
+)
+
+const keySize = 32
+
+var (
+	ErrorUnknownKey = errors.New("unknown master key")
+	ErrorInvalidKey = errors.New("invalid master key")
+)
+
+// Keyring holds the master keys used to wrap per-file data keys. New data
+// keys are always wrapped with the active key, the others are kept so that
+// files wrapped before a rotation can still be read and re-wrapped.
+type Keyring struct {
+	activeId string
+	keys     map[string][]byte
+}
+
+func NewKeyring(keys map[string][]byte, activeId string) (*Keyring, error) {
+	if _, ok := keys[activeId]; !ok {
+		return nil, fmt.Errorf("active key %q: %w", activeId, ErrorUnknownKey)
+	}
+
+	for id, key := range keys {
+		if id == CustomerKeyId {
+			return nil, fmt.Errorf("key id %q is reserved: %w", id, ErrorInvalidKey)
+		}
+
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key %q must be %d bytes: %w", id, keySize, ErrorInvalidKey)
+		}
+	}
+
+	return &Keyring{
+		activeId: activeId,
+		keys:     keys,
+	}, nil
+}
+
+// NewKeyringFromConfig builds the keyring described by the ENCRYPTION_*
+// settings.
+func NewKeyringFromConfig(cfg config.EncryptionConfig) (*Keyring, error) {
+	keys, err := ParseKeys(cfg.MasterKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeyring(keys, cfg.ActiveKeyId)
+}
+
+// ParseKeys parses master keys in the "id:base64key,id:base64key" form used
+// by the ENCRYPTION_MASTER_KEYS setting.
+func ParseKeys(value string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(pair, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("malformed key %q: %w", pair, ErrorInvalidKey)
+		}
+
+		if _, ok := keys[id]; ok {
+			return nil, fmt.Errorf("duplicate key %q: %w", id, ErrorInvalidKey)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, ErrorInvalidKey)
+		}
+
+		keys[id] = key
+	}
+
+	return keys, nil
+}
+
+func (k *Keyring) ActiveKeyId() string {
+	return k.activeId
+}
+
+// Wrap encrypts a data key with the active master key.
+func (k *Keyring) Wrap(dataKey []byte) (string, []byte, error) {
+	wrapped, err := wrapKey(k.keys[k.activeId], dataKey, []byte(k.activeId))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return k.activeId, wrapped, nil
+}
+
+// Unwrap decrypts a data key that was wrapped with the master key keyId.
+func (k *Keyring) Unwrap(keyId string, wrapped []byte) ([]byte, error) {
+	key, ok := k.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("key %q: %w", keyId, ErrorUnknownKey)
+	}
+
+	return unwrapKey(key, wrapped, []byte(keyId))
+}
+
+// Rewrap moves a wrapped data key to the active master key. The file
+// contents stay encrypted with the same data key.
+func (k *Keyring) Rewrap(keyId string, wrapped []byte) (string, []byte, error) {
+	dataKey, err := k.Unwrap(keyId, wrapped)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return k.Wrap(dataKey)
+}
+
+func wrapKey(kek []byte, dataKey []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, dataKey, additionalData), nil
+}
+
+func unwrapKey(kek []byte, wrapped []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(wrapped) < aead.NonceSize() {
+		return nil, ErrorInvalidKey
+	}
+
+	nonce, ciphertext := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+
+	dataKey, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	return dataKey, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
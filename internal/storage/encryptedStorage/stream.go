@@ -0,0 +1,215 @@
+package encryptedstorage
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// Encrypted files are stored as a header followed by independently sealed
+// chunks, so any plaintext offset maps to a single chunk that can be read
+// and authenticated on its own:
+//
+//	header: magic (4) | chunk size, uint32 BE (4) | nonce prefix (7)
+//	chunk:  AES-256-GCM(plaintext[i*chunkSize:(i+1)*chunkSize]) (+16 byte tag)
+//
+// Chunk nonces are the prefix, the big endian chunk index and a flag marking
+// the final chunk, and the header is authenticated with every chunk, which
+// stops chunks from being reordered, dropped or the file from being truncated.
+const (
+	defaultChunkSize = 64 << 10
+	prefixSize       = 7
+	headerSize       = 4 + 4 + prefixSize
+)
+
+var magic = []byte("FSE1")
+
+var ErrorCorrupted = errors.New("encrypted file is corrupted")
+
+func chunkNonce(prefix []byte, index uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[prefixSize:], index)
+	if last {
+		nonce[11] = 1
+	}
+
+	return nonce
+}
+
+type encryptReader struct {
+	src     io.Reader
+	aead    cipher.AEAD
+	header  []byte
+	chunk   []byte
+	sealed  []byte
+	pending []byte
+	index   uint32
+	done    bool
+}
+
+func newEncryptReader(src io.Reader, key []byte, chunkSize int) (*encryptReader, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	binary.BigEndian.PutUint32(header[4:], uint32(chunkSize))
+	if _, err := rand.Read(header[8:]); err != nil {
+		return nil, err
+	}
+
+	return &encryptReader{
+		src:     src,
+		aead:    aead,
+		header:  header,
+		chunk:   make([]byte, chunkSize),
+		sealed:  make([]byte, 0, chunkSize+aead.Overhead()),
+		pending: header,
+	}, nil
+}
+
+func (e *encryptReader) Read(p []byte) (int, error) {
+	for len(e.pending) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+
+		if err := e.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, e.pending)
+	e.pending = e.pending[n:]
+
+	return n, nil
+}
+
+func (e *encryptReader) next() error {
+	n, err := io.ReadFull(e.src, e.chunk)
+	last := err == io.EOF || err == io.ErrUnexpectedEOF
+	if err != nil && !last {
+		return err
+	}
+
+	if !last && e.index == math.MaxUint32 {
+		return errors.New("file is too large to encrypt")
+	}
+
+	e.pending = e.aead.Seal(e.sealed[:0], chunkNonce(e.header[8:], e.index, last), e.chunk[:n], e.header)
+	e.index++
+	e.done = last
+
+	return nil
+}
+
+type decryptReader struct {
+	src       io.ReaderAt
+	aead      cipher.AEAD
+	header    []byte
+	chunkSize int64
+	bodySize  int64
+	chunks    int64
+	size      int64
+}
+
+func newDecryptReader(src io.ReaderAt, srcSize int64, key []byte) (*decryptReader, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, headerSize)
+	if _, err := src.ReadAt(header, 0); err != nil {
+		return nil, ErrorCorrupted
+	}
+
+	if !bytes.Equal(header[:4], magic) {
+		return nil, ErrorCorrupted
+	}
+
+	chunkSize := int64(binary.BigEndian.Uint32(header[4:]))
+	overhead := int64(aead.Overhead())
+	sealedSize := chunkSize + overhead
+	bodySize := srcSize - headerSize
+
+	if chunkSize == 0 || bodySize < overhead {
+		return nil, ErrorCorrupted
+	}
+
+	chunks := (bodySize + sealedSize - 1) / sealedSize
+	lastSize := bodySize - (chunks-1)*sealedSize
+	if lastSize < overhead || chunks-1 > math.MaxUint32 {
+		return nil, ErrorCorrupted
+	}
+
+	return &decryptReader{
+		src:       src,
+		aead:      aead,
+		header:    header,
+		chunkSize: chunkSize,
+		bodySize:  bodySize,
+		chunks:    chunks,
+		size:      (chunks-1)*chunkSize + lastSize - overhead,
+	}, nil
+}
+
+// Size returns the plaintext size.
+func (d *decryptReader) Size() int64 {
+	return d.size
+}
+
+func (d *decryptReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	n := 0
+	for n < len(p) && off < d.size {
+		index := off / d.chunkSize
+
+		plain, err := d.openChunk(index)
+		if err != nil {
+			return n, err
+		}
+
+		copied := copy(p[n:], plain[off-index*d.chunkSize:])
+		n += copied
+		off += int64(copied)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (d *decryptReader) openChunk(index int64) ([]byte, error) {
+	sealedSize := d.chunkSize + int64(d.aead.Overhead())
+	start := index * sealedSize
+	length := min(sealedSize, d.bodySize-start)
+
+	sealed := make([]byte, length)
+	if n, err := d.src.ReadAt(sealed, headerSize+start); n != len(sealed) {
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	last := index == d.chunks-1
+	plain, err := d.aead.Open(sealed[:0], chunkNonce(d.header[8:], uint32(index), last), sealed, d.header)
+	if err != nil {
+		return nil, ErrorCorrupted
+	}
+
+	return plain, nil
+}
@@ -0,0 +1,78 @@
+package instrumentedstorage
+
+import (
+	"context"
+	"file-service/m/internal/metrics"
+	"file-service/m/internal/storage"
+	"file-service/m/internal/tracing"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Storage records the latency and errors of every operation of the
+// backend, and the bytes that are saved and read. Every operation is traced
+// as a span of the request.
+type Storage struct {
+	storage.Backend
+	metrics *metrics.Metrics
+}
+
+func New(backend storage.Backend, m *metrics.Metrics) *Storage {
+	return &Storage{
+		Backend: backend,
+		metrics: m,
+	}
+}
+
+func (s *Storage) SaveFile(ctx context.Context, file io.Reader, name string, meta *storage.Meta) (err error) {
+	ctx, span := s.startSpan(ctx, "save", name)
+	defer tracing.End(span, &err)
+
+	start := time.Now()
+	err = s.Backend.SaveFile(ctx, file, name, meta)
+	s.metrics.ObserveStorage(s.GetStorageType(), "save", time.Since(start), err)
+
+	if err == nil {
+		s.metrics.AddUploaded(meta.Size)
+		span.SetAttributes(attribute.Int64("storage.size", meta.Size))
+	}
+
+	return err
+}
+
+func (s *Storage) GetFile(ctx context.Context, name string, meta storage.Meta) (_ []byte, err error) {
+	ctx, span := s.startSpan(ctx, "get", name)
+	defer tracing.End(span, &err)
+
+	start := time.Now()
+	data, err := s.Backend.GetFile(ctx, name, meta)
+	s.metrics.ObserveStorage(s.GetStorageType(), "get", time.Since(start), err)
+
+	if err == nil {
+		s.metrics.AddDownloaded(int64(len(data)))
+		span.SetAttributes(attribute.Int("storage.size", len(data)))
+	}
+
+	return data, err
+}
+
+func (s *Storage) DeleteFile(ctx context.Context, name string) (err error) {
+	ctx, span := s.startSpan(ctx, "delete", name)
+	defer tracing.End(span, &err)
+
+	start := time.Now()
+	err = s.Backend.DeleteFile(ctx, name)
+	s.metrics.ObserveStorage(s.GetStorageType(), "delete", time.Since(start), err)
+
+	return err
+}
+
+func (s *Storage) startSpan(ctx context.Context, operation string, name string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "storage."+operation, trace.WithAttributes(
+		attribute.String("storage.backend", s.GetStorageType()),
+		attribute.String("storage.name", name),
+	))
+}
@@ -0,0 +1,90 @@
+package instrumentedstorage_test
+
+import (
+	"context"
+	"errors"
+	"file-service/m/internal/metrics"
+	"file-service/m/internal/storage"
+	instrumentedstorage "file-service/m/internal/storage/instrumentedStorage"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryBackend struct {
+	files map[string][]byte
+}
+
+func (m *memoryBackend) GetStoragePath() string {
+	return "memory"
+}
+
+func (m *memoryBackend) GetStorageType() string {
+	return "memory"
+}
+
+func (m *memoryBackend) SaveFile(_ context.Context, file io.Reader, name string, _ *storage.Meta) error {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	m.files[name] = data
+
+	return nil
+}
+
+func (m *memoryBackend) GetFile(_ context.Context, name string, _ storage.Meta) ([]byte, error) {
+	data, ok := m.files[name]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+
+	return data, nil
+}
+
+func (m *memoryBackend) DeleteFile(_ context.Context, name string) error {
+	delete(m.files, name)
+
+	return nil
+}
+
+func TestStorage(t *testing.T) {
+	m := metrics.New()
+	s := instrumentedstorage.New(&memoryBackend{files: map[string][]byte{}}, m)
+
+	require.NoError(t, s.SaveFile(context.Background(), strings.NewReader("hello"), "a", &storage.Meta{Size: 5}))
+
+	data, err := s.GetFile(context.Background(), "a", storage.Meta{})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	_, err = s.GetFile(context.Background(), "b", storage.Meta{})
+	assert.Error(t, err)
+
+	require.NoError(t, s.DeleteFile(context.Background(), "a"))
+
+	out := scrape(t, m)
+
+	assert.Contains(t, out, `file_service_transferred_bytes_total{direction="upload"} 5`)
+	assert.Contains(t, out, `file_service_transferred_bytes_total{direction="download"} 5`)
+	assert.Contains(t, out, `file_service_storage_operation_duration_seconds_count{backend="memory",operation="save"} 1`)
+	assert.Contains(t, out, `file_service_storage_operation_duration_seconds_count{backend="memory",operation="get"} 2`)
+	assert.Contains(t, out, `file_service_storage_operation_duration_seconds_count{backend="memory",operation="delete"} 1`)
+	assert.Contains(t, out, `file_service_storage_operation_errors_total{backend="memory",operation="get"} 1`)
+	assert.NotContains(t, out, `file_service_storage_operation_errors_total{backend="memory",operation="save"}`)
+}
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	return w.Body.String()
+}
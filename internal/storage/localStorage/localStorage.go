@@ -1,15 +1,25 @@
 package localstorage
 
 import (
+	"context"
+	"errors"
+	"file-service/m/internal/storage"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrorInvalidName = errors.New("invalid file name")
+	ErrorOutsideRoot = errors.New("file path is outside of storage root")
 )
 
 type Storage struct {
 	StoragePath string
 	StorageType string
+	root        string
 }
 
 func New(storagePath string) (*Storage, error) {
@@ -19,9 +29,20 @@ func New(storagePath string) (*Storage, error) {
 		return nil, fmt.Errorf("failed to create storage directory: %w", err)
 	}
 
+	root, err := filepath.Abs(storagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve storage directory: %w", err)
+	}
+
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve storage directory: %w", err)
+	}
+
 	return &Storage{
 		StoragePath: storagePath,
 		StorageType: "local",
+		root:        root,
 	}, nil
 }
 
@@ -33,29 +54,78 @@ func (s *Storage) GetStorageType() string {
 	return s.StorageType
 }
 
-func (s *Storage) createFilePath(name string) string {
-	return fmt.Sprintf("%s/%s", s.StoragePath, name)
+// createFilePath resolves name inside the storage root. Names are expected to
+// be generated by the service, so anything that is not a plain file name is
+// refused instead of being cleaned up.
+func (s *Storage) createFilePath(name string) (string, error) {
+	if name == "" || name == "." || name == ".." ||
+		strings.ContainsAny(name, "/\\\x00") || name != filepath.Base(name) {
+		return "", ErrorInvalidName
+	}
+
+	path := filepath.Join(s.root, name)
+
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", ErrorOutsideRoot
+	}
+
+	return path, nil
 }
 
-func (s *Storage) SaveFile(file multipart.File, name string) error {
-	dst, err := os.Create(s.createFilePath(name))
+// checkRegularFile refuses symlinks and other special files so that an entry
+// planted in the storage directory cannot point reads or deletes elsewhere.
+func checkRegularFile(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return ErrorOutsideRoot
+	}
 
+	return nil
+}
+
+func (s *Storage) SaveFile(ctx context.Context, file io.Reader, name string, _ *storage.Meta) error {
+	path, err := s.createFilePath(name)
 	if err != nil {
 		return err
 	}
 
-	if _, err := io.Copy(dst, file); err != nil {
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
 		return err
 	}
 
-	defer dst.Close()
+	if _, err := io.Copy(dst, storage.ContextReader(ctx, file)); err != nil {
+		dst.Close()
+		os.Remove(path)
+		return err
+	}
 
-	return nil
+	return dst.Close()
 }
 
-func (s *Storage) GetFile(name string) ([]byte, error) {
-	buf, err := os.ReadFile(s.createFilePath(name))
+func (s *Storage) GetFile(ctx context.Context, name string, _ storage.Meta) ([]byte, error) {
+	path, err := s.createFilePath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRegularFile(path); err != nil {
+		return nil, err
+	}
 
+	src, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer src.Close()
+
+	buf, err := io.ReadAll(storage.ContextReader(ctx, src))
 	if err != nil {
 		return nil, err
 	}
@@ -63,8 +133,21 @@ func (s *Storage) GetFile(name string) ([]byte, error) {
 	return buf, nil
 }
 
-func (s *Storage) DeleteFile(name string) error {
-	err := os.Remove(s.createFilePath(name))
+func (s *Storage) DeleteFile(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	path, err := s.createFilePath(name)
+	if err != nil {
+		return err
+	}
+
+	if err := checkRegularFile(path); err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
 
 	if err != nil {
 		return err
@@ -0,0 +1,116 @@
+package localstorage_test
+
+import (
+	"context"
+	"file-service/m/internal/storage"
+	localstorage "file-service/m/internal/storage/localStorage"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type file struct {
+	*strings.Reader
+}
+
+func (f file) Close() error {
+	return nil
+}
+
+func newFile(content string) file {
+	return file{strings.NewReader(content)}
+}
+
+func TestStorage(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "files")
+	local, err := localstorage.New(root)
+	require.NoError(t, err)
+	var meta storage.Meta
+
+	t.Run("save get delete", func(t *testing.T) {
+		require.NoError(t, local.SaveFile(context.Background(), newFile("test"), "name", &meta))
+
+		data, err := local.GetFile(context.Background(), "name", meta)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("test"), data)
+
+		require.NoError(t, local.DeleteFile(context.Background(), "name"))
+		_, err = os.Stat(filepath.Join(root, "name"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("existing file is not overwritten", func(t *testing.T) {
+		require.NoError(t, local.SaveFile(context.Background(), newFile("first"), "twice", &meta))
+		assert.Error(t, local.SaveFile(context.Background(), newFile("second"), "twice", &meta))
+
+		data, err := local.GetFile(context.Background(), "twice", meta)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("first"), data)
+	})
+
+	t.Run("hostile names", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "secret"), []byte("secret"), 0o600))
+
+		names := []string{
+			"",
+			".",
+			"..",
+			"../secret",
+			"../../etc/passwd",
+			"/etc/passwd",
+			`..\secret`,
+			"sub/name",
+			"name\x00.txt",
+		}
+
+		for _, name := range names {
+			assert.ErrorIs(t, local.SaveFile(context.Background(), newFile("x"), name, &meta), localstorage.ErrorInvalidName, name)
+
+			_, err := local.GetFile(context.Background(), name, meta)
+			assert.ErrorIs(t, err, localstorage.ErrorInvalidName, name)
+
+			assert.ErrorIs(t, local.DeleteFile(context.Background(), name), localstorage.ErrorInvalidName, name)
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, "secret"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("secret"), data)
+	})
+
+	t.Run("symlink out of root", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "target"), []byte("target"), 0o600))
+		require.NoError(t, os.Symlink(filepath.Join(dir, "target"), filepath.Join(root, "link")))
+
+		_, err := local.GetFile(context.Background(), "link", meta)
+		assert.ErrorIs(t, err, localstorage.ErrorOutsideRoot)
+		assert.ErrorIs(t, local.DeleteFile(context.Background(), "link"), localstorage.ErrorOutsideRoot)
+		assert.Error(t, local.SaveFile(context.Background(), newFile("x"), "link", &meta))
+
+		data, err := os.ReadFile(filepath.Join(dir, "target"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("target"), data)
+	})
+
+	t.Run("cancelled requests", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.ErrorIs(t, local.SaveFile(ctx, newFile("data"), "cancelled", &meta), context.Canceled)
+		_, err := os.Stat(filepath.Join(root, "cancelled"))
+		assert.True(t, os.IsNotExist(err), "partial file is removed")
+
+		require.NoError(t, local.SaveFile(context.Background(), newFile("data"), "kept", &meta))
+
+		_, err = local.GetFile(ctx, "kept", meta)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		assert.ErrorIs(t, local.DeleteFile(ctx, "kept"), context.Canceled)
+		_, err = os.Stat(filepath.Join(root, "kept"))
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+// Backend is implemented by the storages and the layers that wrap them.
+type Backend interface {
+	GetStoragePath() string
+	GetStorageType() string
+	SaveFile(ctx context.Context, file io.Reader, name string, meta *Meta) error
+	GetFile(ctx context.Context, name string, meta Meta) ([]byte, error)
+	DeleteFile(ctx context.Context, name string) error
+}
+
+// Meta carries per-file attributes a storage backend needs to read a file
+// back. Backends fill it in on save, it is persisted with the files row and
+// handed back to the backend on read.
+type Meta struct {
+	KeyId      string
+	WrappedKey []byte
+	Encoding   string
+
+	// Size is the size of the file as uploaded, set by the caller on save.
+	Size int64
+
+	// CustomerKey is the encryption key supplied by the client with the
+	// request. It is never persisted.
+	CustomerKey []byte
+
+	// KeepEncoding asks for the stored bytes as they are, without undoing
+	// the content encoding, for clients that accept it.
+	KeepEncoding bool
+}
+
+// ContextReader returns a reader that fails with the error of ctx once ctx
+// is done, which stops copies of requests that were cancelled or timed out.
+func ContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r}
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.r.Read(p)
+}
@@ -0,0 +1,53 @@
+package timeoutstorage
+
+import (
+	"context"
+	"file-service/m/internal/config"
+	"file-service/m/internal/storage"
+	"io"
+	"time"
+)
+
+// Storage bounds every operation of the backend with the timeout configured
+// for it. The deadline is set on the context handed to the backend, which
+// stops copying once it expires.
+type Storage struct {
+	storage.Backend
+	timeouts config.StorageTimeoutConfig
+}
+
+func New(backend storage.Backend, timeouts config.StorageTimeoutConfig) *Storage {
+	return &Storage{
+		Backend:  backend,
+		timeouts: timeouts,
+	}
+}
+
+func (s *Storage) SaveFile(ctx context.Context, file io.Reader, name string, meta *storage.Meta) error {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Save)
+	defer cancel()
+
+	return s.Backend.SaveFile(ctx, file, name, meta)
+}
+
+func (s *Storage) GetFile(ctx context.Context, name string, meta storage.Meta) ([]byte, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Get)
+	defer cancel()
+
+	return s.Backend.GetFile(ctx, name, meta)
+}
+
+func (s *Storage) DeleteFile(ctx context.Context, name string) error {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Delete)
+	defer cancel()
+
+	return s.Backend.DeleteFile(ctx, name)
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,79 @@
+package timeoutstorage_test
+
+import (
+	"context"
+	"file-service/m/internal/config"
+	"file-service/m/internal/storage"
+	timeoutstorage "file-service/m/internal/storage/timeoutStorage"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingBackend waits for the context of every call and reports whether
+// it carried a deadline.
+type blockingBackend struct {
+	hadDeadline bool
+}
+
+func (b *blockingBackend) GetStoragePath() string {
+	return "blocking"
+}
+
+func (b *blockingBackend) GetStorageType() string {
+	return "blocking"
+}
+
+func (b *blockingBackend) SaveFile(ctx context.Context, _ io.Reader, _ string, _ *storage.Meta) error {
+	return b.wait(ctx)
+}
+
+func (b *blockingBackend) GetFile(ctx context.Context, _ string, _ storage.Meta) ([]byte, error) {
+	return nil, b.wait(ctx)
+}
+
+func (b *blockingBackend) DeleteFile(ctx context.Context, _ string) error {
+	return b.wait(ctx)
+}
+
+func (b *blockingBackend) wait(ctx context.Context) error {
+	_, b.hadDeadline = ctx.Deadline()
+	if !b.hadDeadline {
+		return nil
+	}
+
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+func TestStorage(t *testing.T) {
+	t.Run("operations time out", func(t *testing.T) {
+		backend := &blockingBackend{}
+		s := timeoutstorage.New(backend, config.StorageTimeoutConfig{
+			Save:   time.Millisecond,
+			Get:    time.Millisecond,
+			Delete: time.Millisecond,
+		})
+
+		err := s.SaveFile(context.Background(), strings.NewReader("data"), "name", &storage.Meta{})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		_, err = s.GetFile(context.Background(), "name", storage.Meta{})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		assert.ErrorIs(t, s.DeleteFile(context.Background(), "name"), context.DeadlineExceeded)
+	})
+
+	t.Run("zero timeout is unbounded", func(t *testing.T) {
+		backend := &blockingBackend{}
+		s := timeoutstorage.New(backend, config.StorageTimeoutConfig{})
+
+		require.NoError(t, s.DeleteFile(context.Background(), "name"))
+		assert.False(t, backend.hadDeadline)
+	})
+}
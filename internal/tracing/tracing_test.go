@@ -0,0 +1,54 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"file-service/m/internal/tracing"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func traced(ctx context.Context, fail bool) (err error) {
+	_, span := tracing.Start(ctx, "postgres.GetFile")
+	defer tracing.End(span, &err)
+
+	if fail {
+		return errors.New("connection refused")
+	}
+
+	return nil
+}
+
+func TestEnd(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), "test", 1))
+
+	require.NoError(t, traced(context.Background(), false))
+	require.Error(t, traced(context.Background(), true))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Empty(t, spans[0].Events)
+
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+	assert.Equal(t, "connection refused", spans[1].Status.Description)
+	require.Len(t, spans[1].Events, 1)
+	assert.Equal(t, "exception", spans[1].Events[0].Name)
+}
+
+func TestSampleRatio(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), "test", 0))
+
+	require.NoError(t, traced(context.Background(), false))
+
+	assert.Empty(t, exporter.GetSpans())
+}
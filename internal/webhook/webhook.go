@@ -0,0 +1,237 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"file-service/m/internal/config"
+	"file-service/m/internal/database"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers sent with every delivery. The signature has the form
+// "t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>">", keyed with
+// the secret of the webhook, so that receivers can check the origin and
+// reject replays.
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	IdHeader        = "X-Webhook-Id"
+	EventHeader     = "X-Webhook-Event"
+)
+
+const secretSize = 32
+
+//go:generate mockery --name=Db
+type Db interface {
+	DispatchFileEvents(ctx context.Context, limit int) (int64, error)
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]database.PendingDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, id int64, attempt database.DeliveryAttempt) error
+}
+
+// Payload is the body of a delivery.
+type Payload struct {
+	Id        int64     `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      File      `json:"data"`
+}
+
+type File struct {
+	FileId string `json:"file_id"`
+	Owner  string `json:"owner"`
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+}
+
+// GenerateSecret returns a new random signing secret.
+func GenerateSecret() (string, error) {
+	buf := make([]byte, secretSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// Sign returns the signature header of body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher delivers the events in the outbox of the database to the
+// webhooks subscribed to them. Deliveries are claimed in the database, so any
+// number of replicas can run one.
+type Dispatcher struct {
+	logger *slog.Logger
+	db     Db
+	client *http.Client
+	cfg    config.WebhookConfig
+	now    func() time.Time
+}
+
+func New(logger *slog.Logger, db Db, cfg config.WebhookConfig) *Dispatcher {
+	return &Dispatcher{
+		logger: logger,
+		db:     db,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Poll(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Error("failed to deliver webhooks", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll creates the deliveries of new events and makes one attempt at every
+// delivery that is due.
+func (d *Dispatcher) Poll(ctx context.Context) error {
+	const op = "webhook.Dispatcher.Poll"
+
+	if _, err := d.db.DispatchFileEvents(ctx, d.cfg.BatchSize); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// the lease outlasts the attempt, a delivery is only claimed again when
+	// this replica went away before recording the outcome
+	deliveries, err := d.db.ClaimWebhookDeliveries(ctx, d.cfg.BatchSize, 2*d.cfg.Timeout+time.Minute)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery database.PendingDelivery) {
+			defer wg.Done()
+
+			d.attempt(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery database.PendingDelivery) {
+	log := d.logger.With(
+		slog.Int64("delivery_id", delivery.Id),
+		slog.Int64("event_id", delivery.Event.Id),
+	)
+
+	statusCode, err := d.send(ctx, delivery)
+
+	attempt := database.DeliveryAttempt{
+		Status:        database.DeliverySucceeded,
+		StatusCode:    statusCode,
+		NextAttemptAt: d.now(),
+	}
+
+	if err != nil {
+		attempts := delivery.Attempts + 1
+
+		attempt.Error = err.Error()
+		attempt.Status = database.DeliveryPending
+		attempt.NextAttemptAt = d.now().Add(d.backoff(attempts))
+
+		if attempts >= d.cfg.MaxAttempts {
+			attempt.Status = database.DeliveryDead
+		}
+
+		log.Warn("webhook delivery failed",
+			slog.Int("attempts", attempts),
+			slog.String("status", attempt.Status),
+			slog.Any("error", err),
+		)
+	}
+
+	// recorded even when ctx is done so that the attempt is not repeated
+	// before the lease runs out
+	if err := d.db.UpdateWebhookDelivery(context.WithoutCancel(ctx), delivery.Id, attempt); err != nil {
+		log.Error("failed to record webhook delivery", slog.Any("error", err))
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery database.PendingDelivery) (int, error) {
+	body, err := json.Marshal(Payload{
+		Id:        delivery.Event.Id,
+		Type:      delivery.Event.Type,
+		CreatedAt: delivery.Event.CreatedAt,
+		Data: File{
+			FileId: delivery.Event.FilePublicId,
+			Owner:  delivery.Event.Owner,
+			Name:   delivery.Event.Name,
+			Size:   delivery.Event.Size,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdHeader, strconv.FormatInt(delivery.Event.Id, 10))
+	req.Header.Set(EventHeader, delivery.Event.Type)
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, d.now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	// drained so that the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns how long to wait after the given number of failed
+// attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.BackoffBase
+	for i := 1; i < attempts && delay < d.cfg.BackoffMax; i++ {
+		delay *= 2
+	}
+
+	return min(delay, d.cfg.BackoffMax)
+}
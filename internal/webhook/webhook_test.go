@@ -0,0 +1,145 @@
+package webhook_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"file-service/m/internal/config"
+	"file-service/m/internal/database"
+	mockLogger "file-service/m/internal/logger/mocks"
+	"file-service/m/internal/webhook"
+	"file-service/m/internal/webhook/mocks"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const publicId = "0190b5d4-8c3a-7c1e-9f3e-6a2b1c4d5e6f"
+
+var cfg = config.WebhookConfig{
+	PollInterval: time.Second,
+	Timeout:      time.Second,
+	MaxAttempts:  3,
+	BackoffBase:  time.Minute,
+	BackoffMax:   3 * time.Minute,
+	BatchSize:    10,
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	signature := webhook.Sign("secret", time.Unix(1700000000, 0), body)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+
+	assert.Equal(t, "t=1700000000,v1="+hex.EncodeToString(mac.Sum(nil)), signature)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := webhook.GenerateSecret()
+	require.NoError(t, err)
+
+	b, err := webhook.GenerateSecret()
+	require.NoError(t, err)
+
+	assert.Len(t, a, 64)
+	assert.NotEqual(t, a, b)
+}
+
+func TestPoll(t *testing.T) {
+	log := mockLogger.NewLogger()
+
+	var got *http.Request
+	var body []byte
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	delivery := func(attempts int) database.PendingDelivery {
+		return database.PendingDelivery{
+			Id:       5,
+			Attempts: attempts,
+			URL:      server.URL,
+			Secret:   "secret",
+			Event: database.FileEvent{
+				Id:           9,
+				Type:         database.FileEventCreated,
+				FileId:       7,
+				FilePublicId: publicId,
+				Owner:        "alice",
+				Name:         "a.txt",
+				Size:         4,
+				CreatedAt:    time.Unix(1700000000, 0).UTC(),
+			},
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		db := mocks.NewDb(t)
+		db.On("DispatchFileEvents", mock.Anything, 10).Return(int64(1), nil).Once()
+		db.On("ClaimWebhookDeliveries", mock.Anything, 10, mock.Anything).
+			Return([]database.PendingDelivery{delivery(0)}, nil).Once()
+		db.On("UpdateWebhookDelivery", mock.Anything, int64(5), mock.MatchedBy(func(attempt database.DeliveryAttempt) bool {
+			return attempt.Status == database.DeliverySucceeded && attempt.StatusCode == http.StatusOK && attempt.Error == ""
+		})).Return(nil).Once()
+
+		status = http.StatusOK
+		require.NoError(t, webhook.New(log, db, cfg).Poll(context.Background()))
+
+		assert.Equal(t, "9", got.Header.Get(webhook.IdHeader))
+		assert.Equal(t, database.FileEventCreated, got.Header.Get(webhook.EventHeader))
+
+		timestamp, _, _ := strings.Cut(strings.TrimPrefix(got.Header.Get(webhook.SignatureHeader), "t="), ",")
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, webhook.Sign("secret", time.Unix(unix, 0), body), got.Header.Get(webhook.SignatureHeader))
+
+		var payload webhook.Payload
+		require.NoError(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, int64(9), payload.Id)
+		assert.Equal(t, publicId, payload.Data.FileId)
+		assert.Equal(t, "alice", payload.Data.Owner)
+	})
+
+	t.Run("failure is retried with backoff", func(t *testing.T) {
+		db := mocks.NewDb(t)
+		db.On("DispatchFileEvents", mock.Anything, 10).Return(int64(0), nil).Once()
+		db.On("ClaimWebhookDeliveries", mock.Anything, 10, mock.Anything).
+			Return([]database.PendingDelivery{delivery(1)}, nil).Once()
+		db.On("UpdateWebhookDelivery", mock.Anything, int64(5), mock.MatchedBy(func(attempt database.DeliveryAttempt) bool {
+			wait := time.Until(attempt.NextAttemptAt)
+			return attempt.Status == database.DeliveryPending && attempt.StatusCode == http.StatusInternalServerError &&
+				wait > time.Minute && wait <= 2*time.Minute
+		})).Return(nil).Once()
+
+		status = http.StatusInternalServerError
+		require.NoError(t, webhook.New(log, db, cfg).Poll(context.Background()))
+	})
+
+	t.Run("last attempt", func(t *testing.T) {
+		db := mocks.NewDb(t)
+		db.On("DispatchFileEvents", mock.Anything, 10).Return(int64(0), nil).Once()
+		db.On("ClaimWebhookDeliveries", mock.Anything, 10, mock.Anything).
+			Return([]database.PendingDelivery{delivery(2)}, nil).Once()
+		db.On("UpdateWebhookDelivery", mock.Anything, int64(5), mock.MatchedBy(func(attempt database.DeliveryAttempt) bool {
+			return attempt.Status == database.DeliveryDead
+		})).Return(nil).Once()
+
+		status = http.StatusBadGateway
+		require.NoError(t, webhook.New(log, db, cfg).Poll(context.Background()))
+	})
+}